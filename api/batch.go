@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchOperationErrors are the responses for operations of a batch that cannot be applied
+var batchOperationErrors = map[error]string{
+	db.ErrInsufficientFunds:      "Insufficient funds",
+	db.ErrInvalidExpiry:          "Expiry must be in the future",
+	db.ErrSameWallet:             "Source and destination wallets must differ",
+	db.ErrSystemAccount:          "System accounts cannot be credited or debited directly",
+	models.ErrInvalidAccountName: "Invalid account name",
+	models.ErrInvalidCurrency:    "Invalid currency code",
+}
+
+// Batch applies several operations atomically
+// @Summary Apply a batch of operations
+// @Description Apply adds, removes and transfers in order in a single database transaction: either all of them
+// @Description are committed or none is. Each operation sees the balances left by the ones before it.
+// @Description If an operation fails, the response names its index and nothing is applied.
+// @Tags batch
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param request body BatchRequest true "Batch request"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} BatchErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} BatchErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /batch [post]
+func (h *Handler) Batch(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	operations := make([]db.BatchOperation, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		if op.Type == "transfer" && (op.FromWalletID == "" || op.ToWalletID == "") {
+			c.JSON(http.StatusBadRequest, BatchErrorResponse{Error: "Operation " + strconv.Itoa(i) + ": from_wallet_id and to_wallet_id are required", Operation: i})
+			return
+		}
+		if op.Type != "transfer" && op.WalletID == "" {
+			c.JSON(http.StatusBadRequest, BatchErrorResponse{Error: "Operation " + strconv.Itoa(i) + ": wallet_id is required", Operation: i})
+			return
+		}
+		if op.Type != "add" && op.ExpiresAt != nil {
+			c.JSON(http.StatusBadRequest, BatchErrorResponse{Error: "Operation " + strconv.Itoa(i) + ": expires_at only applies to add", Operation: i})
+			return
+		}
+
+		operations = append(operations, db.BatchOperation{
+			Type:           op.Type,
+			WalletID:       op.WalletID,
+			Account:        op.Account,
+			Clamp:          op.Clamp,
+			ExpiresAt:      op.ExpiresAt,
+			FromWalletID:   op.FromWalletID,
+			ToWalletID:     op.ToWalletID,
+			Currency:       op.Currency,
+			Amount:         op.Amount,
+			Description:    op.Description,
+			AdditionalData: op.AdditionalData,
+		})
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Apply all operations in one transaction
+	results, err := database.Batch(operations)
+	if err != nil {
+		var batchErr *db.BatchError
+		if errors.As(err, &batchErr) {
+			if batchErr.Err == db.ErrAmountOverflow {
+				c.JSON(http.StatusUnprocessableEntity, BatchErrorResponse{Error: "Operation " + strconv.Itoa(batchErr.Index) + ": " + amountOverflowMessage, Code: "amount_overflow", Operation: batchErr.Index})
+				return
+			}
+			message, ok := batchOperationErrors[batchErr.Err]
+			if !ok {
+				message, ok = currencyRuleErrors[batchErr.Err]
+			}
+			if ok {
+				c.JSON(http.StatusBadRequest, BatchErrorResponse{Error: "Operation " + strconv.Itoa(batchErr.Index) + ": " + message, Code: ruleErrorCodes[batchErr.Err], Operation: batchErr.Index})
+				return
+			}
+		}
+		if err == db.ErrBatchTooLarge {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "Batch is too large to commit atomically, split it into smaller batches"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to apply batch: " + err.Error()})
+		return
+	}
+
+	// Return one result per operation
+	response := BatchResponse{Results: make([]BatchOperationResponse, 0, len(results))}
+	for i, result := range results {
+		entry := BatchOperationResponse{Type: req.Operations[i].Type}
+		if result.Transfer != nil {
+			entry.Transfer = &TransferResponse{
+				TransferID:      result.Transfer.TransferID,
+				Currency:        result.Transfer.Currency,
+				FromTransaction: result.Transfer.Debit,
+				ToTransaction:   result.Transfer.Credit,
+				FromWallet:      result.Transfer.FromWallet,
+				ToWallet:        result.Transfer.ToWallet,
+			}
+		} else {
+			entry.Transaction = result.Result.Transaction
+			entry.Wallet = result.Result.Wallet
+		}
+		response.Results = append(response.Results, entry)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/models"
+)
+
+func TestBatch(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	err = db.SaveCurrency(&models.Currency{Code: "GEMS", Name: "Gems", Decimals: 0, Transferable: true})
+	assert.NoError(t, err)
+
+	_, err = db.AddCurrency("bob", "", models.MustParseAmount("10"), "Initial deposit", nil)
+	assert.NoError(t, err)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		return sendRequest(router, "POST", "/api/v1/batch", body)
+	}
+
+	// Quest completion: rewards in two currencies, an entry fee and a share for the party
+	w := send(`{"operations": [
+		{"type": "add", "wallet_id": "alice", "amount": "100", "description": "Quest reward", "account": "quest_rewards"},
+		{"type": "add", "wallet_id": "alice", "currency": "GEMS", "amount": "3", "description": "Quest reward"},
+		{"type": "remove", "wallet_id": "bob", "amount": "5", "description": "Entry fee", "account": "dungeon"},
+		{"type": "transfer", "from_wallet_id": "alice", "to_wallet_id": "bob", "amount": "20", "description": "Party share"}
+	]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response BatchResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Results, 4)
+
+	assert.Equal(t, "add", response.Results[0].Type)
+	assert.Equal(t, "faucet:quest_rewards", response.Results[0].Transaction.CounterpartyWalletID)
+	assert.Equal(t, models.MustParseAmount("100"), response.Results[0].Wallet.Balance)
+
+	assert.Equal(t, "GEMS", response.Results[1].Transaction.Currency)
+	assert.Equal(t, "sink:dungeon", response.Results[2].Transaction.CounterpartyWalletID)
+
+	// Each operation sees the balances left by the ones before it
+	assert.Equal(t, "transfer", response.Results[3].Type)
+	assert.Nil(t, response.Results[3].Transaction)
+	assert.Equal(t, models.MustParseAmount("80"), response.Results[3].Transfer.FromWallet.Balance)
+	assert.Equal(t, models.MustParseAmount("25"), response.Results[3].Transfer.ToWallet.Balance)
+
+	// A failing operation rolls back the whole batch
+	w = send(`{"operations": [
+		{"type": "add", "wallet_id": "carol", "amount": "50", "description": "Quest reward"},
+		{"type": "remove", "wallet_id": "bob", "amount": "500", "description": "Entry fee"}
+	]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var batchErr BatchErrorResponse
+	err = json.Unmarshal(w.Body.Bytes(), &batchErr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, batchErr.Operation)
+	assert.Equal(t, "Operation 1: Insufficient funds", batchErr.Error)
+
+	balance, err := db.GetWalletBalance("carol", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Amount(0), balance)
+
+	// Operations are validated before anything is applied
+	invalid := []string{
+		`{"operations": []}`,
+		`{"operations": [{"type": "burn", "wallet_id": "alice", "amount": "1", "description": "Unknown"}]}`,
+		`{"operations": [{"type": "add", "amount": "1", "description": "No wallet"}]}`,
+		`{"operations": [{"type": "transfer", "from_wallet_id": "alice", "to_wallet_id": "alice", "amount": "1", "description": "Same wallet"}]}`,
+		`{"operations": [{"type": "transfer", "from_wallet_id": "sink:dungeon", "to_wallet_id": "alice", "amount": "1", "description": "System account"}]}`,
+		`{"operations": [{"type": "add", "wallet_id": "alice", "currency": "NOPE", "amount": "1", "description": "Unknown currency"}]}`,
+	}
+	for _, body := range invalid {
+		w = send(body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	// Batches beyond the configured size are refused
+	operation := `{"type": "add", "wallet_id": "alice", "amount": "1", "description": "Tiny reward"}`
+	w = send(`{"operations": [` + strings.TrimSuffix(strings.Repeat(operation+",", 101), ",") + `]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// Nothing from the refused batches was applied and the ledger still balances
+	balance, err = db.GetWalletBalance("alice", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("80"), balance)
+
+	trial, err := db.TrialBalance()
+	assert.NoError(t, err)
+	for _, currency := range trial {
+		assert.True(t, currency.Balanced)
+	}
+}
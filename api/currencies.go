@@ -0,0 +1,157 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListCurrencies lists the currency definitions
+// @Summary List currencies
+// @Description List every currency definition of the environment, including the default currency
+// @Tags currencies
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Success 200 {array} models.Currency
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /currencies [get]
+func (h *Handler) ListCurrencies(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get currencies
+	currencies, err := database.ListCurrencies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list currencies: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, currencies)
+}
+
+// GetCurrency gets a currency definition
+// @Summary Get currency
+// @Description Get the definition of a currency
+// @Tags currencies
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param currency path string true "Currency code"
+// @Success 200 {object} models.Currency
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /currencies/{currency} [get]
+func (h *Handler) GetCurrency(c *gin.Context) {
+	code, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get currency
+	currency, err := database.GetCurrency(code)
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Currency not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get currency: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, currency)
+}
+
+// PutCurrency creates or replaces a currency definition
+// @Summary Define currency
+// @Description Create or replace the definition of a currency. The rules apply to later transactions;
+// @Description balances already held are not changed.
+// @Tags currencies
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param currency path string true "Currency code"
+// @Param request body CurrencyRequest true "Currency definition"
+// @Success 200 {object} models.Currency
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /currencies/{currency} [put]
+func (h *Handler) PutCurrency(c *gin.Context) {
+	code, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	var req CurrencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	currency := &models.Currency{
+		Code:           code,
+		Name:           req.Name,
+		Decimals:       models.Decimals(),
+		MinTransaction: req.MinTransaction,
+		MaxTransaction: req.MaxTransaction,
+		MaxBalance:     req.MaxBalance,
+		Transferable:   true,
+		AllowNegative:  req.AllowNegative,
+	}
+	if req.Decimals != nil {
+		currency.Decimals = *req.Decimals
+	}
+	if req.Transferable != nil {
+		currency.Transferable = *req.Transferable
+	}
+	if currency.Name == "" {
+		currency.Name = code
+	}
+
+	if err := currency.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency: " + err.Error()})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Save currency
+	if err := database.SaveCurrency(currency); err != nil {
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Currency is being updated concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save currency: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, currency)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+)
+
+func TestCurrencyRegistry(t *testing.T) {
+	router, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Unregistered currencies cannot be used
+	w := sendRequest(router, "POST", "/api/v1/wallets/wallet123/currencies/GEMS/add", `{"amount": "1", "description": "Reward"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "not registered")
+
+	w = sendRequest(router, "GET", "/api/v1/currencies/GEMS", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Define a whole-number currency with limits that cannot be traded
+	w = sendRequest(router, "PUT", "/api/v1/currencies/gems", `{"name": "Gems", "decimals": 0, "min_transaction": "2", "max_transaction": "100", "max_balance": "150", "transferable": false}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var currency models.Currency
+	err := json.Unmarshal(w.Body.Bytes(), &currency)
+	assert.NoError(t, err)
+	assert.Equal(t, "GEMS", currency.Code)
+	assert.Equal(t, "Gems", currency.Name)
+	assert.Equal(t, 0, currency.Decimals)
+	assert.False(t, currency.Transferable)
+
+	// The default currency is always listed
+	w = sendRequest(router, "GET", "/api/v1/currencies", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var currencies []models.Currency
+	err = json.Unmarshal(w.Body.Bytes(), &currencies)
+	assert.NoError(t, err)
+	if assert.Len(t, currencies, 2) {
+		assert.Equal(t, "DEFAULT", currencies[0].Code)
+		assert.Equal(t, "GEMS", currencies[1].Code)
+	}
+
+	// Each rule is enforced with its own error
+	for _, tc := range []struct {
+		path, body, message string
+	}{
+		{"add", `{"amount": "1.5", "description": "Fractional"}`, "decimal places"},
+		{"add", `{"amount": "101", "description": "Too large"}`, "maximum transaction"},
+		{"add", `{"amount": "1", "description": "Too small"}`, "minimum transaction"},
+	} {
+		w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/currencies/GEMS/"+tc.path, tc.body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+		assert.Contains(t, w.Body.String(), tc.message, tc.body)
+	}
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/currencies/GEMS/add", `{"amount": "100", "description": "Reward"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/currencies/GEMS/add", `{"amount": "51", "description": "Over the cap"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "maximum balance")
+
+	w = sendRequest(router, "POST", "/api/v1/transfers", `{"from_wallet_id": "wallet123", "to_wallet_id": "wallet456", "currency": "GEMS", "amount": "10", "description": "Trade"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "cannot be transferred")
+
+	// A currency that may go negative allows removing more than the balance
+	w = sendRequest(router, "PUT", "/api/v1/currencies/TOKENS", `{"allow_negative": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/currencies/TOKENS/remove", `{"amount": "5", "description": "Advance"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("-5"), resp.Wallet.Balance)
+
+	// Inconsistent definitions are rejected
+	w = sendRequest(router, "PUT", "/api/v1/currencies/GEMS", `{"min_transaction": "10", "max_transaction": "5"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "PUT", "/api/v1/currencies/GEMS", `{"decimals": 3}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEnvironmentLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("API_TOKEN", "test-token")
+
+	config := db.DefaultConfig()
+	maxTransaction := models.MustParseAmount("1000")
+	maxBalance := models.MustParseAmount("1500")
+	config.MaxTransaction = &maxTransaction
+	config.MaxBalance = &maxBalance
+
+	dbManager := db.NewDBManagerWithConfig(t.TempDir(), config)
+	defer dbManager.Close()
+	router := SetupRouter(dbManager)
+
+	// The default currency is capped by the environment before it is registered
+	w := sendRequest(router, "POST", "/api/v1/wallets/wallet123/add", `{"amount": "1000.01", "description": "Too large"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "maximum transaction")
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/add", `{"amount": "1000", "description": "Reward"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/add", `{"amount": "600", "description": "Over the cap"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "maximum balance")
+
+	// Clamped credits stop at the environment's cap as well
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/add", `{"amount": "600", "description": "Clamped", "clamp": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp TransactionResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("500"), resp.Transaction.Amount)
+	assert.Equal(t, maxBalance, resp.Wallet.Balance)
+
+	// Registered currencies may set lower limits but not higher ones
+	w = sendRequest(router, "PUT", "/api/v1/currencies/GEMS", `{"max_transaction": "5000", "max_balance": "1200"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/currencies/GEMS", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var currency models.Currency
+	err = json.Unmarshal(w.Body.Bytes(), &currency)
+	assert.NoError(t, err)
+	assert.Equal(t, maxTransaction, *currency.MaxTransaction)
+	assert.Equal(t, models.MustParseAmount("1200"), *currency.MaxBalance)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/currencies/GEMS/add", `{"amount": "2000", "description": "Too large"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "maximum transaction")
+}
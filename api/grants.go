@@ -0,0 +1,499 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// grantErrors are the messages of the errors a grant or enrollment can be refused with
+var grantErrors = map[error]string{
+	db.ErrInvalidGrant:    "Grants need either interval_seconds of at most ten years or a valid five-field cron expression, and an end_at after their start",
+	db.ErrAlreadyEnrolled: "Wallet is already enrolled in the grant",
+	db.ErrGrantNotActive:  "Grant or enrollment is not active",
+	db.ErrGrantNotPaused:  "Grant or enrollment is not paused",
+	db.ErrEnrollmentEnded: "Enrollment has already ended",
+}
+
+// grantError writes the response for an error returned by a grant operation and
+// reports whether it recognised the error; notFound names the missing record
+func grantError(c *gin.Context, err error, notFound string) bool {
+	if message, ok := grantErrors[err]; ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+		return true
+	}
+	if err == db.ErrNotFound {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: notFound + " not found"})
+		return true
+	}
+	if err == db.ErrConflict {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: notFound + " is being updated concurrently, please retry"})
+		return true
+	}
+	return false
+}
+
+// CreateGrant creates a recurring grant
+// @Summary Create a grant
+// @Description Create a recurring grant, such as a daily allowance, that pays a fixed amount from a faucet to every
+// @Description enrolled wallet on a schedule. The schedule is either interval_seconds, counted from each
+// @Description enrollment's start, or a five-field cron expression evaluated in UTC. catch_up decides which
+// @Description occurrences missed while the service was down are granted: all of them, only the latest, or none
+// @Description that is overdue by more than GRANT_GRACE_PERIOD (skip).
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param request body CreateGrantRequest true "Create grant request"
+// @Success 201 {object} models.Grant
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants [post]
+func (h *Handler) CreateGrant(c *gin.Context) {
+	var req CreateGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	var writeOptions []db.WriteOption
+	if req.Account != "" {
+		name, err := models.ParseSystemAccountName(req.Account)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid account name"})
+			return
+		}
+		writeOptions = append(writeOptions, db.WithSystemAccount(name))
+	}
+
+	grant := &models.Grant{
+		Name:            req.Name,
+		Currency:        req.Currency,
+		Amount:          req.Amount,
+		Description:     req.Description,
+		AdditionalData:  req.AdditionalData,
+		IntervalSeconds: req.IntervalSeconds,
+		Cron:            req.Cron,
+		CatchUp:         req.CatchUp,
+		EndAt:           req.EndAt,
+	}
+	if req.StartAt != nil {
+		grant.StartAt = *req.StartAt
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Create grant
+	grant, err = database.CreateGrant(grant, writeOptions...)
+	if err != nil {
+		if err == models.ErrInvalidCurrency {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
+			return
+		}
+		if grantError(c, err, "Grant") || currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create grant: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusCreated, grant)
+}
+
+// ListGrants lists recurring grants
+// @Summary List grants
+// @Description List every recurring grant in the order they were created
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Success 200 {object} GrantsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants [get]
+func (h *Handler) ListGrants(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get grants
+	grants, err := database.ListGrants()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list grants: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GrantsResponse{Grants: grants})
+}
+
+// GetGrant gets a single grant by ID
+// @Summary Get grant
+// @Description Get a recurring grant by its ID
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Success 200 {object} models.Grant
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id} [get]
+func (h *Handler) GetGrant(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get grant
+	grant, err := database.GetGrant(c.Param("grant_id"))
+	if err != nil {
+		if grantError(c, err, "Grant") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get grant: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, grant)
+}
+
+// PauseGrant pauses a grant
+// @Summary Pause a grant
+// @Description Stop crediting every wallet enrolled in an active grant. Occurrences that fall due while the grant is
+// @Description paused are skipped, whatever its catch-up policy.
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Success 200 {object} models.Grant
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/pause [post]
+func (h *Handler) PauseGrant(c *gin.Context) {
+	h.updateGrant(c, (*db.DB).PauseGrant, "pause")
+}
+
+// ResumeGrant resumes a paused grant
+// @Summary Resume a grant
+// @Description Resume crediting the wallets enrolled in a paused grant from their next occurrence
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Success 200 {object} models.Grant
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/resume [post]
+func (h *Handler) ResumeGrant(c *gin.Context) {
+	h.updateGrant(c, (*db.DB).ResumeGrant, "resume")
+}
+
+// updateGrant applies a change of status to the grant in the path and writes the response
+func (h *Handler) updateGrant(c *gin.Context, update func(*db.DB, string) (*models.Grant, error), action string) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	grant, err := update(database, c.Param("grant_id"))
+	if err != nil {
+		if grantError(c, err, "Grant") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to " + action + " grant: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, grant)
+}
+
+// ListGrantRuns lists the history of a grant
+// @Summary List grant runs
+// @Description List the occurrences a grant ran, in order of their due time, with the transaction each granted
+// @Description occurrence recorded or the error a failed one was refused with. The transactions carry the grant_id.
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Param wallet_id query string false "Only runs for this wallet"
+// @Success 200 {object} GrantRunsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/runs [get]
+func (h *Handler) ListGrantRuns(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get runs
+	runs, err := database.ListGrantRuns(c.Param("grant_id"), c.Query("wallet_id"))
+	if err != nil {
+		if grantError(c, err, "Grant") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list grant runs: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GrantRunsResponse{Runs: runs})
+}
+
+// EnrollWallet enrolls a wallet in a grant
+// @Summary Enroll a wallet
+// @Description Enroll a wallet in a grant. Its first occurrence is due at start_at, or at the first cron match from
+// @Description then, and its occurrences end before end_at, e.g. 30 days after the start of a subscription. A wallet
+// @Description whose earlier enrollment ended or was cancelled is enrolled anew.
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Param request body EnrollWalletRequest true "Enroll wallet request"
+// @Success 201 {object} models.GrantEnrollment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/enrollments [post]
+func (h *Handler) EnrollWallet(c *gin.Context) {
+	var req EnrollWalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if models.IsSystemAccount(req.WalletID) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "System accounts cannot be credited or debited directly"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	var startAt time.Time
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+
+	// Enroll wallet
+	enrollment, err := database.EnrollWallet(c.Param("grant_id"), req.WalletID, startAt, req.EndAt)
+	if err != nil {
+		if grantError(c, err, "Grant") || currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to enroll wallet: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// ListEnrollments lists the enrollments in a grant
+// @Summary List enrollments
+// @Description List the enrollments of every wallet in a grant, ordered by wallet ID
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Success 200 {object} GrantEnrollmentsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/enrollments [get]
+func (h *Handler) ListEnrollments(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get enrollments
+	enrollments, err := database.ListEnrollments(c.Param("grant_id"))
+	if err != nil {
+		if grantError(c, err, "Grant") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list enrollments: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GrantEnrollmentsResponse{Enrollments: enrollments})
+}
+
+// GetEnrollment gets the enrollment of a wallet in a grant
+// @Summary Get enrollment
+// @Description Get the enrollment of a wallet in a grant, with its next occurrence and how many occurrences were
+// @Description granted, failed or skipped
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} models.GrantEnrollment
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/enrollments/{wallet_id} [get]
+func (h *Handler) GetEnrollment(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get enrollment
+	enrollment, err := database.GetEnrollment(c.Param("grant_id"), c.Param("wallet_id"))
+	if err != nil {
+		if grantError(c, err, "Enrollment") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get enrollment: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// PauseEnrollment pauses the enrollment of a wallet
+// @Summary Pause an enrollment
+// @Description Stop crediting one wallet enrolled in a grant. Occurrences that fall due while the enrollment is
+// @Description paused are not granted.
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} models.GrantEnrollment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/enrollments/{wallet_id}/pause [post]
+func (h *Handler) PauseEnrollment(c *gin.Context) {
+	h.updateEnrollment(c, (*db.DB).PauseEnrollment, "pause")
+}
+
+// ResumeEnrollment resumes the paused enrollment of a wallet
+// @Summary Resume an enrollment
+// @Description Resume crediting a wallet whose enrollment is paused from its next occurrence
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} models.GrantEnrollment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/enrollments/{wallet_id}/resume [post]
+func (h *Handler) ResumeEnrollment(c *gin.Context) {
+	h.updateEnrollment(c, (*db.DB).ResumeEnrollment, "resume")
+}
+
+// CancelEnrollment cancels the enrollment of a wallet
+// @Summary Cancel an enrollment
+// @Description End the enrollment of a wallet in a grant before its end_at
+// @Tags grants
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param grant_id path string true "Grant ID"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} models.GrantEnrollment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /grants/{grant_id}/enrollments/{wallet_id}/cancel [post]
+func (h *Handler) CancelEnrollment(c *gin.Context) {
+	h.updateEnrollment(c, (*db.DB).CancelEnrollment, "cancel")
+}
+
+// updateEnrollment applies a change of status to the enrollment in the path and writes the response
+func (h *Handler) updateEnrollment(c *gin.Context, update func(*db.DB, string, string) (*models.GrantEnrollment, error), action string) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	enrollment, err := update(database, c.Param("grant_id"), c.Param("wallet_id"))
+	if err != nil {
+		if grantError(c, err, "Enrollment") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to " + action + " enrollment: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, enrollment)
+}
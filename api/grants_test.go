@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/models"
+)
+
+func TestGrants(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	createGrant := func(body string) models.Grant {
+		w := sendRequest(router, "POST", "/api/v1/grants", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var grant models.Grant
+		err := json.Unmarshal(w.Body.Bytes(), &grant)
+		assert.NoError(t, err)
+		return grant
+	}
+
+	enroll := func(grantID, body string) models.GrantEnrollment {
+		w := sendRequest(router, "POST", "/api/v1/grants/"+grantID+"/enrollments", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var enrollment models.GrantEnrollment
+		err := json.Unmarshal(w.Body.Bytes(), &enrollment)
+		assert.NoError(t, err)
+		return enrollment
+	}
+
+	getEnrollment := func(grantID, walletID string) models.GrantEnrollment {
+		w := sendRequest(router, "GET", "/api/v1/grants/"+grantID+"/enrollments/"+walletID, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var enrollment models.GrantEnrollment
+		err := json.Unmarshal(w.Body.Bytes(), &enrollment)
+		assert.NoError(t, err)
+		return enrollment
+	}
+
+	balance := func(walletID string) models.Amount {
+		wallet, err := db.GetWallet(walletID, "")
+		assert.NoError(t, err)
+		return wallet.Balance
+	}
+
+	now := time.Now()
+	subscriptionEnd := now.Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339Nano)
+
+	// A daily subscription grants its first occurrence when the wallet is enrolled
+	daily := createGrant(`{"name": "VIP daily gems", "amount": "100", "description": "VIP allowance", "account": "vip", "interval_seconds": 86400}`)
+	assert.Equal(t, models.GrantStatusActive, daily.Status)
+	assert.Equal(t, models.CatchUpAll, daily.CatchUp)
+	assert.Equal(t, "faucet:vip", daily.Account)
+
+	enrollment := enroll(daily.ID, `{"wallet_id": "vip", "end_at": "`+subscriptionEnd+`"}`)
+	assert.Equal(t, models.EnrollmentStatusActive, enrollment.Status)
+	assert.NotNil(t, enrollment.NextRunAt)
+
+	w := sendRequest(router, "POST", "/api/v1/grants/"+daily.ID+"/enrollments", `{"wallet_id": "vip"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	err = dbManager.RunGrants(now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("100"), balance("vip"))
+
+	ran, err := db.RunGrants(now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ran)
+
+	// After downtime every missed day is caught up, and the enrollment ends with the subscription
+	ran, err = db.RunGrants(now.Add(31 * 24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 29, ran)
+	assert.Equal(t, models.MustParseAmount("3000"), balance("vip"))
+
+	enrollment = getEnrollment(daily.ID, "vip")
+	assert.Equal(t, models.EnrollmentStatusEnded, enrollment.Status)
+	assert.Equal(t, 30, enrollment.Granted)
+	assert.Nil(t, enrollment.NextRunAt)
+
+	// The history links every run to the transaction it recorded
+	w = sendRequest(router, "GET", "/api/v1/grants/"+daily.ID+"/runs?wallet_id=vip", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var runs GrantRunsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &runs)
+	assert.NoError(t, err)
+	assert.Len(t, runs.Runs, 30)
+	assert.Equal(t, models.GrantRunStatusGranted, runs.Runs[0].Status)
+	assert.True(t, runs.Runs[0].DueAt.Before(runs.Runs[1].DueAt))
+
+	tx, err := db.GetTransaction(runs.Runs[0].TransactionID)
+	assert.NoError(t, err)
+	assert.Equal(t, daily.ID, tx.GrantID)
+	assert.Equal(t, "faucet:vip", tx.CounterpartyWalletID)
+
+	// The latest policy grants only the most recent missed occurrence
+	hourly := createGrant(`{"name": "Hourly bonus", "amount": "10", "description": "Hourly bonus", "interval_seconds": 3600, "catch_up": "latest"}`)
+	enroll(hourly.ID, `{"wallet_id": "casual"}`)
+
+	ran, err = db.RunGrants(now.Add(5*time.Hour + time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, models.MustParseAmount("10"), balance("casual"))
+	assert.Equal(t, 5, getEnrollment(hourly.ID, "casual").Skipped)
+
+	// The skip policy grants nothing that is overdue by more than the grace period
+	skipping := createGrant(`{"name": "Login bonus", "amount": "10", "description": "Login bonus", "interval_seconds": 3600, "catch_up": "skip"}`)
+	enroll(skipping.ID, `{"wallet_id": "skipper"}`)
+
+	ran, err = db.RunGrants(now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+
+	_, err = db.RunGrants(now.Add(3*time.Hour + 10*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("10"), balance("skipper"))
+	assert.Equal(t, 3, getEnrollment(skipping.ID, "skipper").Skipped)
+
+	// Paused grants and enrollments credit nothing until they are resumed
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/pause", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/pause", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	ran, err = db.RunGrants(now.Add(4*time.Hour + time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ran)
+
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/resume", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	ran, err = db.RunGrants(now.Add(4*time.Hour + time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, models.MustParseAmount("20"), balance("skipper"))
+
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/enrollments/skipper/pause", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	ran, err = db.RunGrants(now.Add(5*time.Hour + time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ran)
+
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/enrollments/skipper/resume", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/enrollments/skipper/cancel", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants/"+skipping.ID+"/enrollments/skipper/cancel", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// An occurrence that breaks a limit of the wallet is recorded as failed and skipped over
+	cap := models.MustParseAmount("5")
+	_, err = db.SetWalletLimits("capped", "", models.WalletLimits{MaxBalance: &cap})
+	assert.NoError(t, err)
+
+	enroll(hourly.ID, `{"wallet_id": "capped"}`)
+	ran, err = db.RunGrants(now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+
+	enrollment = getEnrollment(hourly.ID, "capped")
+	assert.Equal(t, 1, enrollment.Failed)
+	assert.Equal(t, models.EnrollmentStatusActive, enrollment.Status)
+
+	runs = GrantRunsResponse{}
+	w = sendRequest(router, "GET", "/api/v1/grants/"+hourly.ID+"/runs?wallet_id=capped", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &runs)
+	assert.NoError(t, err)
+	assert.Len(t, runs.Runs, 1)
+	assert.Equal(t, models.GrantRunStatusFailed, runs.Runs[0].Status)
+	assert.NotEmpty(t, runs.Runs[0].Error)
+
+	// Cron schedules fall on their next match in UTC
+	noon := createGrant(`{"name": "Noon chest", "amount": "5", "description": "Noon chest", "cron": "0 12 * * *"}`)
+	enrollment = enroll(noon.ID, `{"wallet_id": "player"}`)
+	assert.Equal(t, 12, enrollment.NextRunAt.UTC().Hour())
+	assert.Equal(t, 0, enrollment.NextRunAt.UTC().Minute())
+	assert.True(t, enrollment.NextRunAt.After(now))
+
+	trial, err := db.TrialBalance()
+	assert.NoError(t, err)
+	for _, currency := range trial {
+		assert.True(t, currency.Balanced)
+	}
+
+	// Grants need exactly one valid schedule
+	w = sendRequest(router, "POST", "/api/v1/grants", `{"name": "Broken", "amount": "5", "description": "Broken", "interval_seconds": 60, "cron": "* * * * *"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants", `{"name": "Broken", "amount": "5", "description": "Broken", "cron": "61 * * * *"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants", `{"name": "Broken", "amount": "5", "description": "Broken"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants", `{"name": "Broken", "amount": "5", "description": "Broken", "interval_seconds": 10000000000}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/grants/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/grants/unknown/enrollments", `{"wallet_id": "player"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var grants GrantsResponse
+	w = sendRequest(router, "GET", "/api/v1/grants", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &grants)
+	assert.NoError(t, err)
+	assert.Len(t, grants.Grants, 4)
+}
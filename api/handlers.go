@@ -1,11 +1,15 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"virtigia-microcurrency/db"
 	"virtigia-microcurrency/middleware"
+	"virtigia-microcurrency/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +24,173 @@ func NewHandler(dbManager *db.DBManager) *Handler {
 	return &Handler{DBManager: dbManager}
 }
 
+// Headers used for idempotent requests
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// idempotencyOptions builds the write options for the request's Idempotency-Key header.
+// It writes an error response and returns false if the header is invalid.
+func idempotencyOptions(c *gin.Context) ([]db.WriteOption, bool) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Idempotency key must be at most 255 characters"})
+		return nil, false
+	}
+
+	return []db.WriteOption{db.WithIdempotencyKey(key)}, true
+}
+
+// Headers used for conditional writes
+const (
+	IfMatchHeader = "If-Match"
+	ETagHeader    = "ETag"
+)
+
+// versionOptions builds the write options for a request's If-Match header or
+// expected_version field. It writes an error response and returns false if the
+// header is malformed or disagrees with the field.
+func versionOptions(c *gin.Context, expected *int64) ([]db.WriteOption, bool) {
+	if header := c.GetHeader(IfMatchHeader); header != "" && header != "*" {
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "If-Match must be a wallet ETag"})
+			return nil, false
+		}
+
+		if expected != nil && *expected != version {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "If-Match and expected_version disagree"})
+			return nil, false
+		}
+		expected = &version
+	}
+
+	if expected == nil {
+		return nil, true
+	}
+
+	return []db.WriteOption{db.WithExpectedVersion(*expected)}, true
+}
+
+// setETag tags a response with the version of the wallet it describes
+func setETag(c *gin.Context, wallet *models.Wallet) {
+	c.Header(ETagHeader, `"`+strconv.FormatInt(wallet.Version, 10)+`"`)
+}
+
+// currencyParam reads the currency code from the route. Routes without one use
+// the default currency and return an empty code. It writes an error response and
+// returns false if the code is invalid.
+func currencyParam(c *gin.Context) (string, bool) {
+	code := c.Param("currency")
+	if code == "" {
+		return "", true
+	}
+
+	currency, err := models.ParseCurrency(code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
+		return "", false
+	}
+
+	return currency, true
+}
+
+// defaultPageLimit is the page size of a listing that asks for none, and
+// maxPageLimit the largest page size a listing returns
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// pageLimit reads the limit query parameter of a listing, falling back to
+// defaultPageLimit if it is missing or invalid and capping it at maxPageLimit
+func pageLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+// currencyRuleErrors are the responses for writes that break the rules of their
+// currency or the limits of their wallet, or that the status of their wallet refuses
+var currencyRuleErrors = map[error]string{
+	db.ErrUnknownCurrency:         "Currency is not registered",
+	db.ErrCurrencyPrecision:       "Amount has more decimal places than the currency allows",
+	db.ErrAmountBelowMinimum:      "Amount is below the minimum transaction of the currency",
+	db.ErrAmountAboveMaximum:      "Amount is above the maximum transaction of the currency",
+	db.ErrBalanceLimitExceeded:    "Balance would exceed the maximum balance of the currency",
+	db.ErrCurrencyNotTransferable: "Currency cannot be transferred between wallets",
+	db.ErrWalletTransactionLimit:  "Amount is above the maximum transaction of the wallet",
+	db.ErrWalletBalanceLimit:      "Balance would exceed the maximum balance of the wallet",
+	db.ErrWalletDebitsFrozen:      "Wallet is frozen for debits",
+	db.ErrWalletFrozen:            "Wallet is frozen",
+	db.ErrWalletClosed:            "Wallet is closed",
+}
+
+// ruleErrorCodes are the error codes of writes that exceed a cap or that the status
+// of their wallet refuses, so that clients can tell them apart without parsing messages
+var ruleErrorCodes = map[error]string{
+	db.ErrAmountAboveMaximum:     "currency_transaction_limit",
+	db.ErrBalanceLimitExceeded:   "currency_balance_limit",
+	db.ErrWalletTransactionLimit: "wallet_transaction_limit",
+	db.ErrWalletBalanceLimit:     "wallet_balance_limit",
+	db.ErrWalletDebitsFrozen:     "wallet_debits_frozen",
+	db.ErrWalletFrozen:           "wallet_frozen",
+	db.ErrWalletClosed:           "wallet_closed",
+}
+
+// amountOverflowMessage is the response for writes that would take a balance beyond
+// the largest amount that can be stored
+const amountOverflowMessage = "Balance would exceed the largest amount that can be stored"
+
+// currencyRuleError writes a bad request response if err breaks a rule of the
+// currency or a limit of the wallet, or an unprocessable entity response if the
+// balance would overflow, and reports whether it did
+func currencyRuleError(c *gin.Context, err error) bool {
+	if err == db.ErrAmountOverflow {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: amountOverflowMessage, Code: "amount_overflow"})
+		return true
+	}
+
+	message, ok := currencyRuleErrors[err]
+	if ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: message, Code: ruleErrorCodes[err]})
+	}
+	return ok
+}
+
+// systemAccountOptions builds the write options for the system account named in a request.
+// It writes an error response and returns false if the wallet is itself a system
+// account or the name is invalid.
+func systemAccountOptions(c *gin.Context, walletID, account string) ([]db.WriteOption, bool) {
+	if models.IsSystemAccount(walletID) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "System accounts cannot be credited or debited directly"})
+		return nil, false
+	}
+
+	if account == "" {
+		return nil, true
+	}
+
+	name, err := models.ParseSystemAccountName(account)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid account name"})
+		return nil, false
+	}
+
+	return []db.WriteOption{db.WithSystemAccount(name)}, true
+}
+
 // getDB returns the database for the current environment
 func (h *Handler) getDB(c *gin.Context) (*db.DB, error) {
 	env := middleware.GetEnvironment(c)
@@ -28,19 +199,28 @@ func (h *Handler) getDB(c *gin.Context) (*db.DB, error) {
 
 // AddCurrency adds currency to a wallet
 // @Summary Add currency to a wallet
-// @Description Add currency to a wallet and record the transaction
+// @Description Issue currency from a faucet system account into a wallet and record both entries.
+// @Description Routes without a currency code use the default currency.
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Param X-ENV header string false "Environment (default: production)"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original result"
+// @Param If-Match header string false "Wallet ETag the write is conditional on"
 // @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
 // @Param request body AddCurrencyRequest true "Add currency request"
 // @Success 200 {object} TransactionResponse
+// @Header 200 {string} ETag "Version of the wallet after the write"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /wallets/{wallet_id}/add [post]
+// @Router /wallets/{wallet_id}/currencies/{currency}/add [post]
 func (h *Handler) AddCurrency(c *gin.Context) {
 	walletID := c.Param("wallet_id")
 	if walletID == "" {
@@ -59,6 +239,35 @@ func (h *Handler) AddCurrency(c *gin.Context) {
 		return
 	}
 
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	writeOptions, ok := idempotencyOptions(c)
+	if !ok {
+		return
+	}
+
+	accountOptions, ok := systemAccountOptions(c, walletID, req.Account)
+	if !ok {
+		return
+	}
+	writeOptions = append(writeOptions, accountOptions...)
+
+	conditionOptions, ok := versionOptions(c, req.ExpectedVersion)
+	if !ok {
+		return
+	}
+	writeOptions = append(writeOptions, conditionOptions...)
+
+	if req.Clamp {
+		writeOptions = append(writeOptions, db.WithClamp())
+	}
+	if req.ExpiresAt != nil {
+		writeOptions = append(writeOptions, db.WithExpiry(*req.ExpiresAt))
+	}
+
 	// Get database for current environment
 	database, err := h.getDB(c)
 	if err != nil {
@@ -67,41 +276,68 @@ func (h *Handler) AddCurrency(c *gin.Context) {
 	}
 
 	// Add currency to wallet
-	tx, err := database.AddCurrency(walletID, req.Amount, req.Description, req.AdditionalData)
+	result, err := database.AddCurrency(walletID, currency, req.Amount, req.Description, req.AdditionalData, writeOptions...)
 	if err != nil {
+		if err == db.ErrIdempotencyConflict {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "Idempotency key was already used with a different request"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if err == db.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Wallet has changed since the expected version"})
+			return
+		}
+		if err == db.ErrInvalidExpiry {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Expiry must be in the future"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to add currency: " + err.Error()})
 		return
 	}
 
-	// Get updated wallet
-	wallet, err := database.GetWallet(walletID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet: " + err.Error()})
-		return
+	// Mark responses that repeat an earlier request
+	if result.Replayed {
+		c.Header(IdempotentReplayedHeader, "true")
 	}
+	setETag(c, result.Wallet)
 
 	// Return response
 	c.JSON(http.StatusOK, TransactionResponse{
-		Transaction: tx,
-		Wallet:      wallet,
+		Transaction: result.Transaction,
+		Wallet:      result.Wallet,
 	})
 }
 
 // RemoveCurrency removes currency from a wallet
 // @Summary Remove currency from a wallet
-// @Description Remove currency from a wallet and record the transaction
+// @Description Pay currency from a wallet into a sink system account and record both entries.
+// @Description Routes without a currency code use the default currency.
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Param X-ENV header string false "Environment (default: production)"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original result"
+// @Param If-Match header string false "Wallet ETag the write is conditional on"
 // @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
 // @Param request body RemoveCurrencyRequest true "Remove currency request"
 // @Success 200 {object} TransactionResponse
+// @Header 200 {string} ETag "Version of the wallet after the write"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /wallets/{wallet_id}/remove [post]
+// @Router /wallets/{wallet_id}/currencies/{currency}/remove [post]
 func (h *Handler) RemoveCurrency(c *gin.Context) {
 	walletID := c.Param("wallet_id")
 	if walletID == "" {
@@ -120,6 +356,28 @@ func (h *Handler) RemoveCurrency(c *gin.Context) {
 		return
 	}
 
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	writeOptions, ok := idempotencyOptions(c)
+	if !ok {
+		return
+	}
+
+	accountOptions, ok := systemAccountOptions(c, walletID, req.Account)
+	if !ok {
+		return
+	}
+	writeOptions = append(writeOptions, accountOptions...)
+
+	conditionOptions, ok := versionOptions(c, req.ExpectedVersion)
+	if !ok {
+		return
+	}
+	writeOptions = append(writeOptions, conditionOptions...)
+
 	// Get database for current environment
 	database, err := h.getDB(c)
 	if err != nil {
@@ -128,44 +386,64 @@ func (h *Handler) RemoveCurrency(c *gin.Context) {
 	}
 
 	// Remove currency from wallet
-	tx, err := database.RemoveCurrency(walletID, req.Amount, req.Description, req.AdditionalData)
+	result, err := database.RemoveCurrency(walletID, currency, req.Amount, req.Description, req.AdditionalData, writeOptions...)
 	if err != nil {
+		if err == db.ErrIdempotencyConflict {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "Idempotency key was already used with a different request"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if err == db.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Wallet has changed since the expected version"})
+			return
+		}
 		if err == db.ErrInsufficientFunds {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Insufficient funds"})
 			return
 		}
+		if currencyRuleError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to remove currency: " + err.Error()})
 		return
 	}
 
-	// Get updated wallet
-	wallet, err := database.GetWallet(walletID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet: " + err.Error()})
-		return
+	// Mark responses that repeat an earlier request
+	if result.Replayed {
+		c.Header(IdempotentReplayedHeader, "true")
 	}
+	setETag(c, result.Wallet)
 
 	// Return response
 	c.JSON(http.StatusOK, TransactionResponse{
-		Transaction: tx,
-		Wallet:      wallet,
+		Transaction: result.Transaction,
+		Wallet:      result.Wallet,
 	})
 }
 
 // GetWalletBalance gets the balance of a wallet
 // @Summary Get wallet balance
-// @Description Get the balance of a wallet
+// @Description Get the balance of a wallet in one currency. Routes without a currency code use the default currency.
+// @Description The available balance is the balance less active holds. Wallets with a credit limit also report how much credit is left.
+// @Description Pass at to get the balance at that instant together with the last transaction applied at or before it.
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Param X-ENV header string false "Environment (default: production)"
 // @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
+// @Param at query string false "Return the balance as of this time (RFC3339)"
 // @Success 200 {object} WalletBalanceResponse
+// @Header 200 {string} ETag "Version of the wallet"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /wallets/{wallet_id}/balance [get]
+// @Router /wallets/{wallet_id}/currencies/{currency}/balance [get]
 func (h *Handler) GetWalletBalance(c *gin.Context) {
 	walletID := c.Param("wallet_id")
 	if walletID == "" {
@@ -173,6 +451,11 @@ func (h *Handler) GetWalletBalance(c *gin.Context) {
 		return
 	}
 
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
 	// Get database for current environment
 	database, err := h.getDB(c)
 	if err != nil {
@@ -180,38 +463,135 @@ func (h *Handler) GetWalletBalance(c *gin.Context) {
 		return
 	}
 
+	// Get the historical balance if a time was requested
+	if value := c.Query("at"); value != "" {
+		at, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "at must be an RFC3339 time"})
+			return
+		}
+
+		historical, err := database.GetWalletBalanceAt(walletID, currency, at)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet balance: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, WalletBalanceResponse{
+			WalletID:        walletID,
+			Currency:        historical.Currency,
+			Balance:         historical.Balance,
+			At:              &at,
+			LastTransaction: historical.LastTransaction,
+		})
+		return
+	}
+
 	// Get wallet balance
-	balance, err := database.GetWalletBalance(walletID)
+	wallet, available, err := database.GetAvailableBalance(walletID, currency)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet balance: " + err.Error()})
 		return
 	}
 
+	response := WalletBalanceResponse{
+		WalletID:  walletID,
+		Currency:  wallet.Currency,
+		Balance:   wallet.Balance,
+		Available: &available,
+		Version:   &wallet.Version,
+	}
+	if wallet.CreditLimit > 0 {
+		availableCredit := wallet.AvailableCredit(available)
+		response.CreditLimit = &wallet.CreditLimit
+		response.AvailableCredit = &availableCredit
+	}
+
 	// Return response
-	c.JSON(http.StatusOK, WalletBalanceResponse{
+	setETag(c, wallet)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWalletBalances gets the balances of a wallet in every currency
+// @Summary Get wallet balances
+// @Description Get the balance of a wallet in every currency it has held
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} WalletBalancesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/balances [get]
+func (h *Handler) GetWalletBalances(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get balances in every currency
+	wallets, err := database.GetWalletBalances(walletID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet balances: " + err.Error()})
+		return
+	}
+
+	balances := make([]CurrencyBalance, 0, len(wallets))
+	for _, wallet := range wallets {
+		balances = append(balances, CurrencyBalance{Currency: wallet.Currency, Balance: wallet.Balance})
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, WalletBalancesResponse{
 		WalletID: walletID,
-		Balance:  balance,
+		Balances: balances,
 	})
 }
 
 // GetTransactionHistory gets the transaction history for a wallet
 // @Summary Get transaction history
-// @Description Get the transaction history for a wallet with pagination
+// @Description Get the transaction history of a wallet in one currency with pagination.
+// @Description Routes without a currency code use the default currency.
+// @Description Pass next_cursor or prev_cursor from a previous response as cursor to move between pages;
+// @Description a cursor keeps the sort order of the page it came from and ignores offset.
+// @Description Filter on additional data with additional_data.<key>=<value>, e.g. additional_data.item_id=item789;
+// @Description several such parameters must all match.
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Param X-ENV header string false "Environment (default: production)"
 // @Param wallet_id path string true "Wallet ID"
-// @Param limit query int false "Limit" default(50)
+// @Param currency path string true "Currency code"
+// @Param limit query int false "Limit, at most 500" default(50) maximum(500)
 // @Param offset query int false "Offset" default(0)
 // @Param sort_by query string false "Sort by" default("timestamp")
 // @Param sort_order query string false "Sort order" default("DESC")
+// @Param cursor query string false "Cursor from a previous page"
+// @Param from query string false "Only transactions at or after this time (RFC3339)"
+// @Param to query string false "Only transactions at or before this time (RFC3339)"
+// @Param direction query string false "Only credits or only debits" Enums(credit, debit)
+// @Param min_amount query string false "Minimum signed amount, inclusive"
+// @Param max_amount query string false "Maximum signed amount, inclusive"
+// @Param description query string false "Description contains this text, ignoring case"
 // @Success 200 {object} TransactionHistoryResponse
+// @Header 200 {string} ETag "Version of the wallet"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /wallets/{wallet_id}/transactions [get]
+// @Router /wallets/{wallet_id}/currencies/{currency}/transactions [get]
 func (h *Handler) GetTransactionHistory(c *gin.Context) {
 	walletID := c.Param("wallet_id")
 	if walletID == "" {
@@ -219,15 +599,15 @@ func (h *Handler) GetTransactionHistory(c *gin.Context) {
 		return
 	}
 
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
 	// Parse pagination parameters
-	limitStr := c.DefaultQuery("limit", "50")
+	limit := pageLimit(c)
 	offsetStr := c.DefaultQuery("offset", "0")
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 50
-	}
-
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
 		offset = 0
@@ -238,13 +618,20 @@ func (h *Handler) GetTransactionHistory(c *gin.Context) {
 	sortOrder := c.DefaultQuery("sort_order", "DESC")
 
 	// Validate sort_by parameter
-	if sortBy != "timestamp" && sortBy != "amount" {
-		sortBy = "timestamp"
+	if sortBy != db.SortByTimestamp && sortBy != db.SortByAmount {
+		sortBy = db.SortByTimestamp
 	}
 
 	// Validate and normalize sort_order parameter
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "DESC"
+	if sortOrder != db.SortAscending && sortOrder != db.SortDescending {
+		sortOrder = db.SortDescending
+	}
+
+	// Parse filters
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid filter: " + err.Error()})
+		return
 	}
 
 	// Get database for current environment
@@ -255,27 +642,89 @@ func (h *Handler) GetTransactionHistory(c *gin.Context) {
 	}
 
 	// Get transactions
-	transactions, err := database.GetTransactionsByWallet(walletID, limit, offset, sortBy, sortOrder)
+	page, err := database.GetTransactionsByWallet(walletID, currency, db.TransactionQuery{
+		Limit:     limit,
+		Offset:    offset,
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		Cursor:    c.Query("cursor"),
+		Filter:    filter,
+	})
 	if err != nil {
+		if err == db.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transactions: " + err.Error()})
 		return
 	}
 
 	// Get wallet
-	wallet, err := database.GetWallet(walletID)
+	wallet, err := database.GetWallet(walletID, currency)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet: " + err.Error()})
 		return
 	}
 
 	// Return response
+	setETag(c, wallet)
 	c.JSON(http.StatusOK, TransactionHistoryResponse{
-		Transactions: transactions,
+		Transactions: page.Transactions,
 		Wallet:       wallet,
 		Pagination: Pagination{
-			Limit:  limit,
-			Offset: offset,
-			Count:  len(transactions),
+			Limit:      limit,
+			Offset:     offset,
+			Count:      len(page.Transactions),
+			SortBy:     page.SortBy,
+			SortOrder:  page.SortOrder,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
 		},
 	})
 }
+
+// additionalDataParamPrefix prefixes query parameters that filter on additional data
+const additionalDataParamPrefix = "additional_data."
+
+// parseTransactionFilter reads the transaction history filters from the query string
+func parseTransactionFilter(c *gin.Context) (db.TransactionFilter, error) {
+	var filter db.TransactionFilter
+
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC3339 time", name)
+			}
+			*target = parsed
+		}
+	}
+
+	filter.Direction = c.Query("direction")
+	if filter.Direction != "" && filter.Direction != db.DirectionCredit && filter.Direction != db.DirectionDebit {
+		return filter, fmt.Errorf("direction must be %q or %q", db.DirectionCredit, db.DirectionDebit)
+	}
+
+	for name, target := range map[string]**models.Amount{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		if value := c.Query(name); value != "" {
+			parsed, err := models.ParseAmount(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s: %v", name, err)
+			}
+			*target = &parsed
+		}
+	}
+
+	filter.Description = c.Query("description")
+
+	for name, values := range c.Request.URL.Query() {
+		if key := strings.TrimPrefix(name, additionalDataParamPrefix); key != name && key != "" {
+			if filter.AdditionalData == nil {
+				filter.AdditionalData = make(map[string]string)
+			}
+			filter.AdditionalData[key] = values[0]
+		}
+	}
+
+	return filter, nil
+}
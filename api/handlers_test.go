@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
 )
 
 func setupTestEnvironment(t *testing.T) (*gin.Engine, *db.DBManager, func()) {
@@ -44,6 +46,26 @@ func setupTestEnvironment(t *testing.T) (*gin.Engine, *db.DBManager, func()) {
 	return router, dbManager, cleanup
 }
 
+// sendRequest serves an authenticated JSON request to the test environment and returns the response
+func sendRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	return sendRequestWithHeader(router, method, path, body, nil)
+}
+
+// sendRequestWithHeader is like sendRequest but also sets the given headers
+func sendRequestWithHeader(router *gin.Engine, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+	for name, value := range header {
+		httpReq.Header.Set(name, value)
+	}
+
+	router.ServeHTTP(w, httpReq)
+	return w
+}
+
 func TestAddCurrency(t *testing.T) {
 	router, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -52,7 +74,7 @@ func TestAddCurrency(t *testing.T) {
 
 	// Create request
 	req := AddCurrencyRequest{
-		Amount:      100.0,
+		Amount:      models.MustParseAmount("100"),
 		Description: "Test deposit",
 	}
 	reqBody, _ := json.Marshal(req)
@@ -94,12 +116,12 @@ func TestRemoveCurrency(t *testing.T) {
 	assert.NoError(t, err)
 
 	// First add currency
-	_, err = db.AddCurrency(walletID, 100.0, "Initial deposit", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("100"), "Initial deposit", nil)
 	assert.NoError(t, err)
 
 	// Create request to remove currency
 	req := RemoveCurrencyRequest{
-		Amount:      50.0,
+		Amount:      models.MustParseAmount("50"),
 		Description: "Test withdrawal",
 	}
 	reqBody, _ := json.Marshal(req)
@@ -125,9 +147,11 @@ func TestRemoveCurrency(t *testing.T) {
 	// Check response data
 	assert.Equal(t, walletID, resp.Transaction.WalletID)
 	assert.Equal(t, -req.Amount, resp.Transaction.Amount) // Negative amount for removal
+	assert.Equal(t, models.MustParseAmount("100"), resp.Transaction.BalanceBefore)
+	assert.Equal(t, models.MustParseAmount("50"), resp.Transaction.BalanceAfter)
 	assert.Equal(t, req.Description, resp.Transaction.Description)
 	assert.Equal(t, walletID, resp.Wallet.WalletID)
-	assert.Equal(t, 50.0, resp.Wallet.Balance) // 100 - 50 = 50
+	assert.Equal(t, models.MustParseAmount("50"), resp.Wallet.Balance) // 100 - 50 = 50
 }
 
 func TestGetWalletBalance(t *testing.T) {
@@ -141,7 +165,7 @@ func TestGetWalletBalance(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Add some currency to the wallet
-	_, err = db.AddCurrency(walletID, 100.0, "Initial deposit", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("100"), "Initial deposit", nil)
 	assert.NoError(t, err)
 
 	// Create request
@@ -163,7 +187,56 @@ func TestGetWalletBalance(t *testing.T) {
 
 	// Check response data
 	assert.Equal(t, walletID, resp.WalletID)
-	assert.Equal(t, 100.0, resp.Balance)
+	assert.Equal(t, models.MustParseAmount("100"), resp.Balance)
+}
+
+func TestGetWalletBalanceAt(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	deposit, err := db.AddCurrency(walletID, "", models.MustParseAmount("100"), "Initial deposit", nil)
+	assert.NoError(t, err)
+
+	time.Sleep(2 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = db.RemoveCurrency(walletID, "", models.MustParseAmount("30"), "Purchase", nil)
+	assert.NoError(t, err)
+
+	// The balance in between only reflects the deposit
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/balance?at="+url.QueryEscape(middle.Format(time.RFC3339Nano)), nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp WalletBalanceResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, walletID, resp.WalletID)
+	assert.Equal(t, models.MustParseAmount("100"), resp.Balance)
+	if assert.NotNil(t, resp.LastTransaction) {
+		assert.Equal(t, deposit.Transaction.ID, resp.LastTransaction.ID)
+	}
+
+	// Invalid times are rejected
+	w = httptest.NewRecorder()
+	httpReq, _ = http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/balance?at=yesterday", nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestGetTransactionHistory(t *testing.T) {
@@ -178,7 +251,7 @@ func TestGetTransactionHistory(t *testing.T) {
 
 	// Add some transactions
 	for i := 0; i < 5; i++ {
-		_, err := db.AddCurrency(walletID, 10.0, "Test transaction", nil)
+		_, err := db.AddCurrency(walletID, "", models.MustParseAmount("10"), "Test transaction", nil)
 		assert.NoError(t, err)
 	}
 
@@ -201,7 +274,7 @@ func TestGetTransactionHistory(t *testing.T) {
 
 	// Check response data
 	assert.Equal(t, walletID, resp.Wallet.WalletID)
-	assert.Equal(t, 50.0, resp.Wallet.Balance) // 5 * 10 = 50
+	assert.Equal(t, models.MustParseAmount("50"), resp.Wallet.Balance) // 5 * 10 = 50
 	assert.Equal(t, 5, len(resp.Transactions))
 	assert.Equal(t, 5, resp.Pagination.Count)
 }
@@ -245,16 +318,16 @@ func TestGetTransactionHistorySortingByTimestamp(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Add transactions with different timestamps (simulate by adding them sequentially)
-	_, err = db.AddCurrency(walletID, 10.0, "Transaction 1", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("10"), "Transaction 1", nil)
 	assert.NoError(t, err)
 
 	// Small delay to ensure different timestamps
 	time.Sleep(1 * time.Millisecond)
-	_, err = db.AddCurrency(walletID, 20.0, "Transaction 2", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("20"), "Transaction 2", nil)
 	assert.NoError(t, err)
 
 	time.Sleep(1 * time.Millisecond)
-	_, err = db.AddCurrency(walletID, 30.0, "Transaction 3", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("30"), "Transaction 3", nil)
 	assert.NoError(t, err)
 
 	// Test DESC sorting (default)
@@ -272,9 +345,9 @@ func TestGetTransactionHistorySortingByTimestamp(t *testing.T) {
 
 	// Should be sorted by timestamp DESC (newest first)
 	assert.Equal(t, 3, len(resp.Transactions))
-	assert.Equal(t, 30.0, resp.Transactions[0].Amount) // Newest first
-	assert.Equal(t, 20.0, resp.Transactions[1].Amount)
-	assert.Equal(t, 10.0, resp.Transactions[2].Amount)
+	assert.Equal(t, models.MustParseAmount("30"), resp.Transactions[0].Amount) // Newest first
+	assert.Equal(t, models.MustParseAmount("20"), resp.Transactions[1].Amount)
+	assert.Equal(t, models.MustParseAmount("10"), resp.Transactions[2].Amount)
 
 	// Test ASC sorting
 	w = httptest.NewRecorder()
@@ -290,9 +363,9 @@ func TestGetTransactionHistorySortingByTimestamp(t *testing.T) {
 
 	// Should be sorted by timestamp ASC (oldest first)
 	assert.Equal(t, 3, len(resp.Transactions))
-	assert.Equal(t, 10.0, resp.Transactions[0].Amount) // Oldest first
-	assert.Equal(t, 20.0, resp.Transactions[1].Amount)
-	assert.Equal(t, 30.0, resp.Transactions[2].Amount)
+	assert.Equal(t, models.MustParseAmount("10"), resp.Transactions[0].Amount) // Oldest first
+	assert.Equal(t, models.MustParseAmount("20"), resp.Transactions[1].Amount)
+	assert.Equal(t, models.MustParseAmount("30"), resp.Transactions[2].Amount)
 }
 
 func TestGetTransactionHistorySortingByAmount(t *testing.T) {
@@ -306,13 +379,13 @@ func TestGetTransactionHistorySortingByAmount(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Add transactions with different amounts
-	_, err = db.AddCurrency(walletID, 30.0, "Large transaction", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("30"), "Large transaction", nil)
 	assert.NoError(t, err)
 
-	_, err = db.AddCurrency(walletID, 10.0, "Small transaction", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("10"), "Small transaction", nil)
 	assert.NoError(t, err)
 
-	_, err = db.AddCurrency(walletID, 20.0, "Medium transaction", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("20"), "Medium transaction", nil)
 	assert.NoError(t, err)
 
 	// Test DESC sorting by amount
@@ -330,9 +403,9 @@ func TestGetTransactionHistorySortingByAmount(t *testing.T) {
 
 	// Should be sorted by amount DESC (highest first)
 	assert.Equal(t, 3, len(resp.Transactions))
-	assert.Equal(t, 30.0, resp.Transactions[0].Amount) // Highest first
-	assert.Equal(t, 20.0, resp.Transactions[1].Amount)
-	assert.Equal(t, 10.0, resp.Transactions[2].Amount)
+	assert.Equal(t, models.MustParseAmount("30"), resp.Transactions[0].Amount) // Highest first
+	assert.Equal(t, models.MustParseAmount("20"), resp.Transactions[1].Amount)
+	assert.Equal(t, models.MustParseAmount("10"), resp.Transactions[2].Amount)
 
 	// Test ASC sorting by amount
 	w = httptest.NewRecorder()
@@ -348,9 +421,9 @@ func TestGetTransactionHistorySortingByAmount(t *testing.T) {
 
 	// Should be sorted by amount ASC (lowest first)
 	assert.Equal(t, 3, len(resp.Transactions))
-	assert.Equal(t, 10.0, resp.Transactions[0].Amount) // Lowest first
-	assert.Equal(t, 20.0, resp.Transactions[1].Amount)
-	assert.Equal(t, 30.0, resp.Transactions[2].Amount)
+	assert.Equal(t, models.MustParseAmount("10"), resp.Transactions[0].Amount) // Lowest first
+	assert.Equal(t, models.MustParseAmount("20"), resp.Transactions[1].Amount)
+	assert.Equal(t, models.MustParseAmount("30"), resp.Transactions[2].Amount)
 }
 
 func TestGetTransactionHistoryPaginationWithSorting(t *testing.T) {
@@ -365,7 +438,7 @@ func TestGetTransactionHistoryPaginationWithSorting(t *testing.T) {
 
 	// Add multiple transactions
 	for i := 1; i <= 10; i++ {
-		_, err := db.AddCurrency(walletID, float64(i*10), "Transaction "+strconv.Itoa(i), nil)
+		_, err := db.AddCurrency(walletID, "", models.MustParseAmount(strconv.Itoa(i*10)), "Transaction "+strconv.Itoa(i), nil)
 		assert.NoError(t, err)
 		time.Sleep(1 * time.Millisecond) // Ensure different timestamps
 	}
@@ -392,9 +465,9 @@ func TestGetTransactionHistoryPaginationWithSorting(t *testing.T) {
 	// Should be sorted by amount DESC and paginated correctly
 	// Full sorted list would be: [100, 90, 80, 70, 60, 50, 40, 30, 20, 10]
 	// With offset 2, limit 3: [80, 70, 60]
-	assert.Equal(t, 80.0, resp.Transactions[0].Amount)
-	assert.Equal(t, 70.0, resp.Transactions[1].Amount)
-	assert.Equal(t, 60.0, resp.Transactions[2].Amount)
+	assert.Equal(t, models.MustParseAmount("80"), resp.Transactions[0].Amount)
+	assert.Equal(t, models.MustParseAmount("70"), resp.Transactions[1].Amount)
+	assert.Equal(t, models.MustParseAmount("60"), resp.Transactions[2].Amount)
 }
 
 func TestGetTransactionHistoryEdgeCases(t *testing.T) {
@@ -424,7 +497,7 @@ func TestGetTransactionHistoryEdgeCases(t *testing.T) {
 	assert.Equal(t, 0, resp.Pagination.Count)
 
 	// Add one transaction
-	_, err = db.AddCurrency(walletID, 50.0, "Single transaction", nil)
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("50"), "Single transaction", nil)
 	assert.NoError(t, err)
 
 	// Test invalid sort_by parameter (should default to timestamp)
@@ -440,7 +513,7 @@ func TestGetTransactionHistoryEdgeCases(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, 1, len(resp.Transactions))
-	assert.Equal(t, 50.0, resp.Transactions[0].Amount)
+	assert.Equal(t, models.MustParseAmount("50"), resp.Transactions[0].Amount)
 
 	// Test invalid sort_order parameter (should default to DESC)
 	w = httptest.NewRecorder()
@@ -455,7 +528,7 @@ func TestGetTransactionHistoryEdgeCases(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, 1, len(resp.Transactions))
-	assert.Equal(t, 50.0, resp.Transactions[0].Amount)
+	assert.Equal(t, models.MustParseAmount("50"), resp.Transactions[0].Amount)
 
 	// Test offset beyond available data
 	w = httptest.NewRecorder()
@@ -471,4 +544,485 @@ func TestGetTransactionHistoryEdgeCases(t *testing.T) {
 
 	assert.Equal(t, 0, len(resp.Transactions))
 	assert.Equal(t, 0, resp.Pagination.Count)
+
+	// Test limit beyond the maximum page size (should be capped)
+	w = sendRequest(router, "GET", "/api/v1/wallets/"+walletID+"/transactions?limit=100000", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(resp.Transactions))
+	assert.Equal(t, maxPageLimit, resp.Pagination.Limit)
+}
+
+func TestAddCurrencyExactDecimals(t *testing.T) {
+	router, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	// Ten rewards of 0.1 must add up to exactly 1.00
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("POST", "/api/v1/wallets/"+walletID+"/add", bytes.NewBufferString(`{"amount": "0.10", "description": "Reward"}`))
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer test-token")
+		httpReq.Header.Set("X-ENV", "test")
+
+		router.ServeHTTP(w, httpReq)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/balance", nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "DEFAULT", "balance": "1.00", "available": "1.00", "version": 10}`, w.Body.String())
+
+	// Amounts with more decimal places than configured are rejected
+	w = httptest.NewRecorder()
+	httpReq, _ = http.NewRequest("POST", "/api/v1/wallets/"+walletID+"/remove", bytes.NewBufferString(`{"amount": "0.001", "description": "Too precise"}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAddCurrencyOverflow(t *testing.T) {
+	router, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	// The largest amount fits once, but a second one would wrap the balance around
+	codes := []int{}
+	var body string
+	for i := 0; i < 2; i++ {
+		w := sendRequest(router, "POST", "/api/v1/wallets/"+walletID+"/add", `{"amount": "92233720368547758.07", "description": "Jackpot"}`)
+		codes = append(codes, w.Code)
+		body = w.Body.String()
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusUnprocessableEntity}, codes)
+
+	var errorResponse ErrorResponse
+	err := json.Unmarshal([]byte(body), &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "amount_overflow", errorResponse.Code)
+
+	// Nothing of the refused write was applied
+	w := sendRequest(router, "GET", "/api/v1/wallets/"+walletID+"/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "DEFAULT", "balance": "92233720368547758.07", "available": "92233720368547758.07", "version": 1}`, w.Body.String())
+
+	// Transfers cannot credit a full wallet either, even from a wallet that may go negative
+	for _, request := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"PUT", "/api/v1/currencies/TOKENS", `{"allow_negative": true}`, http.StatusOK},
+		{"POST", "/api/v1/wallets/" + walletID + "/currencies/TOKENS/add", `{"amount": "92233720368547758.07", "description": "Jackpot"}`, http.StatusOK},
+		{"POST", "/api/v1/transfers", `{"from_wallet_id": "other", "to_wallet_id": "wallet123", "currency": "TOKENS", "amount": "1.00", "description": "Gift"}`, http.StatusUnprocessableEntity},
+	} {
+		w = sendRequest(router, request.method, request.path, request.body)
+		assert.Equal(t, request.code, w.Code, request.path)
+	}
+}
+
+func TestAddCurrencyIdempotencyKey(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	send := func(body string) *httptest.ResponseRecorder {
+		return sendRequestWithHeader(router, "POST", "/api/v1/wallets/"+walletID+"/add", body, map[string]string{IdempotencyKeyHeader: "reward-42"})
+	}
+
+	// First request applies the credit
+	w := send(`{"amount": "10", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var first TransactionResponse
+	err := json.Unmarshal(w.Body.Bytes(), &first)
+	assert.NoError(t, err)
+
+	// A retry returns the original response without crediting again
+	w = send(`{"amount": "10", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+
+	var replay TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &replay)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Transaction.ID, replay.Transaction.ID)
+	assert.Equal(t, models.MustParseAmount("10"), replay.Wallet.Balance)
+
+	// Reusing the key for a different request is rejected
+	w = send(`{"amount": "99", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	balance, err := db.GetWalletBalance(walletID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("10"), balance)
+}
+
+func TestAddCurrencyConcurrentWrites(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+	const requests = 20
+
+	// Concurrent credits to the same wallet conflict in the database and are retried
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			w := httptest.NewRecorder()
+			httpReq, _ := http.NewRequest("POST", "/api/v1/wallets/"+walletID+"/add", bytes.NewBufferString(`{"amount": "1", "description": "Concurrent"}`))
+			httpReq.Header.Set("Content-Type", "application/json")
+			httpReq.Header.Set("Authorization", "Bearer test-token")
+			httpReq.Header.Set("X-ENV", "test")
+			router.ServeHTTP(w, httpReq)
+			codes <- w.Code
+		}()
+	}
+
+	for i := 0; i < requests; i++ {
+		assert.Equal(t, http.StatusOK, <-codes)
+	}
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	balance, err := db.GetWalletBalance(walletID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount(strconv.Itoa(requests)), balance)
+
+	// Retries are visible through the stats endpoint
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/api/v1/stats", nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stats StatsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", stats.Environment)
+	assert.Equal(t, stats.Writes.Conflicts, stats.Writes.Retries)
+	assert.Zero(t, stats.Writes.Exhausted)
+}
+
+func TestGetTransactionHistoryCursorPagination(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	for i := 1; i <= 7; i++ {
+		_, err := db.AddCurrency(walletID, "", models.MustParseAmount(strconv.Itoa(i)), "Transaction "+strconv.Itoa(i), nil)
+		assert.NoError(t, err)
+	}
+
+	fetch := func(query string) TransactionHistoryResponse {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/transactions?"+query, nil)
+		httpReq.Header.Set("Authorization", "Bearer test-token")
+		httpReq.Header.Set("X-ENV", "test")
+
+		router.ServeHTTP(w, httpReq)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp TransactionHistoryResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	amounts := func(resp TransactionHistoryResponse) []string {
+		var result []string
+		for _, tx := range resp.Transactions {
+			result = append(result, tx.Amount.String())
+		}
+		return result
+	}
+
+	// Walk forward, newest first
+	first := fetch("limit=3")
+	assert.Equal(t, []string{"7.00", "6.00", "5.00"}, amounts(first))
+	assert.Empty(t, first.Pagination.PrevCursor)
+	assert.NotEmpty(t, first.Pagination.NextCursor)
+
+	second := fetch("limit=3&cursor=" + first.Pagination.NextCursor)
+	assert.Equal(t, []string{"4.00", "3.00", "2.00"}, amounts(second))
+	assert.NotEmpty(t, second.Pagination.PrevCursor)
+
+	last := fetch("limit=3&cursor=" + second.Pagination.NextCursor)
+	assert.Equal(t, []string{"1.00"}, amounts(last))
+	assert.Empty(t, last.Pagination.NextCursor)
+
+	// Walk back to the start
+	back := fetch("limit=3&cursor=" + last.Pagination.PrevCursor)
+	assert.Equal(t, []string{"4.00", "3.00", "2.00"}, amounts(back))
+
+	start := fetch("limit=3&cursor=" + back.Pagination.PrevCursor)
+	assert.Equal(t, []string{"7.00", "6.00", "5.00"}, amounts(start))
+	assert.Empty(t, start.Pagination.PrevCursor)
+
+	// Cursors keep the ordering they were created with
+	byAmount := fetch("limit=4&sort_by=amount&sort_order=ASC")
+	assert.Equal(t, []string{"1.00", "2.00", "3.00", "4.00"}, amounts(byAmount))
+
+	rest := fetch("limit=4&cursor=" + byAmount.Pagination.NextCursor)
+	assert.Equal(t, []string{"5.00", "6.00", "7.00"}, amounts(rest))
+	assert.Equal(t, "amount", rest.Pagination.SortBy)
+
+	// Malformed cursors are rejected
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/transactions?cursor=bogus", nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTransactionHistoryFilters(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("100"), "Quest reward", map[string]interface{}{"quest_id": "q1"})
+	assert.NoError(t, err)
+	_, err = db.RemoveCurrency(walletID, "", models.MustParseAmount("30"), "Shop purchase", map[string]interface{}{"item_id": "item789"})
+	assert.NoError(t, err)
+
+	time.Sleep(2 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("5"), "Daily QUEST bonus", nil)
+	assert.NoError(t, err)
+	_, err = db.RemoveCurrency(walletID, "", models.MustParseAmount("10"), "Shop purchase", map[string]interface{}{"item_id": "item123"})
+	assert.NoError(t, err)
+
+	fetch := func(query string) []string {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/transactions?"+query, nil)
+		httpReq.Header.Set("Authorization", "Bearer test-token")
+		httpReq.Header.Set("X-ENV", "test")
+
+		router.ServeHTTP(w, httpReq)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp TransactionHistoryResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+
+		var amounts []string
+		for _, tx := range resp.Transactions {
+			amounts = append(amounts, tx.Amount.String())
+		}
+		return amounts
+	}
+
+	assert.Equal(t, []string{"-10.00", "-30.00"}, fetch("direction=debit"))
+	assert.Equal(t, []string{"5.00", "100.00"}, fetch("direction=credit&sort_by=amount&sort_order=ASC"))
+	assert.Equal(t, []string{"100.00", "5.00"}, fetch("min_amount=5&sort_by=amount"))
+	assert.Equal(t, []string{"-10.00", "5.00"}, fetch("min_amount=-10&max_amount=5&sort_by=amount&sort_order=ASC"))
+	assert.Equal(t, []string{"5.00", "100.00"}, fetch("description=quest"))
+	assert.Equal(t, []string{"-30.00"}, fetch("additional_data.item_id=item789"))
+	assert.Equal(t, []string{"-10.00", "5.00"}, fetch("from="+middle.Format(time.RFC3339Nano)))
+	assert.Equal(t, []string{"-30.00", "100.00"}, fetch("to="+middle.Format(time.RFC3339Nano)))
+	assert.Equal(t, []string{"-10.00"}, fetch("from="+middle.Format(time.RFC3339Nano)+"&direction=debit&description=shop"))
+
+	// Filters apply before pagination
+	assert.Equal(t, []string{"-30.00"}, fetch("direction=debit&limit=1&offset=1"))
+
+	// Invalid filters are rejected
+	for _, query := range []string{"direction=sideways", "from=yesterday", "min_amount=abc"} {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/api/v1/wallets/"+walletID+"/transactions?"+query, nil)
+		httpReq.Header.Set("Authorization", "Bearer test-token")
+		httpReq.Header.Set("X-ENV", "test")
+		router.ServeHTTP(w, httpReq)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestMultiCurrencyWallet(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	walletID := "wallet123"
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	for _, code := range []string{"GOLD", "GEMS"} {
+		assert.NoError(t, db.SaveCurrency(&models.Currency{Code: code, Name: code, Decimals: 2, Transferable: true}))
+	}
+
+	// The legacy routes use the default currency
+	_, err = db.AddCurrency(walletID, "", models.MustParseAmount("5"), "Default deposit", nil)
+	assert.NoError(t, err)
+
+	// Codes are case-insensitive
+	w := sendRequest(router, "POST", "/api/v1/wallets/"+walletID+"/currencies/gold/add", `{"amount": "100", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "GOLD", resp.Transaction.Currency)
+	assert.Equal(t, "GOLD", resp.Wallet.Currency)
+	assert.Equal(t, models.MustParseAmount("100"), resp.Wallet.Balance)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/"+walletID+"/currencies/GEMS/add", `{"amount": "3", "description": "Purchase"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Removing a currency the wallet does not hold enough of fails independently of other currencies
+	w = sendRequest(router, "POST", "/api/v1/wallets/"+walletID+"/currencies/GEMS/remove", `{"amount": "50", "description": "Too much"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/"+walletID+"/currencies/GOLD/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "GOLD", "balance": "100.00", "available": "100.00", "version": 1}`, w.Body.String())
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/"+walletID+"/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "DEFAULT", "balance": "5.00", "available": "5.00", "version": 1}`, w.Body.String())
+
+	// Every currency in one call, ordered by code
+	w = sendRequest(router, "GET", "/api/v1/wallets/"+walletID+"/balances", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var balances WalletBalancesResponse
+	err = json.Unmarshal(w.Body.Bytes(), &balances)
+	assert.NoError(t, err)
+	assert.Equal(t, walletID, balances.WalletID)
+	assert.Equal(t, []CurrencyBalance{
+		{Currency: "DEFAULT", Balance: models.MustParseAmount("5")},
+		{Currency: "GEMS", Balance: models.MustParseAmount("3")},
+		{Currency: "GOLD", Balance: models.MustParseAmount("100")},
+	}, balances.Balances)
+
+	// History only contains the requested currency
+	w = sendRequest(router, "GET", "/api/v1/wallets/"+walletID+"/currencies/GOLD/transactions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var history TransactionHistoryResponse
+	err = json.Unmarshal(w.Body.Bytes(), &history)
+	assert.NoError(t, err)
+	assert.Len(t, history.Transactions, 1)
+	assert.Equal(t, "GOLD", history.Transactions[0].Currency)
+	assert.Equal(t, "GOLD", history.Wallet.Currency)
+
+	// Transfers move a single currency
+	w = sendRequest(router, "POST", "/api/v1/transfers", `{"from_wallet_id": "wallet123", "to_wallet_id": "wallet456", "currency": "gold", "amount": "40", "description": "Trade"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var transfer TransferResponse
+	err = json.Unmarshal(w.Body.Bytes(), &transfer)
+	assert.NoError(t, err)
+	assert.Equal(t, "GOLD", transfer.Currency)
+	assert.Equal(t, models.MustParseAmount("60"), transfer.FromWallet.Balance)
+	assert.Equal(t, "GOLD", transfer.ToWallet.Currency)
+
+	// Malformed codes are rejected
+	w = sendRequest(router, "POST", "/api/v1/wallets/"+walletID+"/currencies/g-o-l-d/add", `{"amount": "1", "description": "Bad code"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/transfers", `{"from_wallet_id": "wallet123", "to_wallet_id": "wallet456", "currency": "1GOLD", "amount": "1", "description": "Bad code"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestConditionalWrites(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	send := func(method, path, body, ifMatch string) *httptest.ResponseRecorder {
+		if ifMatch == "" {
+			return sendRequest(router, method, path, body)
+		}
+		return sendRequestWithHeader(router, method, path, body, map[string]string{"If-Match": ifMatch})
+	}
+
+	// A wallet that does not hold the currency yet is at version 0
+	w := send("POST", "/api/v1/wallets/wallet123/add", `{"amount": "100", "description": "Deposit", "expected_version": 0}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	// Balance and history report the version and its ETag
+	w = send("GET", "/api/v1/wallets/wallet123/balance", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	var balance WalletBalanceResponse
+	err = json.Unmarshal(w.Body.Bytes(), &balance)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *balance.Version)
+
+	w = send("GET", "/api/v1/wallets/wallet123/transactions", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	// A write conditional on the current version succeeds and moves the version on
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "30", "description": "Purchase"}`, `"1"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// A stale version is refused without changing the wallet
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "30", "description": "Purchase"}`, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = send("POST", "/api/v1/wallets/wallet123/add", `{"amount": "30", "description": "Refund", "expected_version": 1}`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	wallet, err := db.GetWallet("wallet123", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("70"), wallet.Balance)
+	assert.Equal(t, int64(2), wallet.Version)
+
+	// Weak ETags, the body field and a wildcard are accepted
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "10", "description": "Purchase"}`, `W/"2"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "10", "description": "Purchase", "expected_version": 3}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "10", "description": "Purchase"}`, "*")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Malformed or contradictory conditions are rejected
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "10", "description": "Purchase"}`, "latest")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("POST", "/api/v1/wallets/wallet123/remove", `{"amount": "10", "description": "Purchase", "expected_version": 4}`, `"5"`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"virtigia-microcurrency/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateHold reserves currency in a wallet
+// @Summary Create a hold
+// @Description Reserve part of a wallet's available balance until the hold is captured, released or expires.
+// @Description Held currency stays in the balance but cannot be removed, transferred or held again.
+// @Description Routes without a currency code use the default currency.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
+// @Param request body CreateHoldRequest true "Create hold request"
+// @Success 201 {object} models.Hold
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/holds [post]
+// @Router /wallets/{wallet_id}/currencies/{currency}/holds [post]
+func (h *Handler) CreateHold(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	var req CreateHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount must be positive"})
+		return
+	}
+
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	writeOptions, ok := systemAccountOptions(c, walletID, req.Account)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Reserve currency
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	hold, err := database.CreateHold(walletID, currency, req.Amount, ttl, req.Description, req.AdditionalData, writeOptions...)
+	if err != nil {
+		if err == db.ErrInsufficientFunds {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Insufficient funds"})
+			return
+		}
+		if err == db.ErrInvalidHoldTTL {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "TTL exceeds the maximum hold TTL"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create hold: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusCreated, hold)
+}
+
+// GetHolds lists the active holds of a wallet
+// @Summary List holds
+// @Description Get the active holds of a wallet in one currency, ordered by expiry.
+// @Description Routes without a currency code use the default currency.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
+// @Success 200 {object} HoldsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/holds [get]
+// @Router /wallets/{wallet_id}/currencies/{currency}/holds [get]
+func (h *Handler) GetHolds(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get active holds
+	holds, err := database.GetHolds(walletID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get holds: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, HoldsResponse{
+		WalletID: walletID,
+		Holds:    holds,
+	})
+}
+
+// GetHold gets a single hold by ID
+// @Summary Get hold
+// @Description Get a hold by its ID. Holds past their expiry are reported as expired.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param hold_id path string true "Hold ID"
+// @Success 200 {object} models.Hold
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /holds/{hold_id} [get]
+func (h *Handler) GetHold(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get hold
+	hold, err := database.GetHold(c.Param("hold_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Hold not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get hold: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, hold)
+}
+
+// CaptureHold pays held currency into the hold's sink
+// @Summary Capture a hold
+// @Description Pay all or part of an active hold from its wallet into its sink and close the hold.
+// @Description Whatever is not captured is released.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param hold_id path string true "Hold ID"
+// @Param request body CaptureHoldRequest false "Capture hold request"
+// @Success 200 {object} CaptureHoldResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /holds/{hold_id}/capture [post]
+func (h *Handler) CaptureHold(c *gin.Context) {
+	// The body is optional; an empty one captures the whole hold
+	var req CaptureHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Capture hold
+	result, err := database.CaptureHold(c.Param("hold_id"), req.Amount)
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Hold not found"})
+			return
+		}
+		if err == db.ErrHoldNotActive {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Hold is no longer active"})
+			return
+		}
+		if err == db.ErrCaptureTooLarge {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount exceeds the amount held"})
+			return
+		}
+		if err == db.ErrInsufficientFunds {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Insufficient funds"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to capture hold: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, CaptureHoldResponse{
+		Hold:        result.Hold,
+		Transaction: result.Transaction,
+		Wallet:      result.Wallet,
+	})
+}
+
+// ReleaseHold cancels a hold
+// @Summary Release a hold
+// @Description Close an active hold without moving any currency, making the held amount available again.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param hold_id path string true "Hold ID"
+// @Success 200 {object} models.Hold
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /holds/{hold_id}/release [post]
+func (h *Handler) ReleaseHold(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Release hold
+	hold, err := database.ReleaseHold(c.Param("hold_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Hold not found"})
+			return
+		}
+		if err == db.ErrHoldNotActive {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Hold is no longer active"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to release hold: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, hold)
+}
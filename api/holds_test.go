@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dbpkg "virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+)
+
+func TestHolds(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	_, err = db.AddCurrency("wallet123", "", models.MustParseAmount("100"), "Initial deposit", nil)
+	assert.NoError(t, err)
+
+	// Reserve currency for a purchase
+	w := sendRequest(router, "POST", "/api/v1/wallets/wallet123/holds", `{"amount": "40", "description": "Sword purchase", "account": "shop", "ttl_seconds": 300}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var hold models.Hold
+	err = json.Unmarshal(w.Body.Bytes(), &hold)
+	assert.NoError(t, err)
+
+	assert.Equal(t, models.HoldStatusActive, hold.Status)
+	assert.Equal(t, "sink:shop", hold.Account)
+	assert.Equal(t, models.MustParseAmount("40"), hold.Amount)
+	assert.WithinDuration(t, hold.CreatedAt.Add(5*time.Minute), hold.ExpiresAt, time.Second)
+
+	// The hold reduces the available balance but not the balance
+	w = sendRequest(router, "GET", "/api/v1/wallets/wallet123/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "DEFAULT", "balance": "100.00", "available": "60.00", "version": 1}`, w.Body.String())
+
+	// Held currency cannot be removed, transferred or held again
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/remove", `{"amount": "70", "description": "Too much"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	_, err = db.Transfer("wallet123", "wallet456", "", models.MustParseAmount("70"), "Too much", nil)
+	assert.Equal(t, dbpkg.ErrInsufficientFunds, err)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/holds", `{"amount": "70", "description": "Too much"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/wallet123/holds", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var holds HoldsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &holds)
+	assert.NoError(t, err)
+	assert.Len(t, holds.Holds, 1)
+
+	// Capturing part of the hold pays it into the sink and releases the rest
+	w = sendRequest(router, "POST", "/api/v1/holds/"+hold.ID+"/capture", `{"amount": "30"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var captured CaptureHoldResponse
+	err = json.Unmarshal(w.Body.Bytes(), &captured)
+	assert.NoError(t, err)
+
+	assert.Equal(t, models.HoldStatusCaptured, captured.Hold.Status)
+	assert.Equal(t, models.MustParseAmount("30"), captured.Hold.CapturedAmount)
+	assert.Equal(t, captured.Transaction.ID, captured.Hold.TransactionID)
+	assert.Equal(t, models.MustParseAmount("-30"), captured.Transaction.Amount)
+	assert.Equal(t, hold.ID, captured.Transaction.HoldID)
+	assert.Equal(t, "sink:shop", captured.Transaction.CounterpartyWalletID)
+	assert.Equal(t, models.MustParseAmount("70"), captured.Wallet.Balance)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/wallet123/balance", "")
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "DEFAULT", "balance": "70.00", "available": "70.00", "version": 2}`, w.Body.String())
+
+	// A closed hold cannot be captured or released again
+	w = sendRequest(router, "POST", "/api/v1/holds/"+hold.ID+"/capture", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/holds/"+hold.ID+"/release", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Releasing a hold makes the amount available again without moving currency
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/holds", `{"amount": "50", "description": "Shield purchase"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &hold)
+	assert.NoError(t, err)
+
+	w = sendRequest(router, "POST", "/api/v1/holds/"+hold.ID+"/capture", `{"amount": "60"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/holds/"+hold.ID+"/release", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &hold)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HoldStatusReleased, hold.Status)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/wallet123/balance", "")
+	assert.JSONEq(t, `{"wallet_id": "wallet123", "currency": "DEFAULT", "balance": "70.00", "available": "70.00", "version": 2}`, w.Body.String())
+
+	// Holds stop reserving currency once they expire
+	expiring, err := db.CreateHold("wallet123", "", models.MustParseAmount("70"), 10*time.Millisecond, "Expiring purchase", nil)
+	assert.NoError(t, err)
+
+	_, available, err := db.GetAvailableBalance("wallet123", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Amount(0), available)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, available, err = db.GetAvailableBalance("wallet123", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("70"), available)
+
+	w = sendRequest(router, "GET", "/api/v1/holds/"+expiring.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &hold)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HoldStatusExpired, hold.Status)
+
+	w = sendRequest(router, "POST", "/api/v1/holds/"+expiring.ID+"/capture", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// TTLs beyond the maximum are rejected and unknown holds are not found
+	w = sendRequest(router, "POST", "/api/v1/wallets/wallet123/holds", `{"amount": "1", "description": "Forever", "ttl_seconds": 604800}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/holds/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrialBalance gets the trial balance of the current environment
+// @Summary Get trial balance
+// @Description Sum the balances of all wallets, faucets and sinks and the amounts of all transactions per currency.
+// @Description Every sum is zero in a consistent ledger. The whole ledger is read, so use it for audits only.
+// @Tags ledger
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Success 200 {object} TrialBalanceResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /ledger/trial-balance [get]
+func (h *Handler) GetTrialBalance(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Sum the ledger
+	currencies, err := database.TrialBalance()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute trial balance: " + err.Error()})
+		return
+	}
+
+	balanced := true
+	for _, currency := range currencies {
+		balanced = balanced && currency.Balanced
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, TrialBalanceResponse{
+		Environment: middleware.GetEnvironment(c),
+		Balanced:    balanced,
+		Currencies:  currencies,
+	})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/models"
+)
+
+func TestDoubleEntryLedger(t *testing.T) {
+	router, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Currency is issued from a named faucet
+	w := sendRequest(router, "POST", "/api/v1/wallets/alice/add", `{"amount": "100", "description": "Quest reward", "account": "quest_rewards"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp TransactionResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "faucet:quest_rewards", resp.Transaction.CounterpartyWalletID)
+
+	// The balancing entry on the faucet points back at the player's entry
+	w = sendRequest(router, "GET", "/api/v1/transactions/"+resp.Transaction.CounterpartyTransactionID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var entry models.Transaction
+	err = json.Unmarshal(w.Body.Bytes(), &entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "faucet:quest_rewards", entry.WalletID)
+	assert.Equal(t, models.MustParseAmount("-100"), entry.Amount)
+	assert.Equal(t, "alice", entry.CounterpartyWalletID)
+	assert.Equal(t, resp.Transaction.ID, entry.CounterpartyTransactionID)
+
+	// Spending pays into the default sink unless one is named
+	w = sendRequest(router, "POST", "/api/v1/wallets/alice/remove", `{"amount": "30", "description": "Sword", "account": "shop"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/alice/remove", `{"amount": "5", "description": "Repair"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "sink:default", resp.Transaction.CounterpartyWalletID)
+
+	w = sendRequest(router, "POST", "/api/v1/transfers", `{"from_wallet_id": "alice", "to_wallet_id": "bob", "amount": "15", "description": "Trade"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// System accounts only move through players
+	w = sendRequest(router, "POST", "/api/v1/wallets/faucet:quest_rewards/add", `{"amount": "1", "description": "Mint"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/alice/add", `{"amount": "1", "description": "Bad account", "account": "quest-rewards"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/sink:shop/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "sink:shop", "currency": "DEFAULT", "balance": "30.00", "available": "30.00", "version": 1}`, w.Body.String())
+
+	// Every account and every entry sums to zero
+	w = sendRequest(router, "GET", "/api/v1/ledger/trial-balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var trial TrialBalanceResponse
+	err = json.Unmarshal(w.Body.Bytes(), &trial)
+	assert.NoError(t, err)
+	assert.True(t, trial.Balanced)
+	if assert.Len(t, trial.Currencies, 1) {
+		currency := trial.Currencies[0]
+		assert.Equal(t, "DEFAULT", currency.Currency)
+		assert.Equal(t, models.MustParseAmount("65"), currency.Wallets)
+		assert.Equal(t, models.MustParseAmount("-100"), currency.Faucets)
+		assert.Equal(t, models.MustParseAmount("35"), currency.Sinks)
+		assert.Equal(t, models.Amount(0), currency.Balances)
+		assert.Equal(t, models.Amount(0), currency.Entries)
+		assert.True(t, currency.Balanced)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetWalletLimits sets the limits of a wallet
+// @Summary Set wallet limits
+// @Description Replace the limits of a wallet in one currency. A credit limit lets the available balance go
+// @Description that far below zero; a maximum balance and maximum transaction cap the wallet in addition to the
+// @Description limits of the currency. The limits apply to later transactions; a balance already beyond them is
+// @Description not changed. Routes without a currency code use the default currency.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
+// @Param request body WalletLimitsRequest true "Wallet limits"
+// @Success 200 {object} models.Wallet
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/limits [put]
+// @Router /wallets/{wallet_id}/currencies/{currency}/limits [put]
+func (h *Handler) SetWalletLimits(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	var req WalletLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Set limits
+	wallet, err := database.SetWalletLimits(walletID, currency, models.WalletLimits{
+		CreditLimit:    req.CreditLimit,
+		MaxBalance:     req.MaxBalance,
+		MaxTransaction: req.MaxTransaction,
+	})
+	if err != nil {
+		if err == db.ErrSystemAccount {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "System accounts have no limits"})
+			return
+		}
+		if err == db.ErrInvalidLimit {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Credit limit must not be negative and maximums must be positive"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to set wallet limits: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, wallet)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dbpkg "virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+)
+
+func TestWalletLimits(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	_, err = db.AddCurrency("vip", "", models.MustParseAmount("100"), "Initial deposit", nil)
+	assert.NoError(t, err)
+
+	// Give the wallet a credit limit
+	w := sendRequest(router, "PUT", "/api/v1/wallets/vip/limits", `{"credit_limit": "50"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var wallet models.Wallet
+	err = json.Unmarshal(w.Body.Bytes(), &wallet)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("50"), wallet.CreditLimit)
+	assert.Equal(t, models.MustParseAmount("100"), wallet.Balance)
+
+	// The wallet may now go below zero up to its credit limit
+	w = sendRequest(router, "POST", "/api/v1/wallets/vip/remove", `{"amount": "130", "description": "VIP purchase"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/vip/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"wallet_id": "vip", "currency": "DEFAULT", "balance": "-30.00", "available": "-30.00", "version": 2, "credit_limit": "50.00", "available_credit": "20.00"}`, w.Body.String())
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/vip/remove", `{"amount": "30", "description": "Too much"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Transfers and holds draw on the same credit
+	_, err = db.CreateHold("vip", "", models.MustParseAmount("15"), time.Minute, "Pending purchase", nil)
+	assert.NoError(t, err)
+
+	_, err = db.Transfer("vip", "friend", "", models.MustParseAmount("10"), "Gift", nil)
+	assert.Equal(t, dbpkg.ErrInsufficientFunds, err)
+
+	_, err = db.Transfer("vip", "friend", "", models.MustParseAmount("5"), "Gift", nil)
+	assert.NoError(t, err)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/vip/balance", "")
+	assert.JSONEq(t, `{"wallet_id": "vip", "currency": "DEFAULT", "balance": "-35.00", "available": "-50.00", "version": 3, "credit_limit": "50.00", "available_credit": "0.00"}`, w.Body.String())
+
+	// Wallets without a credit limit still cannot go negative
+	w = sendRequest(router, "POST", "/api/v1/wallets/friend/remove", `{"amount": "6", "description": "Too much"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Removing the credit limit leaves the balance as it is but allows no further debits
+	w = sendRequest(router, "PUT", "/api/v1/wallets/vip/limits", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/wallets/vip/balance", "")
+	assert.JSONEq(t, `{"wallet_id": "vip", "currency": "DEFAULT", "balance": "-35.00", "available": "-50.00", "version": 3}`, w.Body.String())
+
+	// Invalid limits are rejected
+	invalid := map[string]string{
+		"/api/v1/wallets/vip/limits":                       `{"credit_limit": "-1"}`,
+		"/api/v1/wallets/faucet:rewards/limits":            `{"credit_limit": "10"}`,
+		"/api/v1/wallets/vip/currencies/NOPE/limits":       `{"credit_limit": "10"}`,
+		"/api/v1/wallets/vip/currencies/not-a-code/limits": `{"credit_limit": "10"}`,
+	}
+	for path, body := range invalid {
+		w = sendRequest(router, "PUT", path, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestWalletCaps(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	maxTransaction := models.MustParseAmount("1000")
+	maxBalance := models.MustParseAmount("5000")
+	err = db.SaveCurrency(&models.Currency{Code: "GOLD", Name: "Gold", Decimals: 0, MaxTransaction: &maxTransaction, MaxBalance: &maxBalance, Transferable: true})
+	assert.NoError(t, err)
+
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response.Code
+	}
+
+	// The currency's caps apply to every wallet of the environment
+	w := sendRequest(router, "POST", "/api/v1/wallets/player/currencies/GOLD/add", `{"amount": "2000", "description": "Exploit"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "currency_transaction_limit", errorCode(w))
+
+	// A wallet can be capped further
+	w = sendRequest(router, "PUT", "/api/v1/wallets/player/currencies/GOLD/limits", `{"max_balance": "1500", "max_transaction": "800"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/currencies/GOLD/add", `{"amount": "900", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_transaction_limit", errorCode(w))
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/currencies/GOLD/add", `{"amount": "800", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/currencies/GOLD/add", `{"amount": "800", "description": "Quest reward"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_balance_limit", errorCode(w))
+
+	_, err = db.Transfer("player", "friend", "GOLD", models.MustParseAmount("900"), "Gift", nil)
+	assert.Equal(t, dbpkg.ErrWalletTransactionLimit, err)
+
+	// Clamped rewards top the wallet up to its cap and record what was requested
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/currencies/GOLD/add", `{"amount": "800", "description": "Quest reward", "clamp": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("700"), response.Transaction.Amount)
+	assert.Equal(t, models.MustParseAmount("800"), response.Transaction.RequestedAmount)
+	assert.Equal(t, models.MustParseAmount("1500"), response.Wallet.Balance)
+
+	// Nothing is credited to a wallet already at its cap
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/currencies/GOLD/add", `{"amount": "10", "description": "Quest reward", "clamp": true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_balance_limit", errorCode(w))
+
+	w = sendRequest(router, "POST", "/api/v1/batch", `{"operations": [{"type": "add", "wallet_id": "player", "currency": "GOLD", "amount": "10", "description": "Quest reward", "clamp": true}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_balance_limit", errorCode(w))
+
+	// Wallets without caps of their own are clamped to the currency's
+	w = sendRequest(router, "POST", "/api/v1/wallets/friend/currencies/GOLD/add", `{"amount": "2000", "description": "Quest reward", "clamp": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("1000"), response.Transaction.Amount)
+	assert.Equal(t, models.MustParseAmount("2000"), response.Transaction.RequestedAmount)
+
+	// Caps must be positive and fit the currency's precision
+	w = sendRequest(router, "PUT", "/api/v1/wallets/player/currencies/GOLD/limits", `{"max_balance": "0"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "PUT", "/api/v1/wallets/player/currencies/GOLD/limits", `{"max_transaction": "10.5"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLots lists the expiring lots of a wallet
+// @Summary List lots
+// @Description Get the balance of a wallet in one currency broken down into its active expiring lots, in expiry
+// @Description order, and the part of the balance that does not expire. Debits spend the lots that expire first.
+// @Description Routes without a currency code use the default currency.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param currency path string true "Currency code"
+// @Success 200 {object} LotsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/lots [get]
+// @Router /wallets/{wallet_id}/currencies/{currency}/lots [get]
+func (h *Handler) GetLots(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	currency, ok := currencyParam(c)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get active lots
+	wallet, lots, err := database.GetLots(walletID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get lots: " + err.Error()})
+		return
+	}
+
+	nonExpiring := wallet.Balance
+	for _, lot := range lots {
+		nonExpiring -= lot.Remaining
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, LotsResponse{
+		WalletID:    walletID,
+		Currency:    wallet.Currency,
+		Balance:     wallet.Balance,
+		NonExpiring: nonExpiring,
+		Lots:        lots,
+	})
+}
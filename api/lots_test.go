@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dbpkg "virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+)
+
+func TestLots(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	getLots := func() LotsResponse {
+		w := sendRequest(router, "GET", "/api/v1/wallets/player/lots", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response LotsResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response
+	}
+
+	now := time.Now()
+	later := now.Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	latest := now.Add(2 * time.Hour).UTC().Format(time.RFC3339Nano)
+
+	// Grant currency that never expires and two event grants that do
+	_, err = db.AddCurrency("player", "", models.MustParseAmount("50"), "Starting balance", nil)
+	assert.NoError(t, err)
+
+	w := sendRequest(router, "POST", "/api/v1/wallets/player/add", `{"amount": "100", "description": "Event reward", "expires_at": "`+latest+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var added TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &added)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, added.Transaction.LotID)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/add", `{"amount": "30", "description": "Event reward", "expires_at": "`+later+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Lots are listed soonest expiring first next to the balance that never expires
+	lots := getLots()
+	assert.Equal(t, models.MustParseAmount("180"), lots.Balance)
+	assert.Equal(t, models.MustParseAmount("50"), lots.NonExpiring)
+	assert.Len(t, lots.Lots, 2)
+	assert.Equal(t, models.MustParseAmount("30"), lots.Lots[0].Remaining)
+	assert.Equal(t, added.Transaction.LotID, lots.Lots[1].ID)
+
+	// Debits spend the lots that expire first
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/remove", `{"amount": "40", "description": "Event shop"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	lots = getLots()
+	assert.Equal(t, models.MustParseAmount("50"), lots.NonExpiring)
+	assert.Len(t, lots.Lots, 1)
+	assert.Equal(t, models.MustParseAmount("100"), lots.Lots[0].Amount)
+	assert.Equal(t, models.MustParseAmount("90"), lots.Lots[0].Remaining)
+
+	// The expiry job removes what is left of a lot once it expires
+	expired, err := db.ExpireLots(now.Add(90 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	err = dbManager.ExpireLots(now.Add(3 * time.Hour))
+	assert.NoError(t, err)
+
+	lots = getLots()
+	assert.Equal(t, models.MustParseAmount("50"), lots.Balance)
+	assert.Equal(t, models.MustParseAmount("50"), lots.NonExpiring)
+	assert.Empty(t, lots.Lots)
+
+	page, err := db.GetTransactionsByWallet("player", "", dbpkg.TransactionQuery{Limit: 1, SortBy: dbpkg.SortByTimestamp, SortOrder: dbpkg.SortDescending})
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("-90"), page.Transactions[0].Amount)
+	assert.Equal(t, "sink:expired", page.Transactions[0].CounterpartyWalletID)
+	assert.Equal(t, added.Transaction.LotID, page.Transactions[0].LotID)
+
+	trial, err := db.TrialBalance()
+	assert.NoError(t, err)
+	for _, currency := range trial {
+		assert.True(t, currency.Balanced)
+	}
+
+	// Expiries must lie in the future and only apply to adds
+	past := now.Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+	w = sendRequest(router, "POST", "/api/v1/wallets/player/add", `{"amount": "10", "description": "Late reward", "expires_at": "`+past+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/batch", `{"operations": [{"type": "remove", "wallet_id": "player", "amount": "10", "description": "Event shop", "expires_at": "`+latest+`"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,20 +1,51 @@
 package api
 
 import (
+	"time"
+
+	"virtigia-microcurrency/db"
 	"virtigia-microcurrency/models"
 )
 
 // AddCurrencyRequest is the request for adding currency to a wallet
 type AddCurrencyRequest struct {
-	Amount        float64                `json:"amount" binding:"required,gt=0"`
-	Description   string                 `json:"description" binding:"required"`
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"100.00"`
+	Description    string                 `json:"description" binding:"required"`
 	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// Account names the faucet the currency is issued from (default: "default")
+	Account string `json:"account,omitempty" example:"quest_rewards"`
+
+	// Clamp credits as much of the amount as the currency's and wallet's limits allow instead of failing
+	Clamp bool `json:"clamp,omitempty"`
+
+	// ExpiresAt makes the credited currency expire at this time; it is tracked as a lot of the wallet
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2023-01-08T12:00:00Z"`
+
+	// ExpectedVersion makes the write fail unless the wallet is still at this version
+	ExpectedVersion *int64 `json:"expected_version,omitempty" example:"7"`
 }
 
 // RemoveCurrencyRequest is the request for removing currency from a wallet
 type RemoveCurrencyRequest struct {
-	Amount        float64                `json:"amount" binding:"required,gt=0"`
-	Description   string                 `json:"description" binding:"required"`
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"100.00"`
+	Description    string                 `json:"description" binding:"required"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// Account names the sink the currency is paid into (default: "default")
+	Account string `json:"account,omitempty" example:"shop"`
+
+	// ExpectedVersion makes the write fail unless the wallet is still at this version
+	ExpectedVersion *int64 `json:"expected_version,omitempty" example:"7"`
+}
+
+// ReverseTransactionRequest is the request for reversing a transaction
+type ReverseTransactionRequest struct {
+	// Amount reverses part of the transaction; omit it to reverse everything not reversed yet
+	Amount models.Amount `json:"amount,omitempty" binding:"gte=0" swaggertype:"string" example:"20.00"`
+
+	// Description defaults to "Reversal of <transaction_id>"
+	Description    string                 `json:"description,omitempty" example:"Refund of mistaken grant"`
 	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
 }
 
@@ -33,18 +64,313 @@ type TransactionHistoryResponse struct {
 
 // WalletBalanceResponse is the response for wallet balance
 type WalletBalanceResponse struct {
-	WalletID string  `json:"wallet_id"`
-	Balance  float64 `json:"balance"`
+	WalletID string        `json:"wallet_id"`
+	Currency string        `json:"currency" example:"GOLD"`
+	Balance  models.Amount `json:"balance" swaggertype:"string" example:"100.00"`
+
+	// Available and Version are not set for historical balance queries. Available is the balance less active holds.
+	Available *models.Amount `json:"available,omitempty" swaggertype:"string" example:"60.00"`
+	Version   *int64         `json:"version,omitempty" example:"7"`
+
+	// CreditLimit and AvailableCredit are only set for wallets with a credit limit.
+	// AvailableCredit is the part of the credit limit the wallet has not used.
+	CreditLimit     *models.Amount `json:"credit_limit,omitempty" swaggertype:"string" example:"500.00"`
+	AvailableCredit *models.Amount `json:"available_credit,omitempty" swaggertype:"string" example:"500.00"`
+
+	// At and LastTransaction are only set for historical balance queries
+	At              *time.Time          `json:"at,omitempty"`
+	LastTransaction *models.Transaction `json:"last_transaction,omitempty"`
+}
+
+// CurrencyBalance is the balance of a wallet in one currency
+type CurrencyBalance struct {
+	Currency string        `json:"currency" example:"GOLD"`
+	Balance  models.Amount `json:"balance" swaggertype:"string" example:"100.00"`
+}
+
+// WalletBalancesResponse is the response for the balances of a wallet in every currency
+type WalletBalancesResponse struct {
+	WalletID string            `json:"wallet_id"`
+	Balances []CurrencyBalance `json:"balances"`
+}
+
+// CurrencyRequest is the request for defining a currency
+type CurrencyRequest struct {
+	Name string `json:"name" example:"Gold"`
+
+	// Decimals defaults to CURRENCY_DECIMALS and may not exceed it
+	Decimals *int `json:"decimals,omitempty" example:"2"`
+
+	MinTransaction *models.Amount `json:"min_transaction,omitempty" swaggertype:"string" example:"1.00"`
+	MaxTransaction *models.Amount `json:"max_transaction,omitempty" swaggertype:"string" example:"10000.00"`
+	MaxBalance     *models.Amount `json:"max_balance,omitempty" swaggertype:"string" example:"1000000.00"`
+
+	// Transferable defaults to true
+	Transferable  *bool `json:"transferable,omitempty" example:"true"`
+	AllowNegative bool  `json:"allow_negative"`
+}
+
+// CreateHoldRequest is the request for reserving currency in a wallet
+type CreateHoldRequest struct {
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"40.00"`
+	Description    string                 `json:"description" binding:"required"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// Account names the sink the hold is captured into (default: "default")
+	Account string `json:"account,omitempty" example:"shop"`
+
+	// TTLSeconds is how long the hold lasts before it expires (default: HOLD_TTL)
+	TTLSeconds int `json:"ttl_seconds,omitempty" binding:"gte=0" example:"300"`
+}
+
+// ScheduleOperationRequest is the request for scheduling an add or removal
+type ScheduleOperationRequest struct {
+	Type           string                 `json:"type" binding:"required,oneof=add remove" example:"add"`
+	WalletID       string                 `json:"wallet_id" binding:"required" example:"wallet123"`
+	Currency       string                 `json:"currency,omitempty" example:"GEMS"`
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"500.00"`
+	Description    string                 `json:"description" binding:"required"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// Account names the faucet or sink the operation is posted against (default: "default")
+	Account string `json:"account,omitempty" example:"holiday_event"`
+
+	// ExecuteAt is when the operation is applied; it must be in the future
+	ExecuteAt time.Time `json:"execute_at" binding:"required" example:"2026-12-24T18:00:00Z"`
+}
+
+// ScheduledOperationsResponse is the response for a list of scheduled operations
+type ScheduledOperationsResponse struct {
+	Operations []*models.ScheduledOperation `json:"operations"`
+}
+
+// CreateGrantRequest is the request for creating a recurring grant
+type CreateGrantRequest struct {
+	Name           string                 `json:"name" binding:"required" example:"VIP daily gems"`
+	Currency       string                 `json:"currency,omitempty" example:"GEMS"`
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"100.00"`
+	Description    string                 `json:"description" binding:"required"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// Account names the faucet the grant is paid from (default: "default")
+	Account string `json:"account,omitempty" example:"vip"`
+
+	// IntervalSeconds or Cron sets the schedule; exactly one of them is required. Intervals are at most ten years.
+	IntervalSeconds int64  `json:"interval_seconds,omitempty" binding:"gte=0,lte=315360000" example:"86400"`
+	Cron            string `json:"cron,omitempty" example:"0 12 * * *"`
+
+	// CatchUp decides which occurrences missed during downtime are granted (default: "all")
+	CatchUp string `json:"catch_up,omitempty" binding:"omitempty,oneof=all latest skip" example:"all"`
+
+	// StartAt and EndAt bound the occurrences of every enrollment (default: from now, without end)
+	StartAt *time.Time `json:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+}
+
+// GrantsResponse is the response for a list of grants
+type GrantsResponse struct {
+	Grants []*models.Grant `json:"grants"`
+}
+
+// EnrollWalletRequest is the request for enrolling a wallet in a grant
+type EnrollWalletRequest struct {
+	WalletID string `json:"wallet_id" binding:"required" example:"wallet123"`
+
+	// StartAt and EndAt bound the occurrences granted to the wallet (default: from now until the grant ends)
+	StartAt *time.Time `json:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty" example:"2026-02-01T00:00:00Z"`
+}
+
+// GrantEnrollmentsResponse is the response for a list of enrollments in a grant
+type GrantEnrollmentsResponse struct {
+	Enrollments []*models.GrantEnrollment `json:"enrollments"`
+}
+
+// GrantRunsResponse is the response for the history of a grant
+type GrantRunsResponse struct {
+	Runs []*models.GrantRun `json:"runs"`
+}
+
+// WalletLimitsRequest is the request for setting the limits of a wallet
+type WalletLimitsRequest struct {
+	// CreditLimit lets the available balance go this far below zero; omit it to remove the credit limit
+	CreditLimit models.Amount `json:"credit_limit,omitempty" binding:"gte=0" swaggertype:"string" example:"500.00"`
+
+	// MaxBalance and MaxTransaction cap the balance and the size of a single transaction; omit them to remove the caps
+	MaxBalance     *models.Amount `json:"max_balance,omitempty" binding:"omitempty,gt=0" swaggertype:"string" example:"100000.00"`
+	MaxTransaction *models.Amount `json:"max_transaction,omitempty" binding:"omitempty,gt=0" swaggertype:"string" example:"5000.00"`
+}
+
+// WalletStatusRequest is the request for changing the status of a wallet
+type WalletStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active debit_frozen frozen closed" example:"debit_frozen"`
+	Reason string `json:"reason" binding:"required" example:"Suspected fraud, ticket 4711"`
+
+	// Actor names who made the change, for the audit trail
+	Actor string `json:"actor" binding:"required" example:"support:alice"`
+}
+
+// WalletStatusHistoryResponse is the response for the audit trail of a wallet's status
+type WalletStatusHistoryResponse struct {
+	WalletID string                       `json:"wallet_id"`
+	Changes  []*models.WalletStatusChange `json:"changes"`
+}
+
+// WalletResponse is the full record of a wallet: its metadata, status and balances in every currency
+type WalletResponse struct {
+	models.WalletMetadata
+	Status   string           `json:"status" example:"active"`
+	Balances []*models.Wallet `json:"balances"`
+
+	// Exists is false for wallet IDs that were never written to or described
+	Exists bool `json:"exists"`
+}
+
+// WalletsResponse is the response for a page of wallets
+type WalletsResponse struct {
+	Wallets    []*WalletResponse `json:"wallets"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// WalletMetadataRequest is the request for replacing the metadata of a wallet
+type WalletMetadataRequest struct {
+	OwnerID    string                 `json:"owner_id" example:"player-42"`
+	Labels     []string               `json:"labels" example:"vip,beta"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// WalletMetadataPatchRequest is the request for changing part of the metadata of a wallet.
+// Omitted fields are left unchanged; attributes are merged and a null attribute is removed.
+type WalletMetadataPatchRequest struct {
+	OwnerID    *string                `json:"owner_id,omitempty" example:"player-42"`
+	Labels     *[]string              `json:"labels,omitempty" example:"vip,beta"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// CaptureHoldRequest is the request for capturing a hold
+type CaptureHoldRequest struct {
+	// Amount captures part of the hold and releases the rest; omit it to capture the whole hold
+	Amount models.Amount `json:"amount,omitempty" binding:"gte=0" swaggertype:"string" example:"30.00"`
+}
+
+// CaptureHoldResponse is the response for a captured hold
+type CaptureHoldResponse struct {
+	Hold        *models.Hold        `json:"hold"`
+	Transaction *models.Transaction `json:"transaction"`
+	Wallet      *models.Wallet      `json:"wallet"`
+}
+
+// LotsResponse is the response for the expiring lots of a wallet
+type LotsResponse struct {
+	WalletID string        `json:"wallet_id"`
+	Currency string        `json:"currency" example:"EVENT_TOKEN"`
+	Balance  models.Amount `json:"balance" swaggertype:"string" example:"150.00"`
+
+	// NonExpiring is the part of the balance that is not in a lot
+	NonExpiring models.Amount `json:"non_expiring" swaggertype:"string" example:"50.00"`
+	Lots        []*models.Lot `json:"lots"`
+}
+
+// HoldsResponse is the response for the active holds of a wallet
+type HoldsResponse struct {
+	WalletID string         `json:"wallet_id"`
+	Holds    []*models.Hold `json:"holds"`
+}
+
+// TrialBalanceResponse is the response for the trial balance of an environment
+type TrialBalanceResponse struct {
+	Environment string             `json:"environment"`
+	Balanced    bool               `json:"balanced"`
+	Currencies  []*db.TrialBalance `json:"currencies"`
+}
+
+// StatsResponse is the response for database statistics of an environment
+type StatsResponse struct {
+	Environment string   `json:"environment"`
+	Writes      db.Stats `json:"writes"`
 }
 
 // Pagination contains pagination information
 type Pagination struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Count  int `json:"count"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Count      int    `json:"count"`
+	SortBy     string `json:"sort_by"`
+	SortOrder  string `json:"sort_order"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // ErrorResponse is the response for an error
 type ErrorResponse struct {
 	Error string `json:"error"`
-}
\ No newline at end of file
+
+	// Code identifies errors that clients are expected to handle, such as a broken limit
+	Code string `json:"code,omitempty" example:"wallet_balance_limit"`
+}
+
+// TransferRequest is the request for moving currency between two wallets
+type TransferRequest struct {
+	FromWalletID   string                 `json:"from_wallet_id" binding:"required"`
+	ToWalletID     string                 `json:"to_wallet_id" binding:"required"`
+	Currency       string                 `json:"currency,omitempty" example:"GOLD"`
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"25.00"`
+	Description    string                 `json:"description" binding:"required"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+}
+
+// BatchRequest is the request for applying several operations atomically
+type BatchRequest struct {
+	Operations []BatchOperationRequest `json:"operations" binding:"required,min=1,dive"`
+}
+
+// BatchOperationRequest is one add, remove or transfer of a batch
+type BatchOperationRequest struct {
+	Type string `json:"type" binding:"required,oneof=add remove transfer" example:"add"`
+
+	// WalletID and Account apply to add and remove; Clamp and ExpiresAt apply to add
+	WalletID  string     `json:"wallet_id,omitempty" example:"wallet123"`
+	Account   string     `json:"account,omitempty" example:"quest_rewards"`
+	Clamp     bool       `json:"clamp,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// FromWalletID and ToWalletID apply to transfer
+	FromWalletID string `json:"from_wallet_id,omitempty"`
+	ToWalletID   string `json:"to_wallet_id,omitempty"`
+
+	Currency       string                 `json:"currency,omitempty" example:"GOLD"`
+	Amount         models.Amount          `json:"amount" binding:"required,gt=0" swaggertype:"string" example:"100.00"`
+	Description    string                 `json:"description" binding:"required"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+}
+
+// BatchResponse is the response for a batch, with one result per operation in request order
+type BatchResponse struct {
+	Results []BatchOperationResponse `json:"results"`
+}
+
+// BatchOperationResponse is the result of one operation of a batch. Add and remove
+// set Transaction and Wallet; transfer sets Transfer.
+type BatchOperationResponse struct {
+	Type        string              `json:"type" example:"add"`
+	Transaction *models.Transaction `json:"transaction,omitempty"`
+	Wallet      *models.Wallet      `json:"wallet,omitempty"`
+	Transfer    *TransferResponse   `json:"transfer,omitempty"`
+}
+
+// BatchErrorResponse is the response for a batch that was rolled back because one of its operations failed
+type BatchErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty" example:"wallet_balance_limit"`
+	Operation int    `json:"operation"`
+}
+
+// TransferResponse is the response for a transfer
+type TransferResponse struct {
+	TransferID      string              `json:"transfer_id"`
+	Currency        string              `json:"currency"`
+	FromTransaction *models.Transaction `json:"from_transaction"`
+	ToTransaction   *models.Transaction `json:"to_transaction"`
+	FromWallet      *models.Wallet      `json:"from_wallet"`
+	ToWallet        *models.Wallet      `json:"to_wallet"`
+}
@@ -31,14 +31,107 @@ func SetupRouter(dbManager *db.DBManager) *gin.Engine {
 		// Wallet routes
 		wallets := api.Group("/wallets")
 		{
+			// Wallet records and metadata
+			wallets.GET("", handler.ListWallets)
+			wallets.GET("/:wallet_id", handler.GetWallet)
+			wallets.PUT("/:wallet_id", handler.SetWalletMetadata)
+			wallets.PATCH("/:wallet_id", handler.PatchWalletMetadata)
+
 			// Wallet operations
 			wallets.POST("/:wallet_id/add", handler.AddCurrency)
 			wallets.POST("/:wallet_id/remove", handler.RemoveCurrency)
 			wallets.GET("/:wallet_id/balance", handler.GetWalletBalance)
 
+			wallets.GET("/:wallet_id/balances", handler.GetWalletBalances)
+
 			// Transaction history
 			wallets.GET("/:wallet_id/transactions", handler.GetTransactionHistory)
+
+			// Holds on available balance
+			wallets.POST("/:wallet_id/holds", handler.CreateHold)
+			wallets.GET("/:wallet_id/holds", handler.GetHolds)
+
+			// Limits of a wallet
+			wallets.PUT("/:wallet_id/limits", handler.SetWalletLimits)
+
+			// Status of a wallet and its audit trail
+			wallets.GET("/:wallet_id/status", handler.GetWalletStatus)
+			wallets.PUT("/:wallet_id/status", handler.SetWalletStatus)
+			wallets.GET("/:wallet_id/status/history", handler.GetWalletStatusHistory)
+
+			// Expiring lots of a wallet
+			wallets.GET("/:wallet_id/lots", handler.GetLots)
+
+			// Operations in a specific currency
+			wallets.POST("/:wallet_id/currencies/:currency/add", handler.AddCurrency)
+			wallets.POST("/:wallet_id/currencies/:currency/remove", handler.RemoveCurrency)
+			wallets.GET("/:wallet_id/currencies/:currency/balance", handler.GetWalletBalance)
+			wallets.GET("/:wallet_id/currencies/:currency/transactions", handler.GetTransactionHistory)
+			wallets.POST("/:wallet_id/currencies/:currency/holds", handler.CreateHold)
+			wallets.GET("/:wallet_id/currencies/:currency/holds", handler.GetHolds)
+			wallets.PUT("/:wallet_id/currencies/:currency/limits", handler.SetWalletLimits)
+			wallets.GET("/:wallet_id/currencies/:currency/lots", handler.GetLots)
 		}
+
+		// Currency definitions
+		currencies := api.Group("/currencies")
+		{
+			currencies.GET("", handler.ListCurrencies)
+			currencies.GET("/:currency", handler.GetCurrency)
+			currencies.PUT("/:currency", handler.PutCurrency)
+		}
+
+		// Transaction routes
+		api.GET("/transactions/:transaction_id", handler.GetTransaction)
+		api.POST("/transactions/:transaction_id/reverse", handler.ReverseTransaction)
+
+		// Hold routes
+		holds := api.Group("/holds")
+		{
+			holds.GET("/:hold_id", handler.GetHold)
+			holds.POST("/:hold_id/capture", handler.CaptureHold)
+			holds.POST("/:hold_id/release", handler.ReleaseHold)
+		}
+
+		// Scheduled operation routes
+		scheduled := api.Group("/scheduled")
+		{
+			scheduled.POST("", handler.ScheduleOperation)
+			scheduled.GET("", handler.ListScheduledOperations)
+			scheduled.GET("/:scheduled_id", handler.GetScheduledOperation)
+			scheduled.POST("/:scheduled_id/cancel", handler.CancelScheduledOperation)
+		}
+
+		// Recurring grant routes
+		grants := api.Group("/grants")
+		{
+			grants.POST("", handler.CreateGrant)
+			grants.GET("", handler.ListGrants)
+			grants.GET("/:grant_id", handler.GetGrant)
+			grants.POST("/:grant_id/pause", handler.PauseGrant)
+			grants.POST("/:grant_id/resume", handler.ResumeGrant)
+			grants.GET("/:grant_id/runs", handler.ListGrantRuns)
+
+			// Enrollments of wallets
+			grants.POST("/:grant_id/enrollments", handler.EnrollWallet)
+			grants.GET("/:grant_id/enrollments", handler.ListEnrollments)
+			grants.GET("/:grant_id/enrollments/:wallet_id", handler.GetEnrollment)
+			grants.POST("/:grant_id/enrollments/:wallet_id/pause", handler.PauseEnrollment)
+			grants.POST("/:grant_id/enrollments/:wallet_id/resume", handler.ResumeEnrollment)
+			grants.POST("/:grant_id/enrollments/:wallet_id/cancel", handler.CancelEnrollment)
+		}
+
+		// Transfer routes
+		api.POST("/transfers", handler.CreateTransfer)
+
+		// Atomic batches of operations
+		api.POST("/batch", handler.Batch)
+
+		// Ledger audits
+		api.GET("/ledger/trial-balance", handler.GetTrialBalance)
+
+		// Statistics
+		api.GET("/stats", handler.GetStats)
 	}
 
 	return router
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scheduledStatuses are the statuses scheduled operations can be listed by
+var scheduledStatuses = map[string]bool{
+	models.ScheduledStatusPending:   true,
+	models.ScheduledStatusExecuted:  true,
+	models.ScheduledStatusFailed:    true,
+	models.ScheduledStatusCancelled: true,
+}
+
+// ScheduleOperation schedules an add or removal
+// @Summary Schedule an operation
+// @Description Store an add or removal to be applied at execute_at. A background job applies due operations
+// @Description exactly once and records the transaction on the operation; an operation that breaks a rule,
+// @Description e.g. for lack of funds, is marked failed with the error.
+// @Tags scheduled
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param request body ScheduleOperationRequest true "Schedule operation request"
+// @Success 201 {object} models.ScheduledOperation
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /scheduled [post]
+func (h *Handler) ScheduleOperation(c *gin.Context) {
+	var req ScheduleOperationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	writeOptions, ok := systemAccountOptions(c, req.WalletID, req.Account)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Schedule operation
+	scheduled, err := database.ScheduleOperation(req.Type, req.WalletID, req.Currency, req.Amount, req.Description, req.AdditionalData, req.ExecuteAt, writeOptions...)
+	if err != nil {
+		if err == db.ErrInvalidSchedule {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Scheduled time must be in the future"})
+			return
+		}
+		if err == models.ErrInvalidCurrency {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Scheduled operations are being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to schedule operation: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusCreated, scheduled)
+}
+
+// ListScheduledOperations lists scheduled operations
+// @Summary List scheduled operations
+// @Description List scheduled operations in the order they were created, optionally only those of one wallet
+// @Description or in one status.
+// @Tags scheduled
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id query string false "Only operations on this wallet"
+// @Param status query string false "Only operations in this status" Enums(pending, executed, failed, cancelled)
+// @Success 200 {object} ScheduledOperationsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /scheduled [get]
+func (h *Handler) ListScheduledOperations(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !scheduledStatuses[status] {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid status"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get scheduled operations
+	operations, err := database.ListScheduledOperations(c.Query("wallet_id"), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list scheduled operations: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, ScheduledOperationsResponse{Operations: operations})
+}
+
+// GetScheduledOperation gets a single scheduled operation by ID
+// @Summary Get scheduled operation
+// @Description Get a scheduled operation by its ID, including the transaction it recorded once executed
+// @Tags scheduled
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param scheduled_id path string true "Scheduled operation ID"
+// @Success 200 {object} models.ScheduledOperation
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /scheduled/{scheduled_id} [get]
+func (h *Handler) GetScheduledOperation(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get scheduled operation
+	scheduled, err := database.GetScheduledOperation(c.Param("scheduled_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Scheduled operation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get scheduled operation: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, scheduled)
+}
+
+// CancelScheduledOperation cancels a pending scheduled operation
+// @Summary Cancel a scheduled operation
+// @Description Cancel a pending scheduled operation so that it never runs
+// @Tags scheduled
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param scheduled_id path string true "Scheduled operation ID"
+// @Success 200 {object} models.ScheduledOperation
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /scheduled/{scheduled_id}/cancel [post]
+func (h *Handler) CancelScheduledOperation(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Cancel scheduled operation
+	scheduled, err := database.CancelScheduledOperation(c.Param("scheduled_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Scheduled operation not found"})
+			return
+		}
+		if err == db.ErrNotPending {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Scheduled operation is no longer pending"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Scheduled operation is being updated concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel scheduled operation: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, scheduled)
+}
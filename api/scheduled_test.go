@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/models"
+)
+
+func TestScheduledOperations(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	schedule := func(body string) models.ScheduledOperation {
+		w := sendRequest(router, "POST", "/api/v1/scheduled", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var scheduled models.ScheduledOperation
+		err := json.Unmarshal(w.Body.Bytes(), &scheduled)
+		assert.NoError(t, err)
+		return scheduled
+	}
+
+	getScheduled := func(id string) models.ScheduledOperation {
+		w := sendRequest(router, "GET", "/api/v1/scheduled/"+id, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var scheduled models.ScheduledOperation
+		err := json.Unmarshal(w.Body.Bytes(), &scheduled)
+		assert.NoError(t, err)
+		return scheduled
+	}
+
+	now := time.Now()
+	later := now.Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	latest := now.Add(2 * time.Hour).UTC().Format(time.RFC3339Nano)
+
+	// Schedule a grant, a removal that will lack funds and one that is cancelled
+	grant := schedule(`{"type": "add", "wallet_id": "player", "amount": "100", "description": "Holiday grant", "account": "holiday_event", "execute_at": "` + later + `"}`)
+	assert.Equal(t, models.ScheduledStatusPending, grant.Status)
+	assert.Equal(t, "faucet:holiday_event", grant.Account)
+
+	removal := schedule(`{"type": "remove", "wallet_id": "player", "amount": "500", "description": "Subscription fee", "execute_at": "` + later + `"}`)
+	cancelled := schedule(`{"type": "add", "wallet_id": "player", "amount": "10", "description": "Daily bonus", "execute_at": "` + latest + `"}`)
+
+	w := sendRequest(router, "POST", "/api/v1/scheduled/"+cancelled.ID+"/cancel", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/scheduled/"+cancelled.ID+"/cancel", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Nothing runs before its time
+	ran, err := db.RunScheduledOperations(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ran)
+
+	// Due operations run once, recording their transaction or their error; failed ones do not count as run
+	ran, err = db.RunScheduledOperations(now.Add(3 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ran)
+
+	err = dbManager.RunScheduledOperations(now.Add(3 * time.Hour))
+	assert.NoError(t, err)
+
+	ran, err = db.RunScheduledOperations(now.Add(3 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ran)
+
+	grant = getScheduled(grant.ID)
+	assert.Equal(t, models.ScheduledStatusExecuted, grant.Status)
+	assert.NotEmpty(t, grant.TransactionID)
+	assert.NotNil(t, grant.ExecutedAt)
+
+	tx, err := db.GetTransaction(grant.TransactionID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("100"), tx.Amount)
+	assert.Equal(t, "faucet:holiday_event", tx.CounterpartyWalletID)
+
+	removal = getScheduled(removal.ID)
+	assert.Equal(t, models.ScheduledStatusFailed, removal.Status)
+	assert.Empty(t, removal.TransactionID)
+	assert.NotEmpty(t, removal.Error)
+
+	assert.Equal(t, models.ScheduledStatusCancelled, getScheduled(cancelled.ID).Status)
+
+	wallet, err := db.GetWallet("player", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("100"), wallet.Balance)
+
+	// Operations can be listed by wallet and status
+	w = sendRequest(router, "GET", "/api/v1/scheduled?wallet_id=player&status=executed", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list ScheduledOperationsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &list)
+	assert.NoError(t, err)
+	assert.Len(t, list.Operations, 1)
+	assert.Equal(t, grant.ID, list.Operations[0].ID)
+
+	w = sendRequest(router, "GET", "/api/v1/scheduled?status=done", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "GET", "/api/v1/scheduled/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Operations must be scheduled in the future
+	past := now.Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+	w = sendRequest(router, "POST", "/api/v1/scheduled", `{"type": "add", "wallet_id": "player", "amount": "10", "description": "Late grant", "execute_at": "`+past+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/scheduled", `{"type": "transfer", "wallet_id": "player", "amount": "10", "description": "Gift", "execute_at": "`+later+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStats gets the database statistics for the current environment
+// @Summary Get database statistics
+// @Description Get write conflict and retry counters for the current environment since startup
+// @Tags stats
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Success 200 {object} StatsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stats [get]
+func (h *Handler) GetStats(c *gin.Context) {
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, StatsResponse{
+		Environment: middleware.GetEnvironment(c),
+		Writes:      database.Stats(),
+	})
+}
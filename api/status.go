@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWalletStatus gets the status of a wallet
+// @Summary Get wallet status
+// @Description Get the status of a wallet across all of its currencies, with the reason and actor of the change that
+// @Description set it. Wallets whose status was never changed are active.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} models.WalletStatus
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/status [get]
+func (h *Handler) GetWalletStatus(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get status
+	status, err := database.GetWalletStatus(walletID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet status: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, status)
+}
+
+// SetWalletStatus changes the status of a wallet
+// @Summary Set wallet status
+// @Description Change the status of a wallet in every currency and record the change in its audit trail.
+// @Description debit_frozen refuses removals, outgoing transfers, holds and captures but still allows credits;
+// @Description frozen refuses every debit and credit; closed refuses every write for good and requires all balances
+// @Description of the wallet to be zero. Reversals are corrections and are allowed unless the wallet is closed.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param request body WalletStatusRequest true "Wallet status"
+// @Success 200 {object} models.WalletStatus
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/status [put]
+func (h *Handler) SetWalletStatus(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	var req WalletStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Set status
+	status, err := database.SetWalletStatus(walletID, req.Status, req.Reason, req.Actor)
+	if err != nil {
+		if err == db.ErrSystemAccount {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "System accounts have no status"})
+			return
+		}
+		if err == db.ErrInvalidWalletStatus {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid wallet status"})
+			return
+		}
+		if err == db.ErrWalletNotEmpty {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet cannot be closed while it holds a balance"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to set wallet status: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, status)
+}
+
+// GetWalletStatusHistory gets the audit trail of a wallet's status
+// @Summary Get wallet status history
+// @Description Get every change of a wallet's status, oldest first, with the status it changed from and to, the
+// @Description reason and the actor
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} WalletStatusHistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id}/status/history [get]
+func (h *Handler) GetWalletStatusHistory(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get status changes
+	changes, err := database.GetWalletStatusHistory(walletID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet status history: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, WalletStatusHistoryResponse{WalletID: walletID, Changes: changes})
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/models"
+)
+
+func TestWalletStatus(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	setStatus := func(status string) *httptest.ResponseRecorder {
+		return sendRequest(router, "PUT", "/api/v1/wallets/suspect/status", `{"status": "`+status+`", "reason": "Suspected fraud", "actor": "support:alice"}`)
+	}
+
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var response ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response.Code
+	}
+
+	_, err = db.AddCurrency("suspect", "", models.MustParseAmount("100"), "Starting balance", nil)
+	assert.NoError(t, err)
+	_, err = db.AddCurrency("friend", "", models.MustParseAmount("100"), "Starting balance", nil)
+	assert.NoError(t, err)
+
+	// Wallets start out active
+	w := sendRequest(router, "GET", "/api/v1/wallets/suspect/status", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var status models.WalletStatus
+	err = json.Unmarshal(w.Body.Bytes(), &status)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletStatusActive, status.Status)
+
+	// A wallet frozen for debits can still be credited
+	w = setStatus(models.WalletStatusDebitFrozen)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/suspect/remove", `{"amount": "10", "description": "Shop"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_debits_frozen", errorCode(w))
+
+	w = sendRequest(router, "POST", "/api/v1/transfers", `{"from_wallet_id": "suspect", "to_wallet_id": "friend", "amount": "10", "description": "Gift"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/suspect/holds", `{"amount": "10", "description": "Pending purchase"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/suspect/add", `{"amount": "10", "description": "Compensation"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var added TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &added)
+	assert.NoError(t, err)
+
+	w = sendRequest(router, "POST", "/api/v1/transfers", `{"from_wallet_id": "friend", "to_wallet_id": "suspect", "amount": "10", "description": "Gift"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A frozen wallet can be neither debited nor credited, but reversals still correct it
+	w = setStatus(models.WalletStatusFrozen)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/suspect/add", `{"amount": "10", "description": "Compensation"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_frozen", errorCode(w))
+
+	w = sendRequest(router, "POST", "/api/v1/batch", `{"operations": [{"type": "add", "wallet_id": "friend", "amount": "10", "description": "Reward"}, {"type": "add", "wallet_id": "suspect", "amount": "10", "description": "Reward"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_frozen", errorCode(w))
+
+	w = sendRequest(router, "POST", "/api/v1/transactions/"+added.Transaction.ID+"/reverse", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Only empty wallets can be closed, and closed wallets stay closed
+	w = setStatus(models.WalletStatusClosed)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = setStatus(models.WalletStatusActive)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/suspect/remove", `{"amount": "110", "description": "Cash out"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = setStatus(models.WalletStatusClosed)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendRequest(router, "POST", "/api/v1/wallets/suspect/add", `{"amount": "10", "description": "Compensation"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "wallet_closed", errorCode(w))
+
+	w = setStatus(models.WalletStatusActive)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Every change is recorded with its reason and actor
+	w = sendRequest(router, "GET", "/api/v1/wallets/suspect/status/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var history WalletStatusHistoryResponse
+	err = json.Unmarshal(w.Body.Bytes(), &history)
+	assert.NoError(t, err)
+	assert.Len(t, history.Changes, 4)
+	assert.Equal(t, models.WalletStatusActive, history.Changes[0].From)
+	assert.Equal(t, models.WalletStatusDebitFrozen, history.Changes[0].To)
+	assert.Equal(t, models.WalletStatusClosed, history.Changes[3].To)
+	assert.Equal(t, "support:alice", history.Changes[3].Actor)
+	assert.Equal(t, "Suspected fraud", history.Changes[3].Reason)
+
+	// Statuses need a known value, a reason and an actor
+	w = sendRequest(router, "PUT", "/api/v1/wallets/friend/status", `{"status": "suspended", "reason": "Test", "actor": "support:alice"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "PUT", "/api/v1/wallets/friend/status", `{"status": "frozen", "reason": "Test"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "PUT", "/api/v1/wallets/faucet:default/status", `{"status": "frozen", "reason": "Test", "actor": "support:alice"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"virtigia-microcurrency/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTransaction gets a single transaction by ID
+// @Summary Get transaction
+// @Description Get a transaction by its ID
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param transaction_id path string true "Transaction ID"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /transactions/{transaction_id} [get]
+func (h *Handler) GetTransaction(c *gin.Context) {
+	transactionID := c.Param("transaction_id")
+	if transactionID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Transaction ID is required"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get transaction
+	tx, err := database.GetTransaction(transactionID)
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transaction: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, tx)
+}
+
+// ReverseTransaction reverses a transaction in full or in part
+// @Summary Reverse a transaction
+// @Description Post compensating entries that move the amount of a transaction and its counterparty entry back.
+// @Description Omit amount to reverse everything not reversed yet. The originals are marked reversed or partially_reversed.
+// @Description Reversals are posted even if the debited wallet goes negative.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the original result"
+// @Param transaction_id path string true "Transaction ID"
+// @Param request body ReverseTransactionRequest false "Reverse transaction request"
+// @Success 200 {object} TransactionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /transactions/{transaction_id}/reverse [post]
+func (h *Handler) ReverseTransaction(c *gin.Context) {
+	transactionID := c.Param("transaction_id")
+	if transactionID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Transaction ID is required"})
+		return
+	}
+
+	// The body is optional; an empty one reverses the whole transaction
+	var req ReverseTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	writeOptions, ok := idempotencyOptions(c)
+	if !ok {
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Reverse transaction
+	result, err := database.Reverse(transactionID, req.Amount, req.Description, req.AdditionalData, writeOptions...)
+	if err != nil {
+		if err == db.ErrNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+			return
+		}
+		if err == db.ErrNotReversible {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Transaction cannot be reversed"})
+			return
+		}
+		if err == db.ErrAlreadyReversed {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Transaction has already been reversed"})
+			return
+		}
+		if err == db.ErrReversalTooLarge {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount exceeds the part of the transaction left to reverse"})
+			return
+		}
+		if err == db.ErrIdempotencyConflict {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "Idempotency key was already used with a different request"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to reverse transaction: " + err.Error()})
+		return
+	}
+
+	// Mark responses that repeat an earlier request
+	if result.Replayed {
+		c.Header(IdempotentReplayedHeader, "true")
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, TransactionResponse{
+		Transaction: result.Transaction,
+		Wallet:      result.Wallet,
+	})
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbpkg "virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+)
+
+func TestGetTransaction(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	result, err := db.AddCurrency("wallet123", "", models.MustParseAmount("15"), "Quest reward", map[string]interface{}{"quest_id": "q1"})
+	assert.NoError(t, err)
+
+	// Create request
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/api/v1/transactions/"+result.Transaction.ID, nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tx models.Transaction
+	err = json.Unmarshal(w.Body.Bytes(), &tx)
+	assert.NoError(t, err)
+
+	assert.Equal(t, result.Transaction.ID, tx.ID)
+	assert.Equal(t, "wallet123", tx.WalletID)
+	assert.Equal(t, models.MustParseAmount("15"), tx.Amount)
+	assert.Equal(t, "q1", tx.AdditionalData["quest_id"])
+
+	// Unknown IDs are reported as not found
+	w = httptest.NewRecorder()
+	httpReq, _ = http.NewRequest("GET", "/api/v1/transactions/unknown", nil)
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReverseTransaction(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	grant, err := db.AddCurrency("wallet123", "", models.MustParseAmount("100"), "Mistaken grant", nil, dbpkg.WithSystemAccount("quest_rewards"))
+	assert.NoError(t, err)
+
+	purchase, err := db.RemoveCurrency("wallet123", "", models.MustParseAmount("50"), "Item purchase", nil)
+	assert.NoError(t, err)
+
+	reverse := func(transactionID, body string) *httptest.ResponseRecorder {
+		return sendRequest(router, "POST", "/api/v1/transactions/"+transactionID+"/reverse", body)
+	}
+
+	// Refund part of the grant
+	w := reverse(grant.Transaction.ID, `{"amount": "30", "description": "Partial clawback"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response TransactionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, models.MustParseAmount("-30"), response.Transaction.Amount)
+	assert.Equal(t, grant.Transaction.ID, response.Transaction.ReversalOf)
+	assert.Equal(t, "faucet:quest_rewards", response.Transaction.CounterpartyWalletID)
+	assert.Equal(t, "Partial clawback", response.Transaction.Description)
+	assert.Equal(t, models.MustParseAmount("20"), response.Wallet.Balance)
+
+	original, err := db.GetTransaction(grant.Transaction.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusPartiallyReversed, original.Status)
+	assert.Equal(t, models.MustParseAmount("30"), original.ReversedAmount)
+
+	// More than is left cannot be reversed
+	w = reverse(grant.Transaction.ID, `{"amount": "80"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// An empty body reverses the rest, even though the player already spent it
+	w = reverse(grant.Transaction.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("-70"), response.Transaction.Amount)
+	assert.Equal(t, "Reversal of "+grant.Transaction.ID, response.Transaction.Description)
+	assert.Equal(t, models.MustParseAmount("-50"), response.Wallet.Balance)
+
+	// Both entries of the grant are marked reversed and the faucet is back to zero
+	original, err = db.GetTransaction(grant.Transaction.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusReversed, original.Status)
+
+	entry, err := db.GetTransaction(grant.Transaction.CounterpartyTransactionID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusReversed, entry.Status)
+	assert.Equal(t, models.MustParseAmount("100"), entry.ReversedAmount)
+
+	faucet, err := db.GetWalletBalance("faucet:quest_rewards", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Amount(0), faucet)
+
+	// History shows the status of the reversed grant
+	page, err := db.GetTransactionsByWallet("wallet123", "", dbpkg.TransactionQuery{Limit: 10, SortBy: dbpkg.SortByTimestamp, SortOrder: dbpkg.SortAscending})
+	assert.NoError(t, err)
+	assert.Len(t, page.Transactions, 4)
+	assert.Equal(t, models.TransactionStatusReversed, page.Transactions[0].Status)
+
+	// A reversed transaction cannot be reversed again, and neither can a reversal
+	w = reverse(grant.Transaction.ID, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = reverse(response.Transaction.ID, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Refunding a purchase credits the wallet from the sink
+	w = reverse(purchase.Transaction.ID, `{"amount": "50"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("50"), response.Transaction.Amount)
+	assert.Equal(t, models.Amount(0), response.Wallet.Balance)
+
+	// Unknown transactions are reported as not found
+	w = reverse("unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The ledger still balances
+	trial, err := db.TrialBalance()
+	assert.NoError(t, err)
+	for _, currency := range trial {
+		assert.True(t, currency.Balanced)
+	}
+}
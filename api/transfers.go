@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTransfer moves currency between two wallets atomically
+// @Summary Transfer currency between wallets
+// @Description Debit one wallet and credit another in a single atomic operation.
+// @Description Transfers without a currency use the default currency. System accounts cannot take part in transfers.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param request body TransferRequest true "Transfer request"
+// @Success 200 {object} TransferResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /transfers [post]
+func (h *Handler) CreateTransfer(c *gin.Context) {
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount must be positive"})
+		return
+	}
+
+	if req.FromWalletID == req.ToWalletID {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Source and destination wallets must differ"})
+		return
+	}
+
+	if req.Currency != "" {
+		currency, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
+			return
+		}
+		req.Currency = currency
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Move currency between wallets
+	result, err := database.Transfer(req.FromWalletID, req.ToWalletID, req.Currency, req.Amount, req.Description, req.AdditionalData)
+	if err != nil {
+		if err == db.ErrInsufficientFunds {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Insufficient funds"})
+			return
+		}
+		if err == db.ErrSystemAccount {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "System accounts cannot take part in transfers"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to transfer currency: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, TransferResponse{
+		TransferID:      result.TransferID,
+		Currency:        result.Currency,
+		FromTransaction: result.Debit,
+		ToTransaction:   result.Credit,
+		FromWallet:      result.FromWallet,
+		ToWallet:        result.ToWallet,
+	})
+}
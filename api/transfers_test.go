@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbpkg "virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	_, err = db.AddCurrency("alice", "", models.MustParseAmount("100"), "Initial deposit", nil)
+	assert.NoError(t, err)
+
+	// Create request
+	req := TransferRequest{
+		FromWalletID: "alice",
+		ToWalletID:   "bob",
+		Amount:       models.MustParseAmount("40"),
+		Description:  "Trade",
+	}
+	reqBody, _ := json.Marshal(req)
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("POST", "/api/v1/transfers", bytes.NewBuffer(reqBody))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer test-token")
+	httpReq.Header.Set("X-ENV", "test")
+
+	router.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp TransferResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+
+	// Both legs share the transfer ID and point at each other
+	assert.NotEmpty(t, resp.TransferID)
+	assert.Equal(t, resp.TransferID, resp.FromTransaction.TransferID)
+	assert.Equal(t, resp.TransferID, resp.ToTransaction.TransferID)
+	assert.Equal(t, models.MustParseAmount("-40"), resp.FromTransaction.Amount)
+	assert.Equal(t, models.MustParseAmount("40"), resp.ToTransaction.Amount)
+	assert.Equal(t, "bob", resp.FromTransaction.CounterpartyWalletID)
+	assert.Equal(t, "alice", resp.ToTransaction.CounterpartyWalletID)
+	assert.Equal(t, models.MustParseAmount("100"), resp.FromTransaction.BalanceBefore)
+	assert.Equal(t, models.MustParseAmount("60"), resp.FromTransaction.BalanceAfter)
+	assert.Equal(t, models.MustParseAmount("0"), resp.ToTransaction.BalanceBefore)
+	assert.Equal(t, models.MustParseAmount("40"), resp.ToTransaction.BalanceAfter)
+	assert.Equal(t, models.MustParseAmount("60"), resp.FromWallet.Balance)
+	assert.Equal(t, models.MustParseAmount("40"), resp.ToWallet.Balance)
+}
+
+func TestCreateTransferInsufficientFunds(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	_, err = db.AddCurrency("alice", "", models.MustParseAmount("10"), "Initial deposit", nil)
+	assert.NoError(t, err)
+
+	for _, body := range []string{
+		`{"from_wallet_id": "alice", "to_wallet_id": "bob", "amount": "40", "description": "Too much"}`,
+		`{"from_wallet_id": "alice", "to_wallet_id": "alice", "amount": "5", "description": "Self"}`,
+		`{"from_wallet_id": "sink:default", "to_wallet_id": "bob", "amount": "5", "description": "From a system account"}`,
+		`{"from_wallet_id": "alice", "to_wallet_id": "faucet:default", "amount": "5", "description": "To a system account"}`,
+	} {
+		w := sendRequest(router, "POST", "/api/v1/transfers", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	// Neither wallet changed
+	balance, err := db.GetWalletBalance("alice", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("10"), balance)
+
+	balance, err = db.GetWalletBalance("bob", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Amount(0), balance)
+
+	page, err := db.GetTransactionsByWallet("bob", "", dbpkg.TransactionQuery{Limit: 10, SortBy: dbpkg.SortByTimestamp, SortOrder: dbpkg.SortDescending})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Transactions)
+}
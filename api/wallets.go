@@ -0,0 +1,279 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"virtigia-microcurrency/db"
+	"virtigia-microcurrency/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWallets lists the wallets of an environment
+// @Summary List wallets
+// @Description List the wallets of an environment in wallet ID order with their full records. System accounts are
+// @Description not listed. Filters narrow the listing and must all match: prefix on the wallet ID, a label, a range
+// @Description on the balance in currency (the default currency if omitted; wallets that never held it have zero)
+// @Description and a range on the last activity. Pass next_cursor from a previous response as cursor for the next page.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param prefix query string false "Wallet ID prefix"
+// @Param label query string false "Label the wallet carries"
+// @Param currency query string false "Currency of the balance range"
+// @Param min_balance query string false "Minimum balance, inclusive"
+// @Param max_balance query string false "Maximum balance, inclusive"
+// @Param active_since query string false "Last activity at or after this time (RFC3339)"
+// @Param active_before query string false "Last activity before this time (RFC3339)"
+// @Param limit query int false "Limit, at most 500" default(50) maximum(500)
+// @Param cursor query string false "Cursor from a previous page"
+// @Success 200 {object} WalletsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets [get]
+func (h *Handler) ListWallets(c *gin.Context) {
+	limit := pageLimit(c)
+
+	// Parse filters
+	filter, err := parseWalletFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid filter: " + err.Error()})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get wallets
+	page, err := database.ListWallets(db.WalletQuery{
+		Limit:  limit,
+		Cursor: c.Query("cursor"),
+		Prefix: c.Query("prefix"),
+		Filter: filter,
+	})
+	if err != nil {
+		if err == db.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid cursor"})
+			return
+		}
+		if err == models.ErrInvalidCurrency {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list wallets: " + err.Error()})
+		return
+	}
+
+	wallets := make([]*WalletResponse, 0, len(page.Wallets))
+	for _, record := range page.Wallets {
+		wallets = append(wallets, newWalletResponse(record))
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, WalletsResponse{Wallets: wallets, NextCursor: page.NextCursor})
+}
+
+// GetWallet gets the full record of a wallet
+// @Summary Get wallet
+// @Description Get a wallet's owner, labels and attributes, when it was created and last active, its status and
+// @Description its balance, version and limits in every currency it has held. exists is false for wallet IDs that
+// @Description were never written to or described, which are returned with an active status and no balances.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Success 200 {object} WalletResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id} [get]
+func (h *Handler) GetWallet(c *gin.Context) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Get wallet
+	response, err := walletResponse(database, walletID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, response)
+}
+
+// SetWalletMetadata replaces the metadata of a wallet
+// @Summary Set wallet metadata
+// @Description Replace the owner, labels and attributes of a wallet. Fields left out are cleared. A wallet that
+// @Description has never been written to is created. Repeated labels are dropped.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param request body WalletMetadataRequest true "Wallet metadata"
+// @Success 200 {object} WalletResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id} [put]
+func (h *Handler) SetWalletMetadata(c *gin.Context) {
+	var req WalletMetadataRequest
+	h.updateWalletMetadata(c, &req, func(database *db.DB, walletID string) (*models.WalletMetadata, error) {
+		return database.SetWalletMetadata(walletID, req.OwnerID, req.Labels, req.Attributes)
+	})
+}
+
+// PatchWalletMetadata changes part of the metadata of a wallet
+// @Summary Update wallet metadata
+// @Description Change the owner, labels or attributes of a wallet. Omitted fields are left unchanged; labels are
+// @Description replaced as a whole, attributes are merged into the existing ones and an attribute set to null is
+// @Description removed. A wallet that has never been written to is created.
+// @Tags wallet
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param X-ENV header string false "Environment (default: production)"
+// @Param wallet_id path string true "Wallet ID"
+// @Param request body WalletMetadataPatchRequest true "Wallet metadata changes"
+// @Success 200 {object} WalletResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /wallets/{wallet_id} [patch]
+func (h *Handler) PatchWalletMetadata(c *gin.Context) {
+	var req WalletMetadataPatchRequest
+	h.updateWalletMetadata(c, &req, func(database *db.DB, walletID string) (*models.WalletMetadata, error) {
+		return database.PatchWalletMetadata(walletID, db.WalletMetadataPatch{
+			OwnerID:    req.OwnerID,
+			Labels:     req.Labels,
+			Attributes: req.Attributes,
+		})
+	})
+}
+
+// updateWalletMetadata binds req, applies update to the wallet named in the path
+// and responds with the wallet's full record
+func (h *Handler) updateWalletMetadata(c *gin.Context, req interface{}, update func(database *db.DB, walletID string) (*models.WalletMetadata, error)) {
+	walletID := c.Param("wallet_id")
+	if walletID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wallet ID is required"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	// Get database for current environment
+	database, err := h.getDB(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get database: " + err.Error()})
+		return
+	}
+
+	// Update metadata
+	if _, err := update(database, walletID); err != nil {
+		if err == db.ErrSystemAccount {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "System accounts have no metadata"})
+			return
+		}
+		if err == db.ErrInvalidLabel {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Labels must not be empty"})
+			return
+		}
+		if err == db.ErrConflict {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Wallet is being updated concurrently, please retry"})
+			return
+		}
+		if currencyRuleError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update wallet metadata: " + err.Error()})
+		return
+	}
+
+	response, err := walletResponse(database, walletID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get wallet: " + err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, response)
+}
+
+// walletResponse reads the full record of a wallet
+func walletResponse(database *db.DB, walletID string) (*WalletResponse, error) {
+	record, err := database.GetWalletRecord(walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWalletResponse(record), nil
+}
+
+// newWalletResponse converts a stored wallet record to its response
+func newWalletResponse(record *db.WalletRecord) *WalletResponse {
+	return &WalletResponse{
+		WalletMetadata: *record.Metadata,
+		Status:         record.Status.Status,
+		Balances:       record.Balances,
+		Exists:         record.Exists,
+	}
+}
+
+// parseWalletFilter reads the filters of a wallet listing from the query string
+func parseWalletFilter(c *gin.Context) (db.WalletFilter, error) {
+	filter := db.WalletFilter{
+		Label:    c.Query("label"),
+		Currency: c.Query("currency"),
+	}
+
+	for name, target := range map[string]**models.Amount{"min_balance": &filter.MinBalance, "max_balance": &filter.MaxBalance} {
+		if value := c.Query(name); value != "" {
+			parsed, err := models.ParseAmount(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s: %v", name, err)
+			}
+			*target = &parsed
+		}
+	}
+
+	for name, target := range map[string]*time.Time{"active_since": &filter.ActiveSince, "active_before": &filter.ActiveBefore} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC3339 time", name)
+			}
+			*target = parsed
+		}
+	}
+
+	return filter, nil
+}
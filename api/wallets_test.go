@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"virtigia-microcurrency/models"
+)
+
+func TestWalletMetadata(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	getWallet := func(w *httptest.ResponseRecorder) WalletResponse {
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var wallet WalletResponse
+		err := json.Unmarshal(w.Body.Bytes(), &wallet)
+		assert.NoError(t, err)
+		return wallet
+	}
+
+	// Wallets are created by their first transaction and record their activity
+	before := time.Now()
+	_, err = db.AddCurrency("player", "", models.MustParseAmount("100"), "Starting balance", nil)
+	assert.NoError(t, err)
+
+	wallet := getWallet(sendRequest(router, "GET", "/api/v1/wallets/player", ""))
+	assert.Equal(t, "player", wallet.WalletID)
+	assert.True(t, wallet.Exists)
+	assert.Equal(t, models.WalletStatusActive, wallet.Status)
+	assert.Len(t, wallet.Balances, 1)
+	assert.Equal(t, models.MustParseAmount("100"), wallet.Balances[0].Balance)
+	assert.NotNil(t, wallet.CreatedAt)
+	assert.False(t, wallet.CreatedAt.Before(before))
+	assert.Equal(t, wallet.CreatedAt, wallet.LastActivityAt)
+	assert.Empty(t, wallet.OwnerID)
+
+	_, err = db.Transfer("player", "friend", "", models.MustParseAmount("10"), "Gift", nil)
+	assert.NoError(t, err)
+
+	active := getWallet(sendRequest(router, "GET", "/api/v1/wallets/player", ""))
+	assert.Equal(t, wallet.CreatedAt, active.CreatedAt)
+	assert.True(t, active.LastActivityAt.After(*wallet.LastActivityAt))
+
+	// Put replaces the metadata as a whole and drops repeated labels
+	wallet = getWallet(sendRequest(router, "PUT", "/api/v1/wallets/player", `{"owner_id": "player-42", "labels": ["vip", "beta", "vip"], "attributes": {"region": "eu", "level": 12}}`))
+	assert.Equal(t, "player-42", wallet.OwnerID)
+	assert.Equal(t, []string{"vip", "beta"}, wallet.Labels)
+	assert.Equal(t, "eu", wallet.Attributes["region"])
+	assert.Equal(t, float64(12), wallet.Attributes["level"])
+	assert.NotNil(t, wallet.UpdatedAt)
+	assert.Len(t, wallet.Balances, 1)
+
+	// Patch changes only what it sets and merges attributes
+	wallet = getWallet(sendRequest(router, "PATCH", "/api/v1/wallets/player", `{"labels": ["vip"], "attributes": {"level": 13, "region": null, "guild": "red"}}`))
+	assert.Equal(t, "player-42", wallet.OwnerID)
+	assert.Equal(t, []string{"vip"}, wallet.Labels)
+	assert.Equal(t, map[string]interface{}{"level": float64(13), "guild": "red"}, wallet.Attributes)
+
+	wallet = getWallet(sendRequest(router, "PUT", "/api/v1/wallets/player", `{"owner_id": "player-43"}`))
+	assert.Equal(t, "player-43", wallet.OwnerID)
+	assert.Empty(t, wallet.Labels)
+	assert.Empty(t, wallet.Attributes)
+
+	// Describing a wallet that was never written to creates it
+	wallet = getWallet(sendRequest(router, "GET", "/api/v1/wallets/newcomer", ""))
+	assert.False(t, wallet.Exists)
+	assert.Nil(t, wallet.CreatedAt)
+	assert.Empty(t, wallet.Balances)
+
+	wallet = getWallet(sendRequest(router, "PATCH", "/api/v1/wallets/newcomer", `{"owner_id": "player-44"}`))
+	assert.True(t, wallet.Exists)
+	assert.NotNil(t, wallet.CreatedAt)
+	assert.Nil(t, wallet.LastActivityAt)
+
+	// Labels must not be empty, and system accounts and closed wallets have no metadata
+	w := sendRequest(router, "PUT", "/api/v1/wallets/player", `{"labels": [""]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendRequest(router, "PUT", "/api/v1/wallets/faucet:default", `{"owner_id": "ops"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	_, err = db.SetWalletStatus("newcomer", models.WalletStatusClosed, "Account deleted", "support:alice")
+	assert.NoError(t, err)
+
+	w = sendRequest(router, "PATCH", "/api/v1/wallets/newcomer", `{"owner_id": "player-45"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListWallets(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Get database instance
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	list := func(query string) WalletsResponse {
+		w := sendRequest(router, "GET", "/api/v1/wallets"+query, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response WalletsResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response
+	}
+
+	ids := func(response WalletsResponse) []string {
+		walletIDs := []string{}
+		for _, wallet := range response.Wallets {
+			walletIDs = append(walletIDs, wallet.WalletID)
+		}
+		return walletIDs
+	}
+
+	for walletID, amount := range map[string]string{"player:1": "10", "player:2": "250", "player:3": "40", "npc:1": "5"} {
+		_, err := db.AddCurrency(walletID, "", models.MustParseAmount(amount), "Starting balance", nil)
+		assert.NoError(t, err)
+	}
+	cutoff := time.Now()
+
+	_, err = db.SetWalletMetadata("player:2", "", []string{"vip"}, nil)
+	assert.NoError(t, err)
+	_, err = db.SetWalletMetadata("player:4", "player-4", []string{"vip"}, nil)
+	assert.NoError(t, err)
+	_, err = db.AddCurrency("player:3", "", models.MustParseAmount("10"), "Quest reward", nil)
+	assert.NoError(t, err)
+
+	// Every wallet is listed in ID order, including those only described so far, but no system accounts
+	response := list("")
+	assert.Equal(t, []string{"npc:1", "player:1", "player:2", "player:3", "player:4"}, ids(response))
+	assert.Empty(t, response.NextCursor)
+	for _, wallet := range response.Wallets {
+		assert.True(t, wallet.Exists)
+	}
+
+	// Cursors continue after the last wallet of a page
+	response = list("?prefix=player:&limit=2")
+	assert.Equal(t, []string{"player:1", "player:2"}, ids(response))
+	assert.NotEmpty(t, response.NextCursor)
+
+	response = list("?prefix=player:&limit=2&cursor=" + response.NextCursor)
+	assert.Equal(t, []string{"player:3", "player:4"}, ids(response))
+	assert.Empty(t, response.NextCursor)
+
+	// Filters must all match
+	assert.Equal(t, []string{"player:2", "player:4"}, ids(list("?label=vip")))
+	assert.Equal(t, []string{"player:2", "player:3"}, ids(list("?min_balance=40")))
+	assert.Equal(t, []string{"player:1", "player:4"}, ids(list("?prefix=player:&max_balance=10")))
+	assert.Equal(t, []string{"player:2"}, ids(list("?label=vip&min_balance=100")))
+	assert.Equal(t, []string{"player:3"}, ids(list("?active_since="+cutoff.UTC().Format(time.RFC3339Nano))))
+	assert.Equal(t, []string{"npc:1", "player:1", "player:2"}, ids(list("?active_before="+cutoff.UTC().Format(time.RFC3339Nano))))
+
+	// Wallets created through the API, by a status or by a bare balance record are listed too
+	for path, body := range map[string]string{
+		"/api/v1/wallets/guest:1/add":    `{"amount": "5", "description": "Welcome bonus"}`,
+		"/api/v1/wallets/guest:2/status": `{"status": "frozen", "reason": "Suspected fraud", "actor": "support:alice"}`,
+	} {
+		method := "POST"
+		if strings.HasSuffix(path, "/status") {
+			method = "PUT"
+		}
+
+		w := sendRequest(router, method, path, body)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	err = db.SaveWallet(&models.Wallet{WalletID: "guest:3", Currency: "DEFAULT"})
+	assert.NoError(t, err)
+
+	response = list("?prefix=guest:")
+	assert.Equal(t, []string{"guest:1", "guest:2", "guest:3"}, ids(response))
+	for _, wallet := range response.Wallets {
+		assert.True(t, wallet.Exists, wallet.WalletID)
+		assert.NotNil(t, wallet.CreatedAt, wallet.WalletID)
+	}
+	assert.Equal(t, models.WalletStatusFrozen, response.Wallets[1].Status)
+
+	// Malformed filters and cursors are refused
+	for _, query := range []string{"?min_balance=abc", "?active_since=yesterday", "?cursor=%25%25", "?currency=1GOLD&min_balance=1"} {
+		w := sendRequest(router, "GET", "/api/v1/wallets"+query, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestListWalletsLimit(t *testing.T) {
+	router, dbManager, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	db, err := dbManager.GetDB("test")
+	assert.NoError(t, err)
+
+	for i := 0; i <= maxPageLimit; i++ {
+		_, err := db.AddCurrency(fmt.Sprintf("player:%03d", i), "", models.MustParseAmount("1"), "Starting balance", nil)
+		assert.NoError(t, err)
+	}
+
+	// Larger pages are capped at the maximum
+	w := sendRequest(router, "GET", "/api/v1/wallets?limit=100000", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response WalletsResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Wallets, maxPageLimit)
+	assert.NotEmpty(t, response.NextCursor)
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"bytes"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// HistoricalBalance is a wallet's balance at a point in time
+type HistoricalBalance struct {
+	Currency string
+	Balance  models.Amount
+
+	// LastTransaction is the last transaction applied at or before the requested time, if any
+	LastTransaction *models.Transaction
+}
+
+// GetWalletBalanceAt computes the balance of a wallet in one currency at the given
+// instant. It starts from the latest balance checkpoint at or before that time and
+// replays only the transactions recorded since, so at most BalanceCheckpointInterval
+// are read. An empty currency selects the default currency.
+func (d *DB) GetWalletBalanceAt(walletID, currency string, at time.Time) (*HistoricalBalance, error) {
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HistoricalBalance{Currency: currency}
+	prefix := models.TimeIndexPrefix(walletID, currency)
+
+	// Index keys sort by time first, so everything up to this key happened at or before at
+	end := append(append([]byte{}, prefix...), models.SortableTime(at.Add(time.Nanosecond))...)
+
+	err = d.db.View(func(txn *badger.Txn) error {
+		checkpoint, err := latestCheckpoint(txn, walletID, currency, at)
+		if err != nil {
+			return err
+		}
+
+		start := prefix
+		if checkpoint != nil {
+			result.Balance = checkpoint.Balance
+			result.LastTransaction, err = loadTransaction(txn, checkpoint.TransactionID)
+			if err != nil {
+				return err
+			}
+			start = result.LastTransaction.TimeIndexKey()
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(start); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			if bytes.Compare(key, end) >= 0 {
+				break
+			}
+			if checkpoint != nil && bytes.Equal(key, start) {
+				continue
+			}
+
+			tx, err := loadTransaction(txn, models.TransactionIDFromIndexKey(key))
+			if err != nil {
+				return err
+			}
+
+			// History migrated from earlier versions may start from a non-zero balance
+			if result.LastTransaction == nil {
+				result.Balance = tx.BalanceBefore
+			}
+
+			result.Balance += tx.Amount
+			result.LastTransaction = tx
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// latestCheckpoint returns the newest checkpoint of a wallet at or before at, or nil if there is none
+func latestCheckpoint(txn *badger.Txn, walletID, currency string, at time.Time) (*models.BalanceCheckpoint, error) {
+	prefix := models.CheckpointPrefix(walletID, currency)
+
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = true
+	opts.Prefix = prefix
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	it.Seek(append(append([]byte{}, prefix...), models.SortableTime(at.Add(time.Nanosecond))...))
+	if !it.Valid() {
+		return nil, nil
+	}
+
+	checkpoint := &models.BalanceCheckpoint{}
+	if err := it.Item().Value(checkpoint.FromJSON); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// newCheckpoint records the balance a wallet reached with its count-th transaction in a currency
+func newCheckpoint(tx *models.Transaction, count int64) *models.BalanceCheckpoint {
+	return &models.BalanceCheckpoint{
+		WalletID:         tx.WalletID,
+		Currency:         tx.Currency,
+		TransactionID:    tx.ID,
+		Timestamp:        tx.Timestamp,
+		Balance:          tx.BalanceAfter,
+		TransactionCount: count,
+	}
+}
+
+// saveCheckpoint writes a checkpoint with the given setter
+func saveCheckpoint(set func(key, val []byte) error, checkpoint *models.BalanceCheckpoint) error {
+	data, err := checkpoint.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return set(checkpoint.Key(), data)
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestGetWalletBalanceAt(t *testing.T) {
+	config := DefaultConfig()
+	config.BalanceCheckpointInterval = 3
+
+	d, err := NewDB(t.TempDir(), "test", config)
+	require.NoError(t, err)
+	defer d.Close()
+
+	before, err := d.GetWalletBalanceAt("w1", "", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.Amount(0), before.Balance)
+	assert.Nil(t, before.LastTransaction)
+
+	// Remember the time after each deposit of 1.00, 2.00, ... 10.00
+	var times []time.Time
+	var ids []string
+	for i := 1; i <= 10; i++ {
+		result, err := d.AddCurrency("w1", "", models.Amount(i*100), "deposit", nil)
+		require.NoError(t, err)
+		ids = append(ids, result.Transaction.ID)
+
+		time.Sleep(time.Millisecond)
+		times = append(times, time.Now())
+		time.Sleep(time.Millisecond)
+	}
+
+	wallet, err := d.GetWallet("w1", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), wallet.TransactionCount)
+
+	for i, at := range times {
+		n := i + 1
+		balance, err := d.GetWalletBalanceAt("w1", "", at)
+		require.NoError(t, err)
+		assert.Equal(t, models.Amount(n*(n+1)/2*100), balance.Balance, "after %d deposits", n)
+		require.NotNil(t, balance.LastTransaction)
+		assert.Equal(t, ids[i], balance.LastTransaction.ID)
+	}
+
+	// The instant of a transaction includes it
+	last, err := d.GetTransaction(ids[5])
+	require.NoError(t, err)
+	balance, err := d.GetWalletBalanceAt("w1", "", last.Timestamp)
+	require.NoError(t, err)
+	assert.Equal(t, last.BalanceAfter, balance.Balance)
+}
+
+func TestGetWalletBalanceAtConcurrentWrites(t *testing.T) {
+	config := DefaultConfig()
+	config.BalanceCheckpointInterval = 5
+	config.RetryAttempts = 1000
+
+	d, err := NewDB(t.TempDir(), "test", config)
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Concurrent adds conflict on the faucet and are retried, committing out of the order they started in
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := d.AddCurrency("w1", "", models.MustParseAmount("1.00"), "deposit", nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balance, err := d.GetWalletBalanceAt("w1", "", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("50.00"), balance.Balance)
+
+	// The balance as of each transaction is the balance it left
+	page, err := d.GetTransactionsByWallet("w1", "", TransactionQuery{Limit: writers, SortBy: SortByTimestamp, SortOrder: SortAscending})
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, writers)
+
+	for i, tx := range page.Transactions {
+		assert.Equal(t, models.Amount((i+1)*100), tx.BalanceAfter, "transaction %d", i)
+
+		balance, err := d.GetWalletBalanceAt("w1", "", tx.Timestamp)
+		require.NoError(t, err)
+		assert.Equal(t, tx.BalanceAfter, balance.Balance, "as of transaction %d", i)
+	}
+}
+
+func TestRunningBalancesChainUnderConcurrentWrites(t *testing.T) {
+	config := DefaultConfig()
+	config.RetryAttempts = 1000
+
+	d, err := NewDB(t.TempDir(), "test", config)
+	require.NoError(t, err)
+	defer d.Close()
+
+	_, err = d.AddCurrency("w2", "", models.MustParseAmount("100.00"), "deposit", nil)
+	require.NoError(t, err)
+
+	// Adds, removals and incoming transfers race on the same wallet
+	const writers = 60
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			switch i % 3 {
+			case 0:
+				_, err = d.AddCurrency("w1", "", models.MustParseAmount("3.00"), "deposit", nil)
+			case 1:
+				_, err = d.Transfer("w2", "w1", "", models.MustParseAmount("2.00"), "gift", nil)
+			case 2:
+				_, err = d.RemoveCurrency("w1", "", models.MustParseAmount("1.00"), "purchase", nil)
+			}
+			if err != ErrInsufficientFunds {
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	wallet, err := d.GetWallet("w1", "")
+	require.NoError(t, err)
+
+	// Read in time order, each transaction starts from the balance the one before it left
+	page, err := d.GetTransactionsByWallet("w1", "", TransactionQuery{Limit: writers, SortBy: SortByTimestamp, SortOrder: SortAscending})
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, int(wallet.TransactionCount))
+
+	assert.Equal(t, models.Amount(0), page.Transactions[0].BalanceBefore)
+	for i := 0; i+1 < len(page.Transactions); i++ {
+		assert.Equal(t, page.Transactions[i].BalanceAfter, page.Transactions[i+1].BalanceBefore, "transaction %d", i)
+	}
+	assert.Equal(t, wallet.Balance, page.Transactions[len(page.Transactions)-1].BalanceAfter)
+}
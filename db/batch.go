@@ -0,0 +1,145 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// Operations accepted by a batch in addition to adds and removals
+const operationTransfer = "transfer"
+
+// ErrBatchTooLarge is returned when a batch has more operations than allowed or
+// its writes exceed what the database can commit in a single transaction
+var ErrBatchTooLarge = errors.New("batch is too large to commit atomically")
+
+// BatchOperation is one add, remove or transfer of a batch
+type BatchOperation struct {
+	// Type is "add", "remove" or "transfer"
+	Type string
+
+	// WalletID and Account apply to adds and removals; Account names the faucet or
+	// sink as with WithSystemAccount and defaults to the default system account
+	WalletID string
+	Account  string
+
+	// Clamp and ExpiresAt apply to adds, as with WithClamp and WithExpiry
+	Clamp     bool
+	ExpiresAt *time.Time
+
+	// FromWalletID and ToWalletID apply to transfers
+	FromWalletID string
+	ToWalletID   string
+
+	Currency       string
+	Amount         models.Amount
+	Description    string
+	AdditionalData map[string]interface{}
+}
+
+// BatchResult is the outcome of one operation of a batch. Adds and removals set
+// Result and transfers set Transfer.
+type BatchResult struct {
+	Result   *Result
+	Transfer *TransferResult
+}
+
+// BatchError reports which operation of a batch failed. The whole batch was rolled back.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch applies adds, removals and transfers in order in a single database
+// transaction, so either all of them are committed or none is. Each operation
+// sees the balances left by the ones before it. If an operation fails the error
+// is a *BatchError naming it.
+func (d *DB) Batch(operations []BatchOperation) ([]*BatchResult, error) {
+	if len(operations) == 0 {
+		return nil, errors.New("batch has no operations")
+	}
+
+	if len(operations) > d.config.BatchMaxOperations {
+		return nil, ErrBatchTooLarge
+	}
+
+	// Validate every operation before touching the database, resolving defaults on a copy
+	operations = append([]BatchOperation(nil), operations...)
+	for i := range operations {
+		op := &operations[i]
+
+		var err error
+		switch op.Type {
+		case operationAdd, operationRemove:
+			account := op.Account
+			if account == "" {
+				account = models.DefaultSystemAccount
+			}
+			op.Currency, op.Account, err = d.writeTarget(op.Type, op.WalletID, op.Currency, op.Amount, account)
+			if err == nil {
+				err = checkExpiry(op.Type, op.ExpiresAt, time.Now())
+			}
+		case operationTransfer:
+			op.Currency, err = d.transferTarget(op.FromWalletID, op.ToWalletID, op.Currency, op.Amount)
+		default:
+			err = fmt.Errorf("unknown operation type %q", op.Type)
+		}
+
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+	}
+
+	var results []*BatchResult
+	err := d.update(func(txn *badger.Txn) error {
+		now := time.Now()
+		results = make([]*BatchResult, 0, len(operations))
+
+		for i, op := range operations {
+			result := &BatchResult{}
+
+			var err error
+			if op.Type == operationTransfer {
+				result.Transfer, err = d.postTransfer(txn, op.FromWalletID, op.ToWalletID, op.Currency, op.Amount, op.Description, op.AdditionalData, now)
+			} else {
+				// An expiry that passed while the batch was retried is refused like any other
+				err = checkExpiry(op.Type, op.ExpiresAt, now)
+				if err == nil {
+					result.Result, err = d.postWrite(txn, op.Type, op.WalletID, op.Account, op.Currency, op.Amount, op.Description, op.AdditionalData, writeOptions{clamp: op.Clamp, expiresAt: op.ExpiresAt}, now)
+				}
+			}
+
+			if err == badger.ErrTxnTooBig {
+				return ErrBatchTooLarge
+			}
+			if err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+
+	if err == badger.ErrTxnTooBig {
+		return nil, ErrBatchTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"virtigia-microcurrency/models"
+)
+
+// Config holds the settings shared by the databases of every environment
+type Config struct {
+	// DefaultCurrency is the currency used when a request names none, and the
+	// currency that balances stored before multi-currency wallets are assigned to
+	DefaultCurrency string
+
+	// IdempotencyRetention is how long idempotency keys are remembered
+	IdempotencyRetention time.Duration
+
+	// RetryAttempts is how many times a conflicting write is attempted before giving up
+	RetryAttempts int
+
+	// RetryBaseDelay is the backoff before the first retry; it doubles up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// BalanceCheckpointInterval is how many transactions of a wallet pass between balance checkpoints
+	BalanceCheckpointInterval int
+
+	// HoldTTL is how long a hold lasts when it is created without a TTL; no hold may last longer than MaxHoldTTL
+	HoldTTL    time.Duration
+	MaxHoldTTL time.Duration
+
+	// BatchMaxOperations is the most operations a single batch may contain
+	BatchMaxOperations int
+
+	// LotExpiryInterval is how often the background job looks for lots that have expired
+	LotExpiryInterval time.Duration
+
+	// ScheduleInterval is how often the background job looks for scheduled operations that are due
+	ScheduleInterval time.Duration
+
+	// ScheduleMaxAttempts is how many runs of a scheduled operation may fail without
+	// breaking a rule, e.g. on storage errors, before the operation is marked failed
+	ScheduleMaxAttempts int
+
+	// GrantGracePeriod is how overdue an occurrence of a grant with the skip catch-up policy may be and still be granted
+	GrantGracePeriod time.Duration
+
+	// MaxTransaction and MaxBalance cap every currency of the environment, if set: they
+	// are the limits of the default currency until it is registered and a ceiling on the
+	// limits of registered currencies
+	MaxTransaction *models.Amount
+	MaxBalance     *models.Amount
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		DefaultCurrency:      "DEFAULT",
+		IdempotencyRetention: 24 * time.Hour,
+		RetryAttempts:        10,
+		RetryBaseDelay:       time.Millisecond,
+		RetryMaxDelay:        100 * time.Millisecond,
+
+		BalanceCheckpointInterval: 100,
+
+		HoldTTL:    15 * time.Minute,
+		MaxHoldTTL: 24 * time.Hour,
+
+		BatchMaxOperations: 100,
+
+		LotExpiryInterval: time.Minute,
+		ScheduleInterval:  10 * time.Second,
+		GrantGracePeriod:  5 * time.Minute,
+
+		ScheduleMaxAttempts: 5,
+	}
+}
+
+// ConfigFromEnv returns the default configuration overridden by environment variables
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+
+	if value := os.Getenv("DEFAULT_CURRENCY"); value != "" {
+		currency, err := models.ParseCurrency(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid DEFAULT_CURRENCY: %q", value)
+		}
+		config.DefaultCurrency = currency
+	}
+
+	if err := durationFromEnv("IDEMPOTENCY_RETENTION", &config.IdempotencyRetention); err != nil {
+		return config, err
+	}
+
+	if err := intFromEnv("WRITE_RETRY_ATTEMPTS", &config.RetryAttempts); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("WRITE_RETRY_BASE_DELAY", &config.RetryBaseDelay); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("WRITE_RETRY_MAX_DELAY", &config.RetryMaxDelay); err != nil {
+		return config, err
+	}
+
+	if err := intFromEnv("BALANCE_CHECKPOINT_INTERVAL", &config.BalanceCheckpointInterval); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("HOLD_TTL", &config.HoldTTL); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("HOLD_MAX_TTL", &config.MaxHoldTTL); err != nil {
+		return config, err
+	}
+
+	if config.HoldTTL > config.MaxHoldTTL {
+		return config, fmt.Errorf("HOLD_TTL %s exceeds HOLD_MAX_TTL %s", config.HoldTTL, config.MaxHoldTTL)
+	}
+
+	if err := intFromEnv("BATCH_MAX_OPERATIONS", &config.BatchMaxOperations); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("LOT_EXPIRY_INTERVAL", &config.LotExpiryInterval); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("SCHEDULE_INTERVAL", &config.ScheduleInterval); err != nil {
+		return config, err
+	}
+
+	if err := intFromEnv("SCHEDULE_MAX_ATTEMPTS", &config.ScheduleMaxAttempts); err != nil {
+		return config, err
+	}
+
+	if err := durationFromEnv("GRANT_GRACE_PERIOD", &config.GrantGracePeriod); err != nil {
+		return config, err
+	}
+
+	if err := amountFromEnv("MAX_TRANSACTION", &config.MaxTransaction); err != nil {
+		return config, err
+	}
+
+	if err := amountFromEnv("MAX_BALANCE", &config.MaxBalance); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// durationFromEnv parses a positive duration such as "24h" from an environment variable if it is set
+func durationFromEnv(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf("invalid %s: %q", name, value)
+	}
+
+	*target = parsed
+	return nil
+}
+
+// amountFromEnv parses a positive amount such as "10000.00" from an environment variable if it is set
+func amountFromEnv(name string, target **models.Amount) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := models.ParseAmount(value)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf("invalid %s: %q", name, value)
+	}
+
+	*target = &parsed
+	return nil
+}
+
+// intFromEnv parses a positive integer from an environment variable if it is set
+func intFromEnv(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf("invalid %s: %q", name, value)
+	}
+
+	*target = parsed
+	return nil
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"errors"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrUnknownCurrency is returned when a write names a currency that is not registered
+	ErrUnknownCurrency = errors.New("currency is not registered")
+
+	// ErrCurrencyPrecision is returned when an amount has more decimal places than its currency allows
+	ErrCurrencyPrecision = errors.New("amount has more decimal places than the currency allows")
+
+	// ErrAmountBelowMinimum is returned when an amount is smaller than the currency's minimum transaction
+	ErrAmountBelowMinimum = errors.New("amount is below the minimum transaction of the currency")
+
+	// ErrAmountAboveMaximum is returned when an amount is larger than the currency's maximum transaction
+	ErrAmountAboveMaximum = errors.New("amount is above the maximum transaction of the currency")
+
+	// ErrBalanceLimitExceeded is returned when a credit would take a wallet above the currency's maximum balance
+	ErrBalanceLimitExceeded = errors.New("balance would exceed the maximum balance of the currency")
+
+	// ErrCurrencyNotTransferable is returned when a transfer names a currency that cannot be transferred
+	ErrCurrencyNotTransferable = errors.New("currency cannot be transferred between wallets")
+)
+
+// defaultCurrency is the definition of the default currency until one is registered:
+// it follows the configured decimals and has only the limits of the environment
+func (d *DB) defaultCurrency() *models.Currency {
+	return d.capCurrency(&models.Currency{
+		Code:         d.config.DefaultCurrency,
+		Name:         d.config.DefaultCurrency,
+		Decimals:     models.Decimals(),
+		Transferable: true,
+	})
+}
+
+// capCurrency lowers the maximum transaction and balance of a currency to those of
+// the environment where the currency allows more, so that every check and clamp of
+// an amount against the currency also stays within the environment's limits
+func (d *DB) capCurrency(currency *models.Currency) *models.Currency {
+	if limit := d.config.MaxTransaction; limit != nil && (currency.MaxTransaction == nil || *currency.MaxTransaction > *limit) {
+		capped := *limit
+		currency.MaxTransaction = &capped
+	}
+	if limit := d.config.MaxBalance; limit != nil && (currency.MaxBalance == nil || *currency.MaxBalance > *limit) {
+		capped := *limit
+		currency.MaxBalance = &capped
+	}
+	return currency
+}
+
+// GetCurrency retrieves a currency definition by code, with the limits of the environment applied
+func (d *DB) GetCurrency(code string) (*models.Currency, error) {
+	code, err := d.currency(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var currency *models.Currency
+	err = d.db.View(func(txn *badger.Txn) error {
+		currency, err = d.loadCurrency(txn, code)
+		return err
+	})
+
+	if err == ErrUnknownCurrency {
+		return nil, ErrNotFound
+	}
+	return currency, err
+}
+
+// ListCurrencies retrieves every currency definition ordered by code, including
+// the default currency even if it has not been registered, with the limits of the
+// environment applied
+func (d *DB) ListCurrencies() ([]*models.Currency, error) {
+	currencies := []*models.Currency{}
+	prefix := models.CurrencyKey("")
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			currency := &models.Currency{}
+			if err := it.Item().Value(currency.FromJSON); err != nil {
+				return err
+			}
+			currencies = append(currencies, d.capCurrency(currency))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert the built-in default currency where it sorts
+	for i, currency := range currencies {
+		if currency.Code == d.config.DefaultCurrency {
+			return currencies, nil
+		}
+		if currency.Code > d.config.DefaultCurrency {
+			currencies = append(currencies[:i], append([]*models.Currency{d.defaultCurrency()}, currencies[i:]...)...)
+			return currencies, nil
+		}
+	}
+
+	return append(currencies, d.defaultCurrency()), nil
+}
+
+// SaveCurrency creates or replaces a currency definition. The new rules apply to
+// later transactions; balances already held are not changed.
+func (d *DB) SaveCurrency(currency *models.Currency) error {
+	code, err := models.ParseCurrency(currency.Code)
+	if err != nil {
+		return err
+	}
+	currency.Code = code
+
+	if err := currency.Validate(); err != nil {
+		return err
+	}
+
+	data, err := currency.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return d.update(func(txn *badger.Txn) error {
+		return txn.Set(currency.Key(), data)
+	})
+}
+
+// loadCurrency reads a currency definition inside a transaction and caps it at the
+// limits of the environment. The default currency is always defined; any other
+// unregistered code is ErrUnknownCurrency.
+func (d *DB) loadCurrency(txn *badger.Txn, code string) (*models.Currency, error) {
+	item, err := txn.Get(models.CurrencyKey(code))
+	if err == badger.ErrKeyNotFound {
+		if code == d.config.DefaultCurrency {
+			return d.defaultCurrency(), nil
+		}
+		return nil, ErrUnknownCurrency
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	currency := &models.Currency{}
+	if err := item.Value(currency.FromJSON); err != nil {
+		return nil, err
+	}
+
+	return d.capCurrency(currency), nil
+}
+
+// checkAmount validates the size of a transaction against the rules of its currency
+func checkAmount(currency *models.Currency, amount models.Amount) error {
+	if !currency.HasPrecision(amount) {
+		return ErrCurrencyPrecision
+	}
+	if currency.MinTransaction != nil && amount < *currency.MinTransaction {
+		return ErrAmountBelowMinimum
+	}
+	if currency.MaxTransaction != nil && amount > *currency.MaxTransaction {
+		return ErrAmountAboveMaximum
+	}
+	return nil
+}
+
+// checkDebit reports ErrInsufficientFunds if removing amount would take the wallet's
+// available balance, its balance less held, below zero in a currency that may not go
+// negative, or below its credit limit if the wallet has one
+func checkDebit(currency *models.Currency, wallet *models.Wallet, exists bool, held, amount models.Amount) error {
+	if !currency.AllowNegative && (!exists || wallet.Balance-held < amount-wallet.CreditLimit) {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
+
+// checkCredit reports ErrBalanceLimitExceeded if adding amount would take the wallet
+// above the maximum balance of the currency, or ErrWalletBalanceLimit if it would take
+// it above its own maximum balance
+func checkCredit(currency *models.Currency, wallet *models.Wallet, amount models.Amount) error {
+	if currency.MaxBalance != nil && wallet.Balance > *currency.MaxBalance-amount {
+		return ErrBalanceLimitExceeded
+	}
+	if wallet.MaxBalance != nil && wallet.Balance > *wallet.MaxBalance-amount {
+		return ErrWalletBalanceLimit
+	}
+	return nil
+}
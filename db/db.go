@@ -19,25 +19,53 @@ var (
 
 	// ErrInsufficientFunds is returned when a wallet doesn't have enough balance
 	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrDuplicateTransactionID is returned instead of overwriting an existing transaction
+	ErrDuplicateTransactionID = errors.New("transaction ID already exists")
+
+	// ErrSameWallet is returned when a transfer names the same wallet on both sides
+	ErrSameWallet = errors.New("cannot transfer to the same wallet")
+
+	// ErrUnbalancedEntries is returned instead of posting a pair of entries that does not sum to zero
+	ErrUnbalancedEntries = errors.New("entries do not balance")
+
+	// ErrSystemAccount is returned when currency is added to or removed from a system account directly
+	ErrSystemAccount = errors.New("system accounts cannot be credited or debited directly")
+
+	// ErrVersionMismatch is returned when a conditional write finds the wallet at a different version than expected
+	ErrVersionMismatch = errors.New("wallet version does not match the expected version")
+
+	// ErrAmountOverflow is returned instead of posting a transaction whose balance would not fit into an Amount
+	ErrAmountOverflow = errors.New("balance would overflow")
 )
 
 // DB represents the database for a specific environment
 type DB struct {
 	db          *badger.DB
 	environment string
+	config      Config
+	ids         idGenerator
+	stats       writeStats
 }
 
 // DBManager manages database connections for different environments
 type DBManager struct {
 	baseDir     string
+	config      Config
 	connections map[string]*DB
 	mu          sync.RWMutex
 }
 
-// NewDBManager creates a new database manager
+// NewDBManager creates a new database manager with the default configuration
 func NewDBManager(baseDir string) *DBManager {
+	return NewDBManagerWithConfig(baseDir, DefaultConfig())
+}
+
+// NewDBManagerWithConfig creates a new database manager with the given configuration
+func NewDBManagerWithConfig(baseDir string, config Config) *DBManager {
 	return &DBManager{
 		baseDir:     baseDir,
+		config:      config,
 		connections: make(map[string]*DB),
 	}
 }
@@ -66,7 +94,7 @@ func (m *DBManager) GetDB(environment string) (*DB, error) {
 	dataDir := filepath.Join(m.baseDir, environment)
 
 	// Create the database
-	db, err := NewDB(dataDir, environment)
+	db, err := NewDB(dataDir, environment, m.config)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +104,42 @@ func (m *DBManager) GetDB(environment string) (*DB, error) {
 	return db, nil
 }
 
+// OpenExisting opens every environment that already has a data directory,
+// so that pending migrations run at startup rather than on the first request
+func (m *DBManager) OpenExisting() error {
+	entries, err := os.ReadDir(m.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		if _, err := m.GetDB(entry.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openDatabases returns the databases of the environments opened so far by environment
+func (m *DBManager) openDatabases() map[string]*DB {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	databases := make(map[string]*DB, len(m.connections))
+	for environment, db := range m.connections {
+		databases[environment] = db
+	}
+	return databases
+}
+
 // Close closes all database connections
 func (m *DBManager) Close() error {
 	m.mu.Lock()
@@ -92,7 +156,7 @@ func (m *DBManager) Close() error {
 }
 
 // NewDB creates a new database instance for a specific environment
-func NewDB(dataDir string, environment string) (*DB, error) {
+func NewDB(dataDir string, environment string, config Config) (*DB, error) {
 	// Ensure data directory exists
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, err
@@ -106,10 +170,31 @@ func NewDB(dataDir string, environment string) (*DB, error) {
 		return nil, err
 	}
 
-	return &DB{
+	d := &DB{
 		db:          db,
 		environment: environment,
-	}, nil
+		config:      config,
+	}
+
+	// Amounts can only be read back with the decimal places they were written with
+	if err := d.checkDecimals(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Bring existing data up to the current layout before serving requests
+	if err := d.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Make sure new transaction IDs sort after every stored one
+	if err := d.seedIDs(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return d, nil
 }
 
 // Close closes the database
@@ -117,101 +202,183 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-// GetWallet retrieves a wallet by wallet ID
-func (d *DB) GetWallet(walletID string) (*models.Wallet, error) {
-	wallet := &models.Wallet{WalletID: walletID}
+// currency validates a currency code, falling back to the default currency when it is empty
+func (d *DB) currency(code string) (string, error) {
+	if code == "" {
+		return d.config.DefaultCurrency, nil
+	}
+	return models.ParseCurrency(code)
+}
 
-	err := d.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(wallet.Key())
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return ErrNotFound
-			}
-			return err
-		}
+// GetWallet retrieves the balance of a wallet in one currency. An empty currency selects the default currency.
+func (d *DB) GetWallet(walletID, currency string) (*models.Wallet, error) {
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallet *models.Wallet
 
-		return item.Value(func(val []byte) error {
-			return wallet.FromJSON(val)
-		})
+	// A wallet that doesn't exist yet has a zero balance
+	err = d.db.View(func(txn *badger.Txn) error {
+		var err error
+		wallet, err = loadBalance(txn, walletID, currency)
+		return err
 	})
 
-	if err == ErrNotFound {
-		// If wallet doesn't exist, create a new one with zero balance
-		wallet.Balance = 0
-		return wallet, nil
+	if err != nil {
+		return nil, err
 	}
 
-	return wallet, err
+	return wallet, nil
 }
 
-// GetWalletBalance retrieves the balance of a wallet by wallet ID
-func (d *DB) GetWalletBalance(walletID string) (float64, error) {
-	wallet, err := d.GetWallet(walletID)
+// GetWalletBalance retrieves the balance of a wallet in one currency
+func (d *DB) GetWalletBalance(walletID, currency string) (models.Amount, error) {
+	wallet, err := d.GetWallet(walletID, currency)
 	if err != nil {
 		return 0, err
 	}
 	return wallet.Balance, nil
 }
 
-// SaveWallet saves a wallet to the database
-func (d *DB) SaveWallet(wallet *models.Wallet) error {
-	data, err := wallet.ToJSON()
-	if err != nil {
+// GetWalletBalances retrieves the balances of a wallet in every currency it has held, ordered by currency
+func (d *DB) GetWalletBalances(walletID string) ([]*models.Wallet, error) {
+	var wallets []*models.Wallet
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		wallets, err = walletBalances(txn, walletID)
 		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return d.db.Update(func(txn *badger.Txn) error {
-		return txn.Set(wallet.Key(), data)
+	return wallets, nil
+}
+
+// SaveWallet saves a wallet to the database, creating the wallet's record if it has none yet
+func (d *DB) SaveWallet(wallet *models.Wallet) error {
+	return d.update(func(txn *badger.Txn) error {
+		if !models.IsSystemAccount(wallet.WalletID) {
+			if err := createWallet(txn, wallet.WalletID, time.Now()); err != nil {
+				return err
+			}
+		}
+		return saveWallet(txn, wallet)
 	})
 }
 
 // SaveTransaction saves a transaction to the database
 func (d *DB) SaveTransaction(tx *models.Transaction) error {
-	data, err := tx.ToJSON()
-	if err != nil {
-		return err
-	}
+	return d.update(func(txn *badger.Txn) error {
+		return saveTransaction(txn.Set, tx)
+	})
+}
 
-	return d.db.Update(func(txn *badger.Txn) error {
-		// Save by transaction ID
-		if err := txn.Set(tx.Key(), data); err != nil {
-			return err
-		}
+// GetTransaction retrieves a transaction by ID
+func (d *DB) GetTransaction(transactionID string) (*models.Transaction, error) {
+	var tx *models.Transaction
 
-		// Save by wallet ID (for indexing)
-		return txn.Set(tx.WalletKey(), data)
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		tx, err = loadTransaction(txn, transactionID)
+		return err
 	})
+
+	return tx, err
 }
 
-// GetTransactionsByWallet retrieves transactions for a wallet with pagination and sorting
-func (d *DB) GetTransactionsByWallet(walletID string, limit, offset int, sortBy string, sortOrder string) ([]*models.Transaction, error) {
-	prefix := []byte("wallet:" + walletID + ":transaction:")
-	var transactions []*models.Transaction
+// Operations recorded by wallet writes
+const (
+	operationAdd    = "add"
+	operationRemove = "remove"
+)
 
-	err := d.db.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchSize = 100 // Collect more for sorting
-		opts.Prefix = prefix
+// Result is the outcome of a wallet write: the recorded transaction and the wallet state it produced
+type Result struct {
+	Transaction *models.Transaction
+	Wallet      *models.Wallet
+
+	// Replayed is set when the result was returned for a repeated idempotency key
+	Replayed bool
+}
 
-		it := txn.NewIterator(opts)
-		defer it.Close()
+// AddCurrency issues currency from a faucet into a wallet and records both entries.
+// An empty currency selects the default currency.
+func (d *DB) AddCurrency(walletID, currency string, amount models.Amount, description string, additionalData map[string]interface{}, opts ...WriteOption) (*Result, error) {
+	return d.applyWrite(operationAdd, walletID, currency, amount, description, additionalData, opts)
+}
 
-		// Collect all transactions for the wallet
-		for it.Seek(prefix); it.Valid(); it.Next() {
-			item := it.Item()
+// RemoveCurrency pays currency from a wallet into a sink and records both entries.
+// An empty currency selects the default currency.
+func (d *DB) RemoveCurrency(walletID, currency string, amount models.Amount, description string, additionalData map[string]interface{}, opts ...WriteOption) (*Result, error) {
+	return d.applyWrite(operationRemove, walletID, currency, amount, description, additionalData, opts)
+}
 
-			var tx models.Transaction
-			err := item.Value(func(val []byte) error {
-				return tx.FromJSON(val)
-			})
+// applyWrite updates the balances of the wallet and its system account and
+// records the balanced pair of transactions atomically
+func (d *DB) applyWrite(operation, walletID, currency string, amount models.Amount, description string, additionalData map[string]interface{}, opts []WriteOption) (*Result, error) {
+	options := newWriteOptions(opts)
 
+	currency, account, err := d.writeTarget(operation, walletID, currency, amount, options.systemAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkExpiry(operation, options.expiresAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var hash string
+	if options.idempotencyKey != "" {
+		if hash, err = requestHash(operation, walletID, currency, account, amount, description, additionalData, options); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *Result
+	err = d.update(func(txn *badger.Txn) error {
+		// Return the original outcome if this request was already applied
+		if options.idempotencyKey != "" {
+			replay, err := replayIdempotent(txn, options.idempotencyKey, hash)
 			if err != nil {
 				return err
 			}
+			if replay != nil {
+				result = replay
+				return nil
+			}
+		}
 
-			transactions = append(transactions, &tx)
+		// Refuse the write if the wallet changed since the client read it
+		if options.expectedVersion != nil {
+			wallet, _, err := loadWallet(txn, walletID, currency)
+			if err != nil {
+				return err
+			}
+			if wallet.Version != *options.expectedVersion {
+				return ErrVersionMismatch
+			}
 		}
 
+		// An expiry that passed while the write was retried is refused like any other
+		now := time.Now()
+		if err := checkExpiry(operation, options.expiresAt, now); err != nil {
+			return err
+		}
+
+		var err error
+		result, err = d.postWrite(txn, operation, walletID, account, currency, amount, description, additionalData, options, now)
+		if err != nil {
+			return err
+		}
+
+		if options.idempotencyKey != "" {
+			return d.saveIdempotent(txn, options.idempotencyKey, hash, result)
+		}
 		return nil
 	})
 
@@ -219,202 +386,358 @@ func (d *DB) GetTransactionsByWallet(walletID string, limit, offset int, sortBy
 		return nil, err
 	}
 
-	// Sort transactions
-	d.sortTransactions(transactions, sortBy, sortOrder)
+	return result, nil
+}
 
-	// Apply pagination
-	start := offset
-	end := offset + limit
+// writeTarget validates an add or removal and resolves its currency and the
+// faucet or sink named by accountName
+func (d *DB) writeTarget(operation, walletID, currency string, amount models.Amount, accountName string) (string, string, error) {
+	if amount <= 0 {
+		return "", "", errors.New("amount must be positive")
+	}
 
-	if start > len(transactions) {
-		return []*models.Transaction{}, nil
+	if models.IsSystemAccount(walletID) {
+		return "", "", ErrSystemAccount
 	}
 
-	if end > len(transactions) {
-		end = len(transactions)
+	currency, err := d.currency(currency)
+	if err != nil {
+		return "", "", err
 	}
 
-	return transactions[start:end], nil
-}
+	name, err := models.ParseSystemAccountName(accountName)
+	if err != nil {
+		return "", "", err
+	}
 
-func (d *DB) sortTransactions(transactions []*models.Transaction, sortBy string, sortOrder string) {
-	switch sortBy {
-	case "timestamp":
-		if sortOrder == "ASC" {
-			sort.Slice(transactions, func(i, j int) bool {
-				return transactions[i].Timestamp.Before(transactions[j].Timestamp)
-			})
-		} else if sortOrder == "DESC" {
-			sort.Slice(transactions, func(i, j int) bool {
-				return transactions[i].Timestamp.After(transactions[j].Timestamp)
-			})
-		}
-	case "amount":
-		if sortOrder == "ASC" {
-			sort.Slice(transactions, func(i, j int) bool {
-				return transactions[i].Amount < transactions[j].Amount
-			})
-		} else if sortOrder == "DESC" {
-			sort.Slice(transactions, func(i, j int) bool {
-				return transactions[i].Amount > transactions[j].Amount
-			})
-		}
+	if operation == operationRemove {
+		return currency, models.SinkAccount(name), nil
 	}
+	return currency, models.FaucetAccount(name), nil
 }
 
-// AddCurrency adds currency to a wallet and records the transaction
-func (d *DB) AddCurrency(walletID string, amount float64, description string, additionalData map[string]interface{}) (*models.Transaction, error) {
-	if amount <= 0 {
-		return nil, errors.New("amount must be positive")
+// postWrite checks an add or removal against the rules of its currency and the
+// wallet's status, balance and limits, then posts it against its system account inside a
+// transaction. A clamped add is first reduced to what the limits allow, and an add
+// with an expiry creates a lot.
+func (d *DB) postWrite(txn *badger.Txn, operation, walletID, account, currency string, amount models.Amount, description string, additionalData map[string]interface{}, options writeOptions, now time.Time) (*Result, error) {
+	definition, err := d.loadCurrency(txn, currency)
+	if err != nil {
+		return nil, err
 	}
 
-	tx := &models.Transaction{
-		ID:             generateID(),
-		WalletID:       walletID,
-		Amount:         amount,
-		Description:    description,
-		AdditionalData: additionalData,
-		Timestamp:      time.Now(),
+	if err := checkWalletStatus(txn, walletID, operation == operationRemove); err != nil {
+		return nil, err
 	}
 
-	err := d.db.Update(func(txn *badger.Txn) error {
-		// Get wallet
-		wallet := &models.Wallet{WalletID: walletID}
-		item, err := txn.Get(wallet.Key())
-
-		if err != nil && err != badger.ErrKeyNotFound {
-			return err
-		}
+	// Get wallet, starting from zero balance if it doesn't exist yet
+	wallet, exists, err := loadWallet(txn, walletID, currency)
+	if err != nil {
+		return nil, err
+	}
 
-		if err == nil {
-			// Wallet exists, read it
-			err = item.Value(func(val []byte) error {
-				return wallet.FromJSON(val)
-			})
+	requested := amount
+	if options.clamp && operation == operationAdd {
+		amount = clampCredit(definition, wallet, amount)
+	}
 
-			if err != nil {
-				return err
-			}
-		} else {
-			// Wallet doesn't exist, initialize with zero balance
-			wallet.Balance = 0
-		}
+	// Check the amount against the rules of the currency and the limits of the wallet
+	if err := checkAmount(definition, amount); err != nil {
+		return nil, err
+	}
+	if err := checkWalletAmount(wallet, amount); err != nil {
+		return nil, err
+	}
 
-		// Update wallet balance
-		wallet.Balance += amount
+	// Check if wallet has enough available balance, or room for the credit
+	signed := amount
+	if operation == operationRemove {
+		signed = -amount // Negative amount for removal
 
-		// Save wallet
-		walletData, err := wallet.ToJSON()
+		held, err := heldAmount(txn, walletID, currency, now)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		if err := txn.Set(wallet.Key(), walletData); err != nil {
-			return err
+		if err := checkDebit(definition, wallet, exists, held, amount); err != nil {
+			return nil, err
 		}
+	} else if err := checkCredit(definition, wallet, amount); err != nil {
+		return nil, err
+	}
 
-		// Save transaction
-		txData, err := tx.ToJSON()
-		if err != nil {
-			return err
-		}
+	// Faucets may go negative and sinks have no cap, so the system account needs no checks
+	system, err := loadAccount(txn, account, currency, walletID)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := txn.Set(tx.Key(), txData); err != nil {
-			return err
-		}
+	// Update both balances and record both entries
+	tx, entry := d.newEntries(walletID, account, currency, signed, description, additionalData, now)
+	if amount != requested {
+		tx.RequestedAmount = requested
+	}
+	tx.GrantID = options.grantID
+	entry.GrantID = options.grantID
 
-		// Save transaction by wallet ID (for indexing)
-		return txn.Set(tx.WalletKey(), txData)
-	})
+	var lot *models.Lot
+	if options.expiresAt != nil && operation == operationAdd {
+		lot = d.newLot(wallet, tx, *options.expiresAt)
+	}
 
-	if err != nil {
+	if err := d.postEntries(txn, wallet, tx, system, entry); err != nil {
 		return nil, err
 	}
 
-	return tx, nil
+	if lot != nil {
+		lot.CreatedAt = tx.Timestamp
+		if err := saveLot(txn, lot); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Result{Transaction: tx, Wallet: wallet}, nil
 }
 
-// RemoveCurrency removes currency from a wallet and records the transaction
-func (d *DB) RemoveCurrency(walletID string, amount float64, description string, additionalData map[string]interface{}) (*models.Transaction, error) {
-	if amount <= 0 {
-		return nil, errors.New("amount must be positive")
+// newEntries builds a transaction of amount on a wallet and the entry that moves
+// the same amount the other way on its counterparty, linked to each other
+func (d *DB) newEntries(walletID, counterpartyID, currency string, amount models.Amount, description string, additionalData map[string]interface{}, now time.Time) (tx, entry *models.Transaction) {
+	tx = &models.Transaction{
+		ID:                   d.ids.New(),
+		WalletID:             walletID,
+		Currency:             currency,
+		Amount:               amount,
+		Description:          description,
+		AdditionalData:       additionalData,
+		CounterpartyWalletID: counterpartyID,
+		Timestamp:            now,
 	}
 
-	tx := &models.Transaction{
-		ID:             generateID(),
-		WalletID:       walletID,
-		Amount:         -amount, // Negative amount for removal
-		Description:    description,
-		AdditionalData: additionalData,
-		Timestamp:      time.Now(),
+	entry = &models.Transaction{
+		ID:                        d.ids.New(),
+		WalletID:                  counterpartyID,
+		Currency:                  currency,
+		Amount:                    -amount,
+		Description:               description,
+		AdditionalData:            additionalData,
+		CounterpartyWalletID:      walletID,
+		CounterpartyTransactionID: tx.ID,
+		Timestamp:                 now,
 	}
+	tx.CounterpartyTransactionID = entry.ID
 
-	err := d.db.Update(func(txn *badger.Txn) error {
-		// Get wallet
-		wallet := &models.Wallet{WalletID: walletID}
-		item, err := txn.Get(wallet.Key())
+	return tx, entry
+}
 
-		if err != nil {
-			if err == badger.ErrKeyNotFound {
-				return ErrInsufficientFunds
-			}
-			return err
-		}
+// postEntries posts a balanced pair of transactions, refusing pairs that do not sum to zero
+func (d *DB) postEntries(txn *badger.Txn, wallet *models.Wallet, tx *models.Transaction, counterparty *models.Wallet, entry *models.Transaction) error {
+	if tx.Amount+entry.Amount != 0 || tx.Currency != entry.Currency {
+		return ErrUnbalancedEntries
+	}
 
-		// Wallet exists, read it
-		err = item.Value(func(val []byte) error {
-			return wallet.FromJSON(val)
-		})
+	// Balances as of a time replay history in time order, which must be commit order
+	// even if the clock stands still or steps back, so both entries are dated after
+	// the latest transaction of either wallet
+	at := counterparty.TransactionTime(wallet.TransactionTime(tx.Timestamp))
+	tx.Timestamp = at
+	entry.Timestamp = at
 
-		if err != nil {
-			return err
-		}
+	if err := d.postTransaction(txn, wallet, tx); err != nil {
+		return err
+	}
+	return d.postTransaction(txn, counterparty, entry)
+}
+
+// postTransaction applies a transaction to a wallet and stores both. It records
+// the running balance on the transaction and writes a balance checkpoint every
+// BalanceCheckpointInterval transactions of the wallet. A system account is posted
+// to its shard for the counterparty instead, which holds only part of its balance,
+// so its entries carry no running balance and it has no checkpoints.
+func (d *DB) postTransaction(txn *badger.Txn, wallet *models.Wallet, tx *models.Transaction) error {
+	balance, ok := wallet.Balance.Add(tx.Amount)
+	if !ok {
+		return ErrAmountOverflow
+	}
 
-		// Check if wallet has enough balance
-		if wallet.Balance < amount {
-			return ErrInsufficientFunds
+	system := models.IsSystemAccount(wallet.WalletID)
+	if !system {
+		tx.BalanceBefore = wallet.Balance
+		tx.BalanceAfter = balance
+	}
+	wallet.Balance = balance
+	wallet.TransactionCount++
+	wallet.Version++
+	at := tx.Timestamp
+	wallet.LastTransactionAt = &at
+
+	if system {
+		if err := saveShard(txn, wallet, tx.CounterpartyWalletID); err != nil {
+			return err
 		}
+		return insertTransaction(txn, tx)
+	}
 
-		// Update wallet balance
-		wallet.Balance -= amount
+	if err := saveWallet(txn, wallet); err != nil {
+		return err
+	}
 
-		// Save wallet
-		walletData, err := wallet.ToJSON()
-		if err != nil {
+	// Debits spend the lots that expire first; expiry transactions close their own lot
+	if tx.Amount < 0 && tx.LotID == "" {
+		if err := consumeLots(txn, wallet.WalletID, wallet.Currency, -tx.Amount); err != nil {
 			return err
 		}
+	}
 
-		if err := txn.Set(wallet.Key(), walletData); err != nil {
+	// Expiring a lot is not activity of the wallet's owner
+	expiry := tx.Amount < 0 && tx.LotID != ""
+	if !expiry {
+		if err := touchWallet(txn, wallet.WalletID, tx.Timestamp); err != nil {
 			return err
 		}
+	}
 
-		// Save transaction
-		txData, err := tx.ToJSON()
-		if err != nil {
-			return err
+	if err := insertTransaction(txn, tx); err != nil {
+		return err
+	}
+
+	if wallet.TransactionCount%int64(d.config.BalanceCheckpointInterval) == 0 {
+		return saveCheckpoint(txn.Set, newCheckpoint(tx, wallet.TransactionCount))
+	}
+	return nil
+}
+
+// loadWallet reads the balance of a wallet in one currency inside a transaction.
+// A wallet that does not hold the currency yet is returned with a zero balance
+// and exists set to false.
+func loadWallet(txn *badger.Txn, walletID, currency string) (wallet *models.Wallet, exists bool, err error) {
+	wallet = &models.Wallet{WalletID: walletID, Currency: currency}
+
+	item, err := txn.Get(wallet.Key())
+	if err == badger.ErrKeyNotFound {
+		return wallet, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = item.Value(func(val []byte) error {
+		return wallet.FromJSON(val)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return wallet, true, nil
+}
+
+// walletBalances reads the balances of a wallet in every currency inside a transaction, ordered by currency
+func walletBalances(txn *badger.Txn, walletID string) ([]*models.Wallet, error) {
+	wallets := []*models.Wallet{}
+	prefix := models.BalancePrefix(walletID)
+
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		wallet := &models.Wallet{}
+		if err := it.Item().Value(wallet.FromJSON); err != nil {
+			return nil, err
 		}
+		wallets = append(wallets, wallet)
+	}
 
-		if err := txn.Set(tx.Key(), txData); err != nil {
-			return err
+	if !models.IsSystemAccount(walletID) {
+		return wallets, nil
+	}
+
+	// System accounts add their shards, which may hold currencies without a balance record
+	byCurrency := make(map[string]*models.Wallet, len(wallets))
+	for _, wallet := range wallets {
+		byCurrency[wallet.Currency] = wallet
+	}
+
+	err := forEachShard(txn, models.ShardPrefix(walletID), func(shard *models.Wallet) error {
+		wallet := byCurrency[shard.Currency]
+		if wallet == nil {
+			wallet = &models.Wallet{WalletID: walletID, Currency: shard.Currency}
+			byCurrency[shard.Currency] = wallet
+			wallets = append(wallets, wallet)
 		}
+		return addShard(wallet, shard)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Save transaction by wallet ID (for indexing)
-		return txn.Set(tx.WalletKey(), txData)
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].Currency < wallets[j].Currency
 	})
+	return wallets, nil
+}
 
+// saveWallet writes a wallet inside a transaction
+func saveWallet(txn *badger.Txn, wallet *models.Wallet) error {
+	data, err := wallet.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return txn.Set(wallet.Key(), data)
+}
+
+// loadTransaction reads a transaction by ID inside a transaction
+func loadTransaction(txn *badger.Txn, transactionID string) (*models.Transaction, error) {
+	tx := &models.Transaction{ID: transactionID}
+
+	item, err := txn.Get(tx.Key())
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if err := item.Value(tx.FromJSON); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
+// insertTransaction writes a new transaction, refusing to overwrite an existing one with the same ID
+func insertTransaction(txn *badger.Txn, tx *models.Transaction) error {
+	_, err := txn.Get(tx.Key())
+	if err == nil {
+		return ErrDuplicateTransactionID
+	}
+	if err != badger.ErrKeyNotFound {
+		return err
+	}
+
+	return saveTransaction(txn.Set, tx)
+}
+
+// saveTransaction writes a transaction and its wallet index entries with the given setter
+func saveTransaction(set func(key, val []byte) error, tx *models.Transaction) error {
+	data, err := tx.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := set(tx.Key(), data); err != nil {
+		return err
+	}
+
+	// Index the transaction under its wallet; index entries only point at the transaction ID
+	if err := set(tx.TimeIndexKey(), nil); err != nil {
+		return err
+	}
+	return set(tx.AmountIndexKey(), nil)
+}
+
 // RunGC runs garbage collection on the database
 func (d *DB) RunGC() error {
 	return d.db.RunValueLogGC(0.5)
 }
-
-// generateID generates a unique ID for transactions
-func generateID() string {
-	return filepath.Base(time.Now().Format("20060102150405.000000000"))
-}
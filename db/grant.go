@@ -0,0 +1,718 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrInvalidGrant is returned when a grant or enrollment has no valid schedule or ends before it starts
+	ErrInvalidGrant = errors.New("grant needs either a positive interval or a valid cron expression, a known catch-up policy and an end after its start")
+
+	// ErrAlreadyEnrolled is returned when a wallet with an active or paused enrollment is enrolled again
+	ErrAlreadyEnrolled = errors.New("wallet is already enrolled in the grant")
+
+	// ErrGrantNotActive is returned when a grant or enrollment that is not active is paused
+	ErrGrantNotActive = errors.New("grant or enrollment is not active")
+
+	// ErrGrantNotPaused is returned when a grant or enrollment that is not paused is resumed
+	ErrGrantNotPaused = errors.New("grant or enrollment is not paused")
+
+	// ErrEnrollmentEnded is returned when an enrollment that already ended is cancelled
+	ErrEnrollmentEnded = errors.New("enrollment has already ended")
+)
+
+// CreateGrant stores a recurring grant. The faucet it is paid from is chosen with
+// WithSystemAccount; other write options are ignored. An empty currency selects the
+// default currency, an empty catch-up policy grants every missed occurrence and a
+// zero start starts the grant now.
+func (d *DB) CreateGrant(grant *models.Grant, opts ...WriteOption) (*models.Grant, error) {
+	if grant.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	currency, err := d.currency(grant.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newWriteOptions(opts)
+
+	name, err := models.ParseSystemAccountName(options.systemAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	grant.Currency = currency
+	grant.Account = models.FaucetAccount(name)
+	grant.Status = models.GrantStatusActive
+	grant.Pauses = nil
+
+	if grant.CatchUp == "" {
+		grant.CatchUp = models.CatchUpAll
+	}
+	if grant.StartAt.IsZero() {
+		grant.StartAt = now
+	}
+
+	if err := checkGrant(grant); err != nil {
+		return nil, err
+	}
+
+	err = d.update(func(txn *badger.Txn) error {
+		// Unknown currencies are refused now rather than when the first occurrence is due
+		if _, err := d.loadCurrency(txn, currency); err != nil {
+			return err
+		}
+
+		grant.ID = d.ids.New()
+		grant.CreatedAt = time.Now()
+		return saveGrant(txn, grant)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// GetGrant retrieves a grant by ID
+func (d *DB) GetGrant(id string) (*models.Grant, error) {
+	var grant *models.Grant
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		grant, err = loadGrant(txn, id)
+		return err
+	})
+
+	return grant, err
+}
+
+// ListGrants retrieves every grant in the order they were created
+func (d *DB) ListGrants() ([]*models.Grant, error) {
+	grants := []*models.Grant{}
+	prefix := models.GrantKey("")
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			grant := &models.Grant{}
+			if err := it.Item().Value(grant.FromJSON); err != nil {
+				return err
+			}
+			grants = append(grants, grant)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// PauseGrant stops crediting the wallets enrolled in a grant until it is resumed
+func (d *DB) PauseGrant(id string) (*models.Grant, error) {
+	return d.updateGrant(id, func(grant *models.Grant, now time.Time) error {
+		if grant.Status != models.GrantStatusActive {
+			return ErrGrantNotActive
+		}
+
+		grant.Status = models.GrantStatusPaused
+		grant.Pauses = append(grant.Pauses, models.GrantPause{PausedAt: now})
+		return nil
+	})
+}
+
+// ResumeGrant resumes a paused grant. Occurrences that fell due while it was paused are skipped.
+func (d *DB) ResumeGrant(id string) (*models.Grant, error) {
+	return d.updateGrant(id, func(grant *models.Grant, now time.Time) error {
+		if grant.Status != models.GrantStatusPaused {
+			return ErrGrantNotPaused
+		}
+
+		grant.Status = models.GrantStatusActive
+		grant.Pauses[len(grant.Pauses)-1].ResumedAt = &now
+		return nil
+	})
+}
+
+// EnrollWallet enrolls a wallet in a grant. Its occurrences start at startAt, or
+// now if it is zero, but never before the grant starts, and end before endAt if it
+// is set. A wallet whose earlier enrollment ended or was cancelled is enrolled anew.
+func (d *DB) EnrollWallet(grantID, walletID string, startAt time.Time, endAt *time.Time) (*models.GrantEnrollment, error) {
+	if models.IsSystemAccount(walletID) {
+		return nil, ErrSystemAccount
+	}
+
+	var enrollment *models.GrantEnrollment
+
+	err := d.update(func(txn *badger.Txn) error {
+		grant, err := loadGrant(txn, grantID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkWalletOpen(txn, walletID); err != nil {
+			return err
+		}
+
+		existing, err := loadEnrollment(txn, grantID, walletID)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		if existing != nil && (existing.Status == models.EnrollmentStatusActive || existing.Status == models.EnrollmentStatusPaused) {
+			return ErrAlreadyEnrolled
+		}
+
+		now := time.Now()
+		enrollment = &models.GrantEnrollment{
+			GrantID:    grantID,
+			WalletID:   walletID,
+			Status:     models.EnrollmentStatusActive,
+			StartAt:    startAt,
+			EndAt:      endAt,
+			EnrolledAt: now,
+		}
+
+		if enrollment.StartAt.IsZero() {
+			enrollment.StartAt = now
+		}
+		if enrollment.StartAt.Before(grant.StartAt) {
+			enrollment.StartAt = grant.StartAt
+		}
+		if endAt != nil && !endAt.After(enrollment.StartAt) {
+			return ErrInvalidGrant
+		}
+
+		// The first occurrence is due when the enrollment starts, or at the first cron match after it
+		first, err := grant.Next(enrollment.StartAt, enrollment.StartAt.Add(-time.Nanosecond))
+		if err != nil {
+			return err
+		}
+		scheduleEnrollment(grant, enrollment, first)
+
+		return saveEnrollment(txn, enrollment)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// GetEnrollment retrieves the enrollment of a wallet in a grant
+func (d *DB) GetEnrollment(grantID, walletID string) (*models.GrantEnrollment, error) {
+	var enrollment *models.GrantEnrollment
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		enrollment, err = loadEnrollment(txn, grantID, walletID)
+		return err
+	})
+
+	return enrollment, err
+}
+
+// ListEnrollments retrieves the enrollments of a grant ordered by wallet ID
+func (d *DB) ListEnrollments(grantID string) ([]*models.GrantEnrollment, error) {
+	enrollments := []*models.GrantEnrollment{}
+	prefix := models.GrantEnrollmentPrefix(grantID)
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		if _, err := loadGrant(txn, grantID); err != nil {
+			return err
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			enrollment := &models.GrantEnrollment{}
+			if err := it.Item().Value(enrollment.FromJSON); err != nil {
+				return err
+			}
+			enrollments = append(enrollments, enrollment)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+// PauseEnrollment stops crediting one wallet enrolled in a grant until it is resumed
+func (d *DB) PauseEnrollment(grantID, walletID string) (*models.GrantEnrollment, error) {
+	return d.updateEnrollment(grantID, walletID, func(grant *models.Grant, enrollment *models.GrantEnrollment, now time.Time) error {
+		if enrollment.Status != models.EnrollmentStatusActive {
+			return ErrGrantNotActive
+		}
+
+		enrollment.Status = models.EnrollmentStatusPaused
+		return nil
+	})
+}
+
+// ResumeEnrollment resumes a paused enrollment from its next occurrence. Occurrences
+// that fell due while it was paused are not granted.
+func (d *DB) ResumeEnrollment(grantID, walletID string) (*models.GrantEnrollment, error) {
+	return d.updateEnrollment(grantID, walletID, func(grant *models.Grant, enrollment *models.GrantEnrollment, now time.Time) error {
+		if enrollment.Status != models.EnrollmentStatusPaused {
+			return ErrGrantNotPaused
+		}
+
+		next, err := grant.Next(enrollment.StartAt, now.Add(-time.Nanosecond))
+		if err != nil {
+			return err
+		}
+
+		enrollment.Status = models.EnrollmentStatusActive
+		scheduleEnrollment(grant, enrollment, next)
+		return nil
+	})
+}
+
+// CancelEnrollment ends the enrollment of a wallet in a grant before its end
+func (d *DB) CancelEnrollment(grantID, walletID string) (*models.GrantEnrollment, error) {
+	return d.updateEnrollment(grantID, walletID, func(grant *models.Grant, enrollment *models.GrantEnrollment, now time.Time) error {
+		if enrollment.Status != models.EnrollmentStatusActive && enrollment.Status != models.EnrollmentStatusPaused {
+			return ErrEnrollmentEnded
+		}
+
+		enrollment.Status = models.EnrollmentStatusCancelled
+		enrollment.NextRunAt = nil
+		return nil
+	})
+}
+
+// ListGrantRuns retrieves the runs of a grant in order of their occurrence. A
+// non-empty walletID only lists the runs of that wallet.
+func (d *DB) ListGrantRuns(grantID, walletID string) ([]*models.GrantRun, error) {
+	runs := []*models.GrantRun{}
+	prefix := models.GrantRunPrefix(grantID)
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		if _, err := loadGrant(txn, grantID); err != nil {
+			return err
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			run := &models.GrantRun{}
+			if err := it.Item().Value(run.FromJSON); err != nil {
+				return err
+			}
+
+			if walletID != "" && run.WalletID != walletID {
+				continue
+			}
+
+			runs = append(runs, run)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// RunGrants credits the enrolled wallets with the occurrences of their grants that
+// are due by now and returns how many ran, granted or failed. Each occurrence is credited in
+// the same transaction that moves its enrollment on to the next occurrence, so it is
+// granted exactly once even if the service stops part way. An occurrence that breaks
+// a rule, e.g. the balance cap of its wallet, is recorded as a failed run; an
+// enrollment that conflicts with concurrent writes or fails for any other reason is
+// retried on the next run, and the other enrollments run regardless. The last such
+// error other than a conflict is returned.
+func (d *DB) RunGrants(now time.Time) (int, error) {
+	prefix := models.GrantDuePrefix()
+	end := expiryBoundary(prefix, now)
+
+	type enrollmentKey struct{ grantID, walletID string }
+	var due []enrollmentKey
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			if bytes.Compare(key, end) >= 0 {
+				break
+			}
+			grantID, walletID := models.EnrollmentFromDueKey(key)
+			due = append(due, enrollmentKey{grantID, walletID})
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	ran := 0
+	var lastErr error
+	for _, key := range due {
+		// Grant the enrollment's occurrences one at a time until none is due
+		for {
+			occurred, err := d.runOccurrence(key.grantID, key.walletID, now)
+			if err != nil && err != ErrConflict {
+				lastErr = err
+			}
+			if err != nil || !occurred {
+				break
+			}
+			ran++
+		}
+	}
+
+	return ran, lastErr
+}
+
+// RunGrants credits the due occurrences of recurring grants in every open environment
+func (m *DBManager) RunGrants(now time.Time) error {
+	var lastErr error
+	for environment, db := range m.openDatabases() {
+		ran, err := db.RunGrants(now)
+		if ran > 0 {
+			log.Printf("Ran %d occurrences of recurring grants in environment %s", ran, environment)
+		}
+		if err != nil {
+			log.Printf("Failed to run recurring grants in environment %s: %v", environment, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// runOccurrence grants the next due occurrence of an enrollment and reports
+// whether one ran. If crediting the wallet breaks a rule, the occurrence is
+// recorded as a failed run instead; any other error is returned without a run,
+// so that the occurrence stays due and is tried again.
+func (d *DB) runOccurrence(grantID, walletID string, now time.Time) (bool, error) {
+	var ran bool
+	err := d.update(func(txn *badger.Txn) error {
+		ran = false
+		return d.applyOccurrence(txn, grantID, walletID, now, func(grant *models.Grant, run *models.GrantRun) error {
+			options := writeOptions{grantID: grant.ID}
+			result, err := d.postWrite(txn, operationAdd, walletID, grant.Account, grant.Currency, grant.Amount, grant.Description, grant.AdditionalData, options, time.Now())
+			if err != nil {
+				return err
+			}
+
+			ran = true
+			run.Status = models.GrantRunStatusGranted
+			run.TransactionID = result.Transaction.ID
+			return nil
+		})
+	})
+
+	if !ruleErrors[err] {
+		return ran, err
+	}
+
+	// The credit was rolled back, so record why it failed instead
+	cause := err
+	err = d.update(func(txn *badger.Txn) error {
+		ran = false
+		return d.applyOccurrence(txn, grantID, walletID, now, func(grant *models.Grant, run *models.GrantRun) error {
+			ran = true
+			run.Status = models.GrantRunStatusFailed
+			run.Error = cause.Error()
+			return nil
+		})
+	})
+
+	// A failed occurrence moves the enrollment on like a granted one
+	return ran, err
+}
+
+// applyOccurrence finds the occurrence of an enrollment that is due at now, passes
+// its run to apply and then records the run and moves the enrollment on to its next
+// occurrence. Occurrences that the grant's pause or catch-up policy leaves out are
+// skipped on the way.
+func (d *DB) applyOccurrence(txn *badger.Txn, grantID, walletID string, now time.Time, apply func(grant *models.Grant, run *models.GrantRun) error) error {
+	grant, err := loadGrant(txn, grantID)
+	if err != nil {
+		return err
+	}
+
+	// Enrollments of a paused grant keep their place in the due index until it is resumed
+	if grant.Status != models.GrantStatusActive {
+		return nil
+	}
+
+	enrollment, err := loadEnrollment(txn, grantID, walletID)
+	if err != nil {
+		return err
+	}
+
+	if err := unindexEnrollment(txn, enrollment); err != nil {
+		return err
+	}
+
+	for enrollment.Status == models.EnrollmentStatusActive && enrollment.NextRunAt != nil && !enrollment.NextRunAt.After(now) {
+		due := *enrollment.NextRunAt
+		next, err := grant.Next(enrollment.StartAt, due)
+		if err != nil {
+			return err
+		}
+
+		// An occurrence that does not move on would be granted again and again
+		if !next.IsZero() && !next.After(due) {
+			return models.ErrScheduleOutOfRange
+		}
+
+		if d.missed(grant, due, next, now) {
+			enrollment.Skipped++
+			scheduleEnrollment(grant, enrollment, next)
+			continue
+		}
+
+		run := &models.GrantRun{
+			GrantID:    grantID,
+			WalletID:   walletID,
+			DueAt:      due,
+			ExecutedAt: now,
+		}
+		if err := apply(grant, run); err != nil {
+			return err
+		}
+		if err := saveGrantRun(txn, run); err != nil {
+			return err
+		}
+
+		if run.Status == models.GrantRunStatusGranted {
+			enrollment.Granted++
+		} else {
+			enrollment.Failed++
+		}
+		enrollment.LastRunAt = &now
+		scheduleEnrollment(grant, enrollment, next)
+		break
+	}
+
+	return saveEnrollment(txn, enrollment)
+}
+
+// missed reports whether an occurrence that is due is left out because the grant
+// was paused at the time or because of its catch-up policy
+func (d *DB) missed(grant *models.Grant, due, next, now time.Time) bool {
+	if grant.Paused(due) {
+		return true
+	}
+
+	switch grant.CatchUp {
+	case models.CatchUpLatest:
+		// A later occurrence is due as well
+		return !next.IsZero() && !next.After(now)
+	case models.CatchUpSkip:
+		return now.Sub(due) > d.config.GrantGracePeriod
+	}
+	return false
+}
+
+// updateGrant loads a grant, applies fn to it and saves it in one transaction
+func (d *DB) updateGrant(id string, fn func(grant *models.Grant, now time.Time) error) (*models.Grant, error) {
+	var grant *models.Grant
+
+	err := d.update(func(txn *badger.Txn) error {
+		var err error
+		grant, err = loadGrant(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(grant, time.Now()); err != nil {
+			return err
+		}
+		return saveGrant(txn, grant)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// updateEnrollment loads an enrollment and its grant, applies fn to the enrollment
+// and saves it in one transaction
+func (d *DB) updateEnrollment(grantID, walletID string, fn func(grant *models.Grant, enrollment *models.GrantEnrollment, now time.Time) error) (*models.GrantEnrollment, error) {
+	var enrollment *models.GrantEnrollment
+
+	err := d.update(func(txn *badger.Txn) error {
+		grant, err := loadGrant(txn, grantID)
+		if err != nil {
+			return err
+		}
+
+		enrollment, err = loadEnrollment(txn, grantID, walletID)
+		if err != nil {
+			return err
+		}
+
+		if err := unindexEnrollment(txn, enrollment); err != nil {
+			return err
+		}
+		if err := fn(grant, enrollment, time.Now()); err != nil {
+			return err
+		}
+		return saveEnrollment(txn, enrollment)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// checkGrant validates the schedule, catch-up policy and bounds of a grant
+func checkGrant(grant *models.Grant) error {
+	if (grant.IntervalSeconds > 0) == (grant.Cron != "") || grant.IntervalSeconds < 0 || grant.IntervalSeconds > models.MaxGrantIntervalSeconds {
+		return ErrInvalidGrant
+	}
+	if _, err := grant.Schedule(); err != nil {
+		return ErrInvalidGrant
+	}
+
+	switch grant.CatchUp {
+	case models.CatchUpAll, models.CatchUpLatest, models.CatchUpSkip:
+	default:
+		return ErrInvalidGrant
+	}
+
+	if grant.EndAt != nil && !grant.EndAt.After(grant.StartAt) {
+		return ErrInvalidGrant
+	}
+	return nil
+}
+
+// scheduleEnrollment sets the next occurrence of an enrollment, ending it if there is none before its end
+func scheduleEnrollment(grant *models.Grant, enrollment *models.GrantEnrollment, next time.Time) {
+	end := enrollment.End(grant)
+	if next.IsZero() || (end != nil && !next.Before(*end)) {
+		enrollment.Status = models.EnrollmentStatusEnded
+		enrollment.NextRunAt = nil
+		return
+	}
+	enrollment.NextRunAt = &next
+}
+
+// loadGrant reads a grant by ID inside a transaction
+func loadGrant(txn *badger.Txn, id string) (*models.Grant, error) {
+	item, err := txn.Get(models.GrantKey(id))
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &models.Grant{}
+	if err := item.Value(grant.FromJSON); err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// saveGrant writes a grant
+func saveGrant(txn *badger.Txn, grant *models.Grant) error {
+	data, err := grant.ToJSON()
+	if err != nil {
+		return err
+	}
+	return txn.Set(grant.Key(), data)
+}
+
+// loadEnrollment reads the enrollment of a wallet in a grant inside a transaction
+func loadEnrollment(txn *badger.Txn, grantID, walletID string) (*models.GrantEnrollment, error) {
+	item, err := txn.Get(models.GrantEnrollmentKey(grantID, walletID))
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment := &models.GrantEnrollment{}
+	if err := item.Value(enrollment.FromJSON); err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// unindexEnrollment removes an enrollment from the due index before its status or next occurrence changes
+func unindexEnrollment(txn *badger.Txn, enrollment *models.GrantEnrollment) error {
+	if enrollment.Status != models.EnrollmentStatusActive || enrollment.NextRunAt == nil {
+		return nil
+	}
+	return txn.Delete(enrollment.DueKey())
+}
+
+// saveEnrollment writes an enrollment, and indexes it by its next occurrence while it is active
+func saveEnrollment(txn *badger.Txn, enrollment *models.GrantEnrollment) error {
+	data, err := enrollment.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Set(enrollment.Key(), data); err != nil {
+		return err
+	}
+
+	if enrollment.Status != models.EnrollmentStatusActive || enrollment.NextRunAt == nil {
+		return nil
+	}
+	return txn.Set(enrollment.DueKey(), nil)
+}
+
+// saveGrantRun writes the run of one occurrence of a grant
+func saveGrantRun(txn *badger.Txn, run *models.GrantRun) error {
+	data, err := run.ToJSON()
+	if err != nil {
+		return err
+	}
+	return txn.Set(run.Key(), data)
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestGrantPausedTwice(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	start := time.Now().Add(-10 * time.Hour)
+	grant, err := d.CreateGrant(&models.Grant{Name: "Hourly bonus", Amount: models.MustParseAmount("10"), Description: "Hourly bonus", IntervalSeconds: 3600, StartAt: start})
+	require.NoError(t, err)
+
+	// Every pause is kept, not only the last one
+	for i := 0; i < 2; i++ {
+		_, err = d.PauseGrant(grant.ID)
+		require.NoError(t, err)
+		grant, err = d.ResumeGrant(grant.ID)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, grant.Pauses, 2)
+	for _, pause := range grant.Pauses {
+		require.NotNil(t, pause.ResumedAt)
+		assert.True(t, grant.Paused(pause.PausedAt))
+		assert.False(t, grant.Paused(*pause.ResumedAt))
+	}
+
+	// Move both pauses into the past so that occurrences fell due in each of them
+	resumed := []time.Time{start.Add(3*time.Hour + 30*time.Minute), start.Add(6*time.Hour + 30*time.Minute)}
+	grant.Pauses = []models.GrantPause{
+		{PausedAt: start.Add(time.Hour + 30*time.Minute), ResumedAt: &resumed[0]},
+		{PausedAt: start.Add(5*time.Hour + 30*time.Minute), ResumedAt: &resumed[1]},
+	}
+	err = d.db.Update(func(txn *badger.Txn) error {
+		return saveGrant(txn, grant)
+	})
+	require.NoError(t, err)
+
+	_, err = d.EnrollWallet(grant.ID, "w1", start, nil)
+	require.NoError(t, err)
+
+	// Of the eleven occurrences due so far, those at two, three and six hours fell in a pause
+	ran, err := d.RunGrants(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 8, ran)
+
+	enrollment, err := d.GetEnrollment(grant.ID, "w1")
+	require.NoError(t, err)
+	assert.Equal(t, 8, enrollment.Granted)
+	assert.Equal(t, 3, enrollment.Skipped)
+
+	balance, err := d.GetWalletBalance("w1", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("80"), balance)
+}
+
+func TestGrantIntervalOutOfRange(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Intervals whose duration would overflow are refused
+	for _, seconds := range []int64{models.MaxGrantIntervalSeconds + 1, 10000000000, 1 << 55} {
+		_, err := d.CreateGrant(&models.Grant{Name: "Rare bonus", Amount: models.MustParseAmount("10"), Description: "Rare bonus", IntervalSeconds: seconds})
+		assert.Equal(t, ErrInvalidGrant, err, seconds)
+
+		// Grants stored with such an interval have no next occurrence rather than one that never moves on
+		grant := &models.Grant{IntervalSeconds: seconds}
+		_, err = grant.Next(time.Now(), time.Now().Add(time.Hour))
+		assert.Equal(t, models.ErrScheduleOutOfRange, err, seconds)
+	}
+
+	// The longest interval still steps forward from far past starts
+	grant := &models.Grant{IntervalSeconds: models.MaxGrantIntervalSeconds}
+	start := time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Now()
+	next, err := grant.Next(start, now)
+	require.NoError(t, err)
+	assert.True(t, next.After(now))
+	assert.False(t, next.After(now.Add(models.MaxGrantIntervalSeconds*time.Second)))
+}
+
+func TestRunGrantsRetriesStorageErrors(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	start := time.Now().Add(-90 * time.Minute)
+	grant, err := d.CreateGrant(&models.Grant{Name: "Hourly bonus", Amount: models.MustParseAmount("10"), Description: "Hourly bonus", IntervalSeconds: 3600, StartAt: start})
+	require.NoError(t, err)
+
+	for _, walletID := range []string{"w1", "w2"} {
+		_, err = d.EnrollWallet(grant.ID, walletID, start, nil)
+		require.NoError(t, err)
+	}
+
+	// A balance record that cannot be read fails the credit of w1 without breaking any rule
+	wallet := &models.Wallet{WalletID: "w1", Currency: d.config.DefaultCurrency}
+	setBalanceRecord := func(val []byte) {
+		err := d.db.Update(func(txn *badger.Txn) error {
+			return txn.Set(wallet.Key(), val)
+		})
+		require.NoError(t, err)
+	}
+	stored, err := wallet.ToJSON()
+	require.NoError(t, err)
+
+	setBalanceRecord([]byte("{"))
+	ran, err := d.RunGrants(time.Now())
+	assert.Error(t, err)
+
+	// w2 is granted regardless, while w1 keeps its occurrences due without a failed run
+	assert.Equal(t, 2, ran)
+
+	enrollment, err := d.GetEnrollment(grant.ID, "w1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, enrollment.Granted)
+	assert.Equal(t, 0, enrollment.Failed)
+
+	runs, err := d.ListGrantRuns(grant.ID, "w1")
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	// Once the record can be read again the occurrences are granted on the next run
+	setBalanceRecord(stored)
+	ran, err = d.RunGrants(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 2, ran)
+
+	for _, walletID := range []string{"w1", "w2"} {
+		balance, err := d.GetWalletBalance(walletID, "")
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseAmount("20"), balance, walletID)
+	}
+}
@@ -0,0 +1,317 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Sort fields and orders supported by transaction history
+const (
+	SortByTimestamp = "timestamp"
+	SortByAmount    = "amount"
+	SortAscending   = "ASC"
+	SortDescending  = "DESC"
+)
+
+// Directions for filtering transaction history
+const (
+	DirectionCredit = "credit"
+	DirectionDebit  = "debit"
+)
+
+// TransactionQuery selects a page of a wallet's transaction history
+type TransactionQuery struct {
+	Limit     int
+	Offset    int
+	SortBy    string
+	SortOrder string
+
+	// Cursor continues from a page returned earlier; it overrides Offset, SortBy and SortOrder
+	Cursor string
+
+	Filter TransactionFilter
+}
+
+// TransactionFilter restricts transaction history. Zero values match every transaction.
+type TransactionFilter struct {
+	// From and To bound the timestamp, both inclusive
+	From time.Time
+	To   time.Time
+
+	// Direction is DirectionCredit or DirectionDebit
+	Direction string
+
+	// MinAmount and MaxAmount bound the signed amount, both inclusive
+	MinAmount *models.Amount
+	MaxAmount *models.Amount
+
+	// Description matches transactions whose description contains it, ignoring case
+	Description string
+
+	// AdditionalData matches transactions whose additional data has all of these values
+	AdditionalData map[string]string
+}
+
+// matches reports whether a transaction passes every filter
+func (f *TransactionFilter) matches(tx *models.Transaction) bool {
+	if !f.From.IsZero() && tx.Timestamp.Before(f.From) || !f.To.IsZero() && tx.Timestamp.After(f.To) {
+		return false
+	}
+
+	if f.Direction == DirectionCredit && tx.Amount <= 0 || f.Direction == DirectionDebit && tx.Amount >= 0 {
+		return false
+	}
+
+	if f.MinAmount != nil && tx.Amount < *f.MinAmount || f.MaxAmount != nil && tx.Amount > *f.MaxAmount {
+		return false
+	}
+
+	if f.Description != "" && !strings.Contains(strings.ToLower(tx.Description), strings.ToLower(f.Description)) {
+		return false
+	}
+
+	for key, want := range f.AdditionalData {
+		value, ok := tx.AdditionalData[key]
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bounds returns the index key range that can contain matching transactions,
+// so that ranges of time or amount outside the filter are never read.
+// A nil bound is open; upper is exclusive.
+func (f *TransactionFilter) bounds(prefix []byte, sortBy string) (lower, upper []byte) {
+	bound := func(value string) []byte {
+		return append(append([]byte{}, prefix...), value...)
+	}
+
+	if sortBy == SortByAmount {
+		min, max := f.MinAmount, f.MaxAmount
+		if f.Direction == DirectionCredit && (min == nil || *min < 1) {
+			one := models.Amount(1)
+			min = &one
+		}
+		if f.Direction == DirectionDebit && (max == nil || *max > -1) {
+			minusOne := models.Amount(-1)
+			max = &minusOne
+		}
+
+		if min != nil {
+			lower = bound(models.SortableAmount(*min))
+		}
+		if max != nil && *max < math.MaxInt64 {
+			upper = bound(models.SortableAmount(*max + 1))
+		}
+		return lower, upper
+	}
+
+	if !f.From.IsZero() {
+		lower = bound(models.SortableTime(f.From))
+	}
+	if !f.To.IsZero() {
+		upper = bound(models.SortableTime(f.To.Add(time.Nanosecond)))
+	}
+	return lower, upper
+}
+
+// TransactionPage is a page of a wallet's transaction history
+type TransactionPage struct {
+	Transactions []*models.Transaction
+	SortBy       string
+	SortOrder    string
+	NextCursor   string
+	PrevCursor   string
+}
+
+// cursor is the decoded form of an opaque pagination cursor
+type cursor struct {
+	Key       string `json:"k"`
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Backward  bool   `json:"b,omitempty"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.Key == "" {
+		return c, ErrInvalidCursor
+	}
+
+	if c.SortBy != SortByTimestamp && c.SortBy != SortByAmount || c.SortOrder != SortAscending && c.SortOrder != SortDescending {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// GetTransactionsByWallet retrieves a page of a wallet's transactions in one currency.
+// Transactions are read in order from the wallet's time or amount index, so
+// without filters only the requested page is loaded regardless of the size of
+// the history. Time and amount filters narrow the index range that is read;
+// the remaining filters are applied to each transaction in that range.
+// An empty currency selects the default currency.
+func (d *DB) GetTransactionsByWallet(walletID, currency string, query TransactionQuery) (*TransactionPage, error) {
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{
+		Transactions: []*models.Transaction{},
+		SortBy:       query.SortBy,
+		SortOrder:    query.SortOrder,
+	}
+
+	var position cursor
+	if query.Cursor != "" {
+		if position, err = decodeCursor(query.Cursor); err != nil {
+			return nil, err
+		}
+		page.SortBy = position.SortBy
+		page.SortOrder = position.SortOrder
+	}
+
+	prefix := models.TimeIndexPrefix(walletID, currency)
+	if page.SortBy == SortByAmount {
+		prefix = models.AmountIndexPrefix(walletID, currency)
+	}
+
+	// Walking back from a cursor runs against the requested order
+	reverse := (page.SortOrder == SortDescending) != position.Backward
+
+	filter := &query.Filter
+	lower, upper := filter.bounds(prefix, page.SortBy)
+
+	err = d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Reverse = reverse
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// Position the iterator at the start of the page
+		skip := 0
+		switch {
+		case position.Key != "":
+			start := append(append([]byte{}, prefix...), position.Key...)
+			it.Seek(start)
+			if it.Valid() && bytes.Equal(it.Item().Key(), start) {
+				it.Next()
+			}
+		case reverse && upper != nil:
+			it.Seek(upper)
+			skip = query.Offset
+		case reverse:
+			it.Seek(append(append([]byte{}, prefix...), 0xff))
+			skip = query.Offset
+		case lower != nil:
+			it.Seek(lower)
+			skip = query.Offset
+		default:
+			it.Seek(prefix)
+			skip = query.Offset
+		}
+
+		// Read one extra match to learn whether another page follows
+		var keys [][]byte
+		var matched []*models.Transaction
+		for ; it.Valid() && len(matched) <= query.Limit; it.Next() {
+			key := it.Item().Key()
+
+			// Stop once the walk leaves the range the filter allows
+			if reverse && lower != nil && bytes.Compare(key, lower) < 0 || !reverse && upper != nil && bytes.Compare(key, upper) >= 0 {
+				break
+			}
+			if reverse && upper != nil && bytes.Compare(key, upper) >= 0 || !reverse && lower != nil && bytes.Compare(key, lower) < 0 {
+				continue
+			}
+
+			tx, err := loadTransaction(txn, models.TransactionIDFromIndexKey(key))
+			if err != nil {
+				return err
+			}
+
+			if !filter.matches(tx) {
+				continue
+			}
+
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			keys = append(keys, it.Item().KeyCopy(nil))
+			matched = append(matched, tx)
+		}
+
+		more := len(keys) > query.Limit
+		if more {
+			keys = keys[:query.Limit]
+			matched = matched[:query.Limit]
+		}
+
+		if position.Backward {
+			for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+				keys[i], keys[j] = keys[j], keys[i]
+				matched[i], matched[j] = matched[j], matched[i]
+			}
+		}
+
+		page.Transactions = append(page.Transactions, matched...)
+
+		if len(keys) == 0 {
+			return nil
+		}
+
+		first := cursor{Key: string(keys[0][len(prefix):]), SortBy: page.SortBy, SortOrder: page.SortOrder, Backward: true}
+		last := cursor{Key: string(keys[len(keys)-1][len(prefix):]), SortBy: page.SortBy, SortOrder: page.SortOrder}
+
+		if position.Backward {
+			// Walking back: the page we came from always follows
+			page.NextCursor = last.encode()
+			if more {
+				page.PrevCursor = first.encode()
+			}
+		} else {
+			if more {
+				page.NextCursor = last.encode()
+			}
+			if position.Key != "" || query.Offset > 0 {
+				page.PrevCursor = first.encode()
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
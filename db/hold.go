@@ -0,0 +1,438 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrHoldNotActive is returned when a hold that was captured, released or has expired is captured or released
+	ErrHoldNotActive = errors.New("hold is no longer active")
+
+	// ErrCaptureTooLarge is returned when a capture exceeds the amount held
+	ErrCaptureTooLarge = errors.New("capture exceeds the amount held")
+
+	// ErrInvalidHoldTTL is returned when a hold TTL is negative or longer than the maximum hold TTL
+	ErrInvalidHoldTTL = errors.New("hold TTL is out of range")
+)
+
+// CaptureResult is the outcome of capturing a hold: the captured hold, the
+// transaction that paid it into its sink and the wallet state it produced
+type CaptureResult struct {
+	Hold        *models.Hold
+	Transaction *models.Transaction
+	Wallet      *models.Wallet
+}
+
+// CreateHold reserves amount of a wallet's available balance until the hold is captured,
+// released or expires after ttl. A zero ttl uses the configured HoldTTL. The sink the
+// hold is captured into is chosen with WithSystemAccount. An empty currency selects the
+// default currency.
+func (d *DB) CreateHold(walletID, currency string, amount models.Amount, ttl time.Duration, description string, additionalData map[string]interface{}, opts ...WriteOption) (*models.Hold, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	if ttl == 0 {
+		ttl = d.config.HoldTTL
+	}
+	if ttl < 0 || ttl > d.config.MaxHoldTTL {
+		return nil, ErrInvalidHoldTTL
+	}
+
+	if models.IsSystemAccount(walletID) {
+		return nil, ErrSystemAccount
+	}
+
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newWriteOptions(opts)
+
+	name, err := models.ParseSystemAccountName(options.systemAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	var hold *models.Hold
+	err = d.update(func(txn *badger.Txn) error {
+		now := time.Now()
+		hold = &models.Hold{
+			ID:             d.ids.New(),
+			WalletID:       walletID,
+			Currency:       currency,
+			Amount:         amount,
+			Account:        models.SinkAccount(name),
+			Status:         models.HoldStatusActive,
+			Description:    description,
+			AdditionalData: additionalData,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(ttl),
+		}
+
+		definition, err := d.loadCurrency(txn, currency)
+		if err != nil {
+			return err
+		}
+
+		if err := checkAmount(definition, amount); err != nil {
+			return err
+		}
+
+		// A hold reserves currency to be spent, so it is refused like a debit
+		if err := checkWalletStatus(txn, walletID, true); err != nil {
+			return err
+		}
+
+		wallet, exists, err := loadWallet(txn, walletID, currency)
+		if err != nil {
+			return err
+		}
+
+		if err := checkWalletAmount(wallet, amount); err != nil {
+			return err
+		}
+
+		// Clear out expired holds so the index only grows with live ones
+		if err := expireHolds(txn, walletID, currency, now); err != nil {
+			return err
+		}
+
+		held, err := heldAmount(txn, walletID, currency, now)
+		if err != nil {
+			return err
+		}
+
+		if err := checkDebit(definition, wallet, exists, held, amount); err != nil {
+			return err
+		}
+
+		return saveHold(txn, hold)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// GetHold retrieves a hold by ID. Holds past their expiry are reported as expired.
+func (d *DB) GetHold(holdID string) (*models.Hold, error) {
+	var hold *models.Hold
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		hold, err = loadHold(txn, holdID, time.Now())
+		return err
+	})
+
+	return hold, err
+}
+
+// GetHolds retrieves the active holds of a wallet in one currency, ordered by expiry.
+// An empty currency selects the default currency.
+func (d *DB) GetHolds(walletID, currency string) ([]*models.Hold, error) {
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var holds []*models.Hold
+	err = d.db.View(func(txn *badger.Txn) error {
+		holds, err = activeHolds(txn, walletID, currency, time.Now())
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// GetAvailableBalance retrieves the balance of a wallet in one currency together with
+// its available balance, the balance less active holds. An empty currency selects the
+// default currency.
+func (d *DB) GetAvailableBalance(walletID, currency string) (*models.Wallet, models.Amount, error) {
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var wallet *models.Wallet
+	var held models.Amount
+
+	err = d.db.View(func(txn *badger.Txn) error {
+		wallet, err = loadBalance(txn, walletID, currency)
+		if err != nil {
+			return err
+		}
+
+		held, err = heldAmount(txn, walletID, currency, time.Now())
+		return err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return wallet, wallet.Balance - held, nil
+}
+
+// CaptureHold pays amount of an active hold from its wallet into its sink and closes
+// the hold; whatever is not captured is released. A zero amount captures the whole hold.
+func (d *DB) CaptureHold(holdID string, amount models.Amount) (*CaptureResult, error) {
+	if amount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+
+	var result *CaptureResult
+	err := d.update(func(txn *badger.Txn) error {
+		now := time.Now()
+		hold, err := loadHold(txn, holdID, now)
+		if err != nil {
+			return err
+		}
+
+		if !hold.Active(now) {
+			return ErrHoldNotActive
+		}
+
+		captured := amount
+		if captured == 0 {
+			captured = hold.Amount
+		}
+		if captured > hold.Amount {
+			return ErrCaptureTooLarge
+		}
+
+		definition, err := d.loadCurrency(txn, hold.Currency)
+		if err != nil {
+			return err
+		}
+
+		if !definition.HasPrecision(captured) {
+			return ErrCurrencyPrecision
+		}
+
+		if err := checkWalletStatus(txn, hold.WalletID, true); err != nil {
+			return err
+		}
+
+		wallet, exists, err := loadWallet(txn, hold.WalletID, hold.Currency)
+		if err != nil {
+			return err
+		}
+
+		// The hold reserved the amount, so only the other holds count against it
+		held, err := heldAmount(txn, hold.WalletID, hold.Currency, now)
+		if err != nil {
+			return err
+		}
+
+		if err := checkDebit(definition, wallet, exists, held-hold.Amount, captured); err != nil {
+			return err
+		}
+
+		sink, err := loadAccount(txn, hold.Account, hold.Currency, hold.WalletID)
+		if err != nil {
+			return err
+		}
+
+		tx, entry := d.newEntries(hold.WalletID, hold.Account, hold.Currency, -captured, hold.Description, hold.AdditionalData, now)
+		tx.HoldID = hold.ID
+		entry.HoldID = hold.ID
+
+		if err := d.postEntries(txn, wallet, tx, sink, entry); err != nil {
+			return err
+		}
+
+		if err := closeHold(txn, hold, models.HoldStatusCaptured); err != nil {
+			return err
+		}
+
+		hold.CapturedAmount = captured
+		hold.TransactionID = tx.ID
+		if err := saveHold(txn, hold); err != nil {
+			return err
+		}
+
+		result = &CaptureResult{Hold: hold, Transaction: tx, Wallet: wallet}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReleaseHold closes an active hold without moving any currency
+func (d *DB) ReleaseHold(holdID string) (*models.Hold, error) {
+	var hold *models.Hold
+	err := d.update(func(txn *badger.Txn) error {
+		now := time.Now()
+
+		var err error
+		hold, err = loadHold(txn, holdID, now)
+		if err != nil {
+			return err
+		}
+
+		if !hold.Active(now) {
+			return ErrHoldNotActive
+		}
+
+		if err := closeHold(txn, hold, models.HoldStatusReleased); err != nil {
+			return err
+		}
+		return saveHold(txn, hold)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// heldAmount sums the holds on a wallet in one currency that are active at now
+func heldAmount(txn *badger.Txn, walletID, currency string, now time.Time) (models.Amount, error) {
+	holds, err := activeHolds(txn, walletID, currency, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var held models.Amount
+	for _, hold := range holds {
+		held += hold.Amount
+	}
+	return held, nil
+}
+
+// activeHolds reads the holds on a wallet in one currency that are active at now, in expiry order
+func activeHolds(txn *badger.Txn, walletID, currency string, now time.Time) ([]*models.Hold, error) {
+	holds := []*models.Hold{}
+	prefix := models.HoldIndexPrefix(walletID, currency)
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = prefix
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	// Index keys sort by expiry, so holds that expired by now come before this key
+	for it.Seek(expiryBoundary(prefix, now)); it.Valid(); it.Next() {
+		hold, err := loadHold(txn, models.HoldIDFromIndexKey(it.Item().Key()), now)
+		if err != nil {
+			return nil, err
+		}
+
+		if hold.Active(now) {
+			holds = append(holds, hold)
+		}
+	}
+
+	return holds, nil
+}
+
+// expireHolds marks the holds on a wallet in one currency that expired by now and
+// removes them from the wallet's index. Expired holds stop counting as soon as they
+// expire; this only keeps their records and the index tidy.
+func expireHolds(txn *badger.Txn, walletID, currency string, now time.Time) error {
+	prefix := models.HoldIndexPrefix(walletID, currency)
+	end := expiryBoundary(prefix, now)
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = prefix
+
+	// Collect the expired holds first; the iterator must be closed before they are rewritten
+	var ids []string
+	it := txn.NewIterator(opts)
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		key := it.Item().Key()
+		if bytes.Compare(key, end) >= 0 {
+			break
+		}
+		ids = append(ids, models.HoldIDFromIndexKey(key))
+	}
+	it.Close()
+
+	for _, id := range ids {
+		hold, err := loadHold(txn, id, now)
+		if err != nil {
+			return err
+		}
+
+		if err := closeHold(txn, hold, models.HoldStatusExpired); err != nil {
+			return err
+		}
+		if err := saveHold(txn, hold); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expiryBoundary returns the first key of an index ordered by expiry under prefix
+// that expires after now
+func expiryBoundary(prefix []byte, now time.Time) []byte {
+	return append(append([]byte{}, prefix...), models.SortableTime(now.Add(time.Nanosecond))...)
+}
+
+// loadHold reads a hold by ID inside a transaction. An active hold past its expiry is returned as expired.
+func loadHold(txn *badger.Txn, holdID string, now time.Time) (*models.Hold, error) {
+	item, err := txn.Get(models.HoldKey(holdID))
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hold := &models.Hold{}
+	if err := item.Value(hold.FromJSON); err != nil {
+		return nil, err
+	}
+
+	if hold.Status == models.HoldStatusActive && !hold.Active(now) {
+		hold.Status = models.HoldStatusExpired
+	}
+
+	return hold, nil
+}
+
+// closeHold sets the final status of a hold and removes it from its wallet's index
+func closeHold(txn *badger.Txn, hold *models.Hold, status string) error {
+	hold.Status = status
+	return txn.Delete(hold.IndexKey())
+}
+
+// saveHold writes a hold, and indexes it under its wallet while it is active
+func saveHold(txn *badger.Txn, hold *models.Hold) error {
+	data, err := hold.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Set(hold.Key(), data); err != nil {
+		return err
+	}
+
+	if hold.Status != models.HoldStatusActive {
+		return nil
+	}
+	return txn.Set(hold.IndexKey(), nil)
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// crockford is the Crockford base32 alphabet used to encode IDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// idLength is the length of an encoded ID
+const idLength = 26
+
+// idGenerator produces ULID-style IDs: a 48-bit millisecond timestamp followed
+// by 80 random bits, encoded so that IDs sort lexicographically by time.
+// IDs created within the same millisecond increment the random part, so every
+// ID is strictly greater than the previous one even under concurrent use.
+type idGenerator struct {
+	mu      sync.Mutex
+	lastMS  uint64
+	lastRnd [10]byte
+}
+
+// New returns a new ID greater than every ID previously returned or observed
+func (g *idGenerator) New() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms > g.lastMS {
+		g.lastMS = ms
+		if _, err := rand.Read(g.lastRnd[:]); err != nil {
+			panic("db: failed to read random bytes: " + err.Error())
+		}
+	} else if !increment(g.lastRnd[:]) {
+		// The random part overflowed, borrow the next millisecond
+		g.lastMS++
+	}
+
+	return encodeID(g.lastMS, g.lastRnd)
+}
+
+// Observe makes sure future IDs sort after id. Values that are not IDs are ignored.
+func (g *idGenerator) Observe(id string) {
+	ms, rnd, ok := decodeID(id)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms > g.lastMS || ms == g.lastMS && string(rnd[:]) > string(g.lastRnd[:]) {
+		g.lastMS = ms
+		g.lastRnd = rnd
+	}
+}
+
+// increment adds one to a big-endian number, reporting false on overflow
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeID encodes a timestamp and random part as 26 base32 characters
+func encodeID(ms uint64, rnd [10]byte) string {
+	var raw [16]byte
+	binary.BigEndian.PutUint16(raw[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(ms))
+	copy(raw[6:], rnd[:])
+
+	// 128 bits are encoded 5 bits at a time, with the first character carrying the top 3 bits
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+
+	var out [idLength]byte
+	for i := idLength - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// decodeID reverses encodeID
+func decodeID(id string) (ms uint64, rnd [10]byte, ok bool) {
+	if len(id) != idLength || id[0] > '7' {
+		return 0, rnd, false
+	}
+
+	var hi, lo uint64
+	for i := 0; i < idLength; i++ {
+		v := indexCrockford(id[i])
+		if v < 0 {
+			return 0, rnd, false
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[0:8], hi)
+	binary.BigEndian.PutUint64(raw[8:16], lo)
+
+	ms = uint64(binary.BigEndian.Uint16(raw[0:2]))<<32 | uint64(binary.BigEndian.Uint32(raw[2:6]))
+	copy(rnd[:], raw[6:])
+	return ms, rnd, true
+}
+
+func indexCrockford(c byte) int {
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// seedIDs feeds the newest stored transaction ID to the generator, so that new
+// IDs keep sorting after existing ones even if the clock moved backwards
+func (d *DB) seedIDs() error {
+	prefix := []byte("transaction:")
+
+	return d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Reverse = true
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// Generated IDs start with '0' for the next thousand years, which keeps them
+		// apart from the timestamp-formatted IDs written by earlier versions
+		for it.Seek(append(prefix, '1')); it.Valid(); it.Next() {
+			id := string(it.Item().Key()[len(prefix):])
+			if _, _, ok := decodeID(id); ok {
+				d.ids.Observe(id)
+				return nil
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestIDGeneratorUniqueAndOrdered(t *testing.T) {
+	var g idGenerator
+
+	const workers, perWorker = 8, 2000
+	ids := make([][]string, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ids[w] = append(ids[w], g.New())
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, list := range ids {
+		// IDs handed to one caller are strictly increasing
+		assert.True(t, sort.StringsAreSorted(list))
+		for _, id := range list {
+			assert.Len(t, id, idLength)
+			assert.False(t, seen[id], "duplicate ID %s", id)
+			seen[id] = true
+		}
+	}
+}
+
+func TestIDGeneratorSeededFromStoredTransactions(t *testing.T) {
+	dir := t.TempDir()
+
+	d, err := NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+
+	// Store a transaction whose ID lies in the future, as if the clock had moved backwards since
+	var future idGenerator
+	future.lastMS = uint64(time.Now().Add(time.Hour).UnixMilli())
+	futureID := future.New()
+	require.NoError(t, d.SaveTransaction(&models.Transaction{ID: futureID, WalletID: "w1", Timestamp: time.Now()}))
+	require.NoError(t, d.Close())
+
+	d, err = NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	result, err := d.AddCurrency("w1", "", models.MustParseAmount("1"), "After restart", nil)
+	require.NoError(t, err)
+	assert.Greater(t, result.Transaction.ID, futureID)
+}
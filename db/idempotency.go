@@ -0,0 +1,156 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// ErrIdempotencyConflict is returned when an idempotency key is reused with a different request
+var ErrIdempotencyConflict = errors.New("idempotency key was already used with a different request")
+
+// WriteOption customises a single wallet write
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	idempotencyKey  string
+	systemAccount   string
+	expectedVersion *int64
+	clamp           bool
+	expiresAt       *time.Time
+
+	// grantID links the transactions of a recurring grant to it; only the grant worker sets it
+	grantID string
+}
+
+// WithIdempotencyKey makes a write idempotent: repeating it with the same key
+// and the same request returns the original result instead of applying it again
+func WithIdempotencyKey(key string) WriteOption {
+	return func(o *writeOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// WithSystemAccount names the faucet an add is issued from or the sink a removal is
+// paid into, e.g. "quest_rewards" for faucet:quest_rewards. Writes without it use
+// the default faucet and sink.
+func WithSystemAccount(name string) WriteOption {
+	return func(o *writeOptions) {
+		o.systemAccount = name
+	}
+}
+
+// WithExpectedVersion makes a write conditional: it fails with ErrVersionMismatch
+// unless the wallet is still at the given version. Wallets that do not hold the
+// currency yet are at version 0.
+func WithExpectedVersion(version int64) WriteOption {
+	return func(o *writeOptions) {
+		o.expectedVersion = &version
+	}
+}
+
+// WithClamp makes an add credit as much of its amount as the maximum transaction and
+// maximum balance of the currency and the wallet allow instead of failing, e.g. for
+// rewards that should top a wallet up to its cap. The transaction records the amount
+// originally requested. Removals are never clamped.
+func WithClamp() WriteOption {
+	return func(o *writeOptions) {
+		o.clamp = true
+	}
+}
+
+// WithExpiry makes the currency credited by an add expire at the given time. The
+// credit is tracked as a lot of the wallet; see ExpireLots.
+func WithExpiry(expiresAt time.Time) WriteOption {
+	return func(o *writeOptions) {
+		o.expiresAt = &expiresAt
+	}
+}
+
+func newWriteOptions(opts []WriteOption) writeOptions {
+	options := writeOptions{systemAccount: models.DefaultSystemAccount}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// requestHash fingerprints a write so that a reused idempotency key can be checked against it
+func requestHash(operation, walletID, currency, account string, amount models.Amount, description string, additionalData map[string]interface{}, options writeOptions) (string, error) {
+	fields := map[string]interface{}{
+		"operation":       operation,
+		"wallet_id":       walletID,
+		"currency":        currency,
+		"account":         account,
+		"amount":          amount,
+		"description":     description,
+		"additional_data": additionalData,
+	}
+
+	// Only writes that use them carry these fields, so keys stored before they existed keep their hash
+	if options.clamp {
+		fields["clamp"] = true
+	}
+	if options.expiresAt != nil {
+		fields["expires_at"] = options.expiresAt.UTC()
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replayIdempotent returns the stored result for an idempotency key, or nil if the key is unused
+func replayIdempotent(txn *badger.Txn, key, hash string) (*Result, error) {
+	item, err := txn.Get(models.IdempotencyKey(key))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record models.IdempotencyRecord
+	if err := item.Value(record.FromJSON); err != nil {
+		return nil, err
+	}
+
+	if record.RequestHash != hash {
+		return nil, ErrIdempotencyConflict
+	}
+
+	tx, err := loadTransaction(txn, record.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Transaction: tx, Wallet: record.Wallet, Replayed: true}, nil
+}
+
+// saveIdempotent stores the result of a write under its idempotency key for the retention window
+func (d *DB) saveIdempotent(txn *badger.Txn, key, hash string, result *Result) error {
+	record := &models.IdempotencyRecord{
+		Key:           key,
+		RequestHash:   hash,
+		TransactionID: result.Transaction.ID,
+		Wallet:        result.Wallet,
+		CreatedAt:     time.Now(),
+	}
+
+	data, err := record.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return txn.SetEntry(badger.NewEntry(models.IdempotencyKey(key), data).WithTTL(d.config.IdempotencyRetention))
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// TrialBalance sums one currency across every account. In a consistent ledger
+// the balances of wallets, faucets and sinks and the amounts of all entries each sum to zero.
+type TrialBalance struct {
+	Currency string `json:"currency" example:"GOLD"`
+
+	// Wallets, Faucets and Sinks are the summed balances of each kind of account
+	Wallets models.Amount `json:"wallets" swaggertype:"string" example:"250.00"`
+	Faucets models.Amount `json:"faucets" swaggertype:"string" example:"-300.00"`
+	Sinks   models.Amount `json:"sinks" swaggertype:"string" example:"50.00"`
+
+	// Balances is the sum of every account balance and Entries the sum of every transaction amount
+	Balances models.Amount `json:"balances" swaggertype:"string" example:"0.00"`
+	Entries  models.Amount `json:"entries" swaggertype:"string" example:"0.00"`
+
+	Balanced bool `json:"balanced"`
+}
+
+// TrialBalance sums every account balance and every transaction per currency, ordered by currency.
+// It reads the whole ledger, so it is meant for audits rather than for serving players.
+func (d *DB) TrialBalance() ([]*TrialBalance, error) {
+	totals := make(map[string]*TrialBalance)
+	total := func(currency string) *TrialBalance {
+		if totals[currency] == nil {
+			totals[currency] = &TrialBalance{Currency: currency}
+		}
+		return totals[currency]
+	}
+
+	add := func(wallet *models.Wallet) error {
+		t := total(wallet.Currency)
+		switch {
+		case strings.HasPrefix(wallet.WalletID, models.FaucetPrefix):
+			t.Faucets += wallet.Balance
+		case strings.HasPrefix(wallet.WalletID, models.SinkPrefix):
+			t.Sinks += wallet.Balance
+		default:
+			t.Wallets += wallet.Balance
+		}
+		return nil
+	}
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		if err := forEachWallet(txn, add); err != nil {
+			return err
+		}
+
+		// System accounts hold most of their balance in shards rather than in their balance record
+		if err := forEachShard(txn, []byte("shard:"), add); err != nil {
+			return err
+		}
+
+		prefix := []byte("transaction:")
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			var tx models.Transaction
+			if err := it.Item().Value(tx.FromJSON); err != nil {
+				return err
+			}
+			total(tx.Currency).Entries += tx.Amount
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*TrialBalance, 0, len(totals))
+	for _, t := range totals {
+		t.Balances = t.Wallets + t.Faucets + t.Sinks
+		t.Balanced = t.Balances == 0 && t.Entries == 0
+		result = append(result, t)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result, nil
+}
+
+// forEachWallet passes every balance record of every wallet and currency to fn
+func forEachWallet(txn *badger.Txn, fn func(wallet *models.Wallet) error) error {
+	prefix := []byte("wallet:")
+
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		item := it.Item()
+
+		// Index entries under the wallet prefix carry no value
+		if item.ValueSize() == 0 {
+			continue
+		}
+
+		wallet := &models.Wallet{}
+		if err := item.Value(wallet.FromJSON); err != nil {
+			return err
+		}
+
+		// Checkpoints share the prefix but are stored under other keys
+		if !bytes.Equal(item.Key(), wallet.Key()) {
+			continue
+		}
+
+		if err := fn(wallet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrInvalidLimit is returned when a credit limit is negative or a maximum is not positive
+	ErrInvalidLimit = errors.New("wallet limit is out of range")
+
+	// ErrWalletTransactionLimit is returned when an amount is larger than the wallet's maximum transaction
+	ErrWalletTransactionLimit = errors.New("amount is above the maximum transaction of the wallet")
+
+	// ErrWalletBalanceLimit is returned when a credit would take a wallet above its own maximum balance
+	ErrWalletBalanceLimit = errors.New("balance would exceed the maximum balance of the wallet")
+)
+
+// SetWalletLimits replaces the limits of a wallet in one currency and returns the
+// wallet. The limits apply to later transactions; a balance already beyond them is
+// not changed. An empty currency selects the default currency.
+func (d *DB) SetWalletLimits(walletID, currency string, limits models.WalletLimits) (*models.Wallet, error) {
+	if limits.CreditLimit < 0 {
+		return nil, ErrInvalidLimit
+	}
+	for _, limit := range []*models.Amount{limits.MaxBalance, limits.MaxTransaction} {
+		if limit != nil && *limit <= 0 {
+			return nil, ErrInvalidLimit
+		}
+	}
+
+	if models.IsSystemAccount(walletID) {
+		return nil, ErrSystemAccount
+	}
+
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallet *models.Wallet
+	err = d.update(func(txn *badger.Txn) error {
+		definition, err := d.loadCurrency(txn, currency)
+		if err != nil {
+			return err
+		}
+
+		for _, limit := range []*models.Amount{&limits.CreditLimit, limits.MaxBalance, limits.MaxTransaction} {
+			if limit != nil && !definition.HasPrecision(*limit) {
+				return ErrCurrencyPrecision
+			}
+		}
+
+		if err := checkWalletOpen(txn, walletID); err != nil {
+			return err
+		}
+
+		wallet, _, err = loadWallet(txn, walletID, currency)
+		if err != nil {
+			return err
+		}
+
+		// Limits may be set before the wallet's first transaction
+		if err := createWallet(txn, walletID, time.Now()); err != nil {
+			return err
+		}
+
+		wallet.WalletLimits = limits
+		return saveWallet(txn, wallet)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// checkWalletAmount validates the size of a transaction against the limits of its wallet
+func checkWalletAmount(wallet *models.Wallet, amount models.Amount) error {
+	if wallet.MaxTransaction != nil && amount > *wallet.MaxTransaction {
+		return ErrWalletTransactionLimit
+	}
+	return nil
+}
+
+// clampCredit returns the largest part of amount that can be credited to the wallet
+// in one transaction without breaking the maximums of its currency or its own. If
+// nothing can be credited amount is returned unchanged, so that the checks reject it.
+func clampCredit(currency *models.Currency, wallet *models.Wallet, amount models.Amount) models.Amount {
+	clamped := amount
+	for _, limit := range []*models.Amount{currency.MaxTransaction, wallet.MaxTransaction} {
+		if limit != nil && clamped > *limit {
+			clamped = *limit
+		}
+	}
+	for _, limit := range []*models.Amount{currency.MaxBalance, wallet.MaxBalance} {
+		if limit != nil && wallet.Balance > *limit-clamped {
+			clamped = *limit - wallet.Balance
+		}
+	}
+
+	if clamped <= 0 {
+		return amount
+	}
+	return clamped
+}
@@ -0,0 +1,327 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// ErrInvalidExpiry is returned when an add is given an expiry that is not in the future
+var ErrInvalidExpiry = errors.New("expiry must be in the future")
+
+// GetLots retrieves the balance of a wallet in one currency together with its active
+// lots in expiry order. The part of the balance not in a lot does not expire. An empty
+// currency selects the default currency.
+func (d *DB) GetLots(walletID, currency string) (*models.Wallet, []*models.Lot, error) {
+	currency, err := d.currency(currency)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var wallet *models.Wallet
+	var lots []*models.Lot
+
+	err = d.db.View(func(txn *badger.Txn) error {
+		wallet, err = loadBalance(txn, walletID, currency)
+		if err != nil {
+			return err
+		}
+
+		lots, err = activeLots(txn, walletID, currency, 0)
+		return err
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wallet, lots, nil
+}
+
+// ExpireLots expires the lots of every wallet that expired by now, in expiry order. What
+// remains of each lot is paid into the expired sink by an expiry transaction. It returns
+// how many lots were expired.
+func (d *DB) ExpireLots(now time.Time) (int, error) {
+	prefix := models.LotExpiryPrefix()
+	end := expiryBoundary(prefix, now)
+
+	var ids []string
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			if bytes.Compare(key, end) >= 0 {
+				break
+			}
+			ids = append(ids, models.LotIDFromIndexKey(key))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Expire each lot in its own transaction so that a large backlog never exceeds a transaction's size
+	expired := 0
+	for _, id := range ids {
+		var active bool
+		err := d.update(func(txn *badger.Txn) error {
+			lot, err := loadLot(txn, id)
+			if err != nil {
+				return err
+			}
+
+			// The lot may have been spent since it was listed
+			active = lot.Status == models.LotStatusActive
+			if !active {
+				return nil
+			}
+			return d.expireLot(txn, lot, time.Now())
+		})
+
+		if err != nil {
+			return expired, err
+		}
+		if active {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// ExpireLots expires the lots that expired by now in every open environment
+func (m *DBManager) ExpireLots(now time.Time) error {
+	var lastErr error
+	for environment, db := range m.openDatabases() {
+		expired, err := db.ExpireLots(now)
+		if expired > 0 {
+			log.Printf("Expired %d lots in environment %s", expired, environment)
+		}
+		if err != nil {
+			log.Printf("Failed to expire lots in environment %s: %v", environment, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// RunLotExpiry expires lots in every open environment every LotExpiryInterval until ctx is done
+func (m *DBManager) RunLotExpiry(ctx context.Context) {
+	ticker := time.NewTicker(m.config.LotExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.ExpireLots(now)
+		}
+	}
+}
+
+// checkExpiry validates the expiry of a write: only adds may expire, and not in the past
+func checkExpiry(operation string, expiresAt *time.Time, now time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if operation != operationAdd {
+		return errors.New("only adds can expire")
+	}
+	if !expiresAt.After(now) {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+// newLot builds the lot created by an expiring credit, linked to its transaction.
+// A wallet below zero first repays what it owes, so only the rest of the credit
+// expires; if nothing is left no lot is created.
+func (d *DB) newLot(wallet *models.Wallet, tx *models.Transaction, expiresAt time.Time) *models.Lot {
+	amount := tx.Amount
+	if wallet.Balance < 0 {
+		amount += wallet.Balance
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	lot := &models.Lot{
+		ID:            d.ids.New(),
+		WalletID:      wallet.WalletID,
+		Currency:      wallet.Currency,
+		Amount:        amount,
+		Remaining:     amount,
+		Status:        models.LotStatusActive,
+		TransactionID: tx.ID,
+		CreatedAt:     tx.Timestamp,
+		ExpiresAt:     expiresAt,
+	}
+	tx.LotID = lot.ID
+
+	return lot
+}
+
+// expireLot pays what remains of a lot into the expired sink and closes it. Expiry is
+// not a write of the wallet's owner, so like a reversal it also debits frozen wallets.
+// A closed wallet refuses every write, and with all of its balances at zero it holds
+// nothing to expire, so its lots are closed without an expiry transaction.
+func (d *DB) expireLot(txn *badger.Txn, lot *models.Lot, now time.Time) error {
+	err := checkWalletOpen(txn, lot.WalletID)
+	if err == ErrWalletClosed {
+		return closeLot(txn, lot, models.LotStatusExpired)
+	}
+	if err != nil {
+		return err
+	}
+
+	wallet, _, err := loadWallet(txn, lot.WalletID, lot.Currency)
+	if err != nil {
+		return err
+	}
+
+	sink, err := loadAccount(txn, models.SinkAccount(models.ExpiredSystemAccount), lot.Currency, lot.WalletID)
+	if err != nil {
+		return err
+	}
+
+	tx, entry := d.newEntries(lot.WalletID, sink.WalletID, lot.Currency, -lot.Remaining, "Expiry of lot "+lot.ID, nil, now)
+	tx.LotID = lot.ID
+	entry.LotID = lot.ID
+
+	if err := d.postEntries(txn, wallet, tx, sink, entry); err != nil {
+		return err
+	}
+
+	lot.ExpiryTransactionID = tx.ID
+	return closeLot(txn, lot, models.LotStatusExpired)
+}
+
+// consumeLots spends amount from a wallet's active lots in one currency, soonest
+// expiring first. Whatever the lots do not cover comes from the rest of the balance.
+func consumeLots(txn *badger.Txn, walletID, currency string, amount models.Amount) error {
+	lots, err := activeLots(txn, walletID, currency, amount)
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range lots {
+		spent := lot.Remaining
+		if spent > amount {
+			spent = amount
+		}
+		amount -= spent
+		lot.Remaining -= spent
+
+		if lot.Remaining > 0 {
+			if err := saveLot(txn, lot); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := closeLot(txn, lot, models.LotStatusConsumed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// activeLots reads the active lots of a wallet in one currency in expiry order. A
+// positive amount stops reading once the lots read cover it.
+func activeLots(txn *badger.Txn, walletID, currency string, amount models.Amount) ([]*models.Lot, error) {
+	lots := []*models.Lot{}
+	prefix := models.LotIndexPrefix(walletID, currency)
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = prefix
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var covered models.Amount
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		if amount > 0 && covered >= amount {
+			break
+		}
+
+		lot, err := loadLot(txn, models.LotIDFromIndexKey(it.Item().Key()))
+		if err != nil {
+			return nil, err
+		}
+
+		lots = append(lots, lot)
+		covered += lot.Remaining
+	}
+
+	return lots, nil
+}
+
+// loadLot reads a lot by ID inside a transaction
+func loadLot(txn *badger.Txn, lotID string) (*models.Lot, error) {
+	item, err := txn.Get(models.LotKey(lotID))
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lot := &models.Lot{}
+	if err := item.Value(lot.FromJSON); err != nil {
+		return nil, err
+	}
+
+	return lot, nil
+}
+
+// closeLot sets the final status of a lot, removes it from both expiry indexes and saves it
+func closeLot(txn *badger.Txn, lot *models.Lot, status string) error {
+	lot.Status = status
+
+	if err := txn.Delete(lot.IndexKey()); err != nil {
+		return err
+	}
+	if err := txn.Delete(lot.ExpiryKey()); err != nil {
+		return err
+	}
+	return saveLot(txn, lot)
+}
+
+// saveLot writes a lot, and indexes it under its wallet and for expiry while it is active
+func saveLot(txn *badger.Txn, lot *models.Lot) error {
+	data, err := lot.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Set(lot.Key(), data); err != nil {
+		return err
+	}
+
+	if lot.Status != models.LotStatusActive {
+		return nil
+	}
+
+	if err := txn.Set(lot.IndexKey(), nil); err != nil {
+		return err
+	}
+	return txn.Set(lot.ExpiryKey(), nil)
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestExpireLotsByWalletStatus(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	lots := make(map[string]string)
+	for _, walletID := range []string{"active", "debit_frozen", "frozen", "closed"} {
+		result, err := d.AddCurrency(walletID, "", models.MustParseAmount("10"), "Event tokens", nil, WithExpiry(time.Now().Add(time.Hour)))
+		require.NoError(t, err)
+		lots[walletID] = result.Transaction.LotID
+	}
+
+	for _, status := range []string{models.WalletStatusDebitFrozen, models.WalletStatusFrozen} {
+		_, err := d.SetWalletStatus(status, status, "Investigation", "support:alice")
+		require.NoError(t, err)
+	}
+
+	// Wallets cannot be closed with a balance, so close this one behind the checks
+	err = d.db.Update(func(txn *badger.Txn) error {
+		status := &models.WalletStatus{WalletID: "closed", Status: models.WalletStatusClosed}
+		data, err := status.ToJSON()
+		if err != nil {
+			return err
+		}
+		return txn.Set(status.Key(), data)
+	})
+	require.NoError(t, err)
+
+	expired, err := d.ExpireLots(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 4, expired)
+
+	loadLotByID := func(id string) *models.Lot {
+		var lot *models.Lot
+		err := d.db.View(func(txn *badger.Txn) error {
+			var err error
+			lot, err = loadLot(txn, id)
+			return err
+		})
+		require.NoError(t, err)
+		return lot
+	}
+
+	// Expiry is not a write of the owner, so it also debits frozen wallets
+	for _, walletID := range []string{"active", "debit_frozen", "frozen"} {
+		lot := loadLotByID(lots[walletID])
+		assert.Equal(t, models.LotStatusExpired, lot.Status, walletID)
+		assert.NotEmpty(t, lot.ExpiryTransactionID, walletID)
+
+		balance, err := d.GetWalletBalance(walletID, "")
+		require.NoError(t, err)
+		assert.Equal(t, models.Amount(0), balance, walletID)
+	}
+
+	// A closed wallet is never written to: its lot is closed without an expiry transaction
+	lot := loadLotByID(lots["closed"])
+	assert.Equal(t, models.LotStatusExpired, lot.Status)
+	assert.Empty(t, lot.ExpiryTransactionID)
+
+	balance, err := d.GetWalletBalance("closed", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("10"), balance)
+
+	sink, err := d.GetWalletBalance(models.SinkAccount(models.ExpiredSystemAccount), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("30"), sink)
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// ErrInvalidLabel is returned when a wallet is given an empty label
+var ErrInvalidLabel = errors.New("labels must not be empty")
+
+// WalletMetadataPatch changes part of a wallet's metadata. Nil fields are left
+// unchanged; attributes are merged into the existing ones and a nil value removes
+// the attribute.
+type WalletMetadataPatch struct {
+	OwnerID    *string
+	Labels     *[]string
+	Attributes map[string]interface{}
+}
+
+// GetWalletMetadata retrieves the metadata of a wallet; wallets without any have only their ID set
+func (d *DB) GetWalletMetadata(walletID string) (*models.WalletMetadata, error) {
+	var metadata *models.WalletMetadata
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		metadata, _, err = loadWalletMetadata(txn, walletID)
+		return err
+	})
+
+	return metadata, err
+}
+
+// SetWalletMetadata replaces the owner, labels and attributes of a wallet,
+// creating the wallet's record if it has none yet
+func (d *DB) SetWalletMetadata(walletID, ownerID string, labels []string, attributes map[string]interface{}) (*models.WalletMetadata, error) {
+	return d.updateWalletMetadata(walletID, func(metadata *models.WalletMetadata) {
+		metadata.OwnerID = ownerID
+		metadata.Labels = labels
+		metadata.Attributes = attributes
+	})
+}
+
+// PatchWalletMetadata changes the fields of a wallet's metadata that the patch sets,
+// creating the wallet's record if it has none yet
+func (d *DB) PatchWalletMetadata(walletID string, patch WalletMetadataPatch) (*models.WalletMetadata, error) {
+	return d.updateWalletMetadata(walletID, func(metadata *models.WalletMetadata) {
+		if patch.OwnerID != nil {
+			metadata.OwnerID = *patch.OwnerID
+		}
+		if patch.Labels != nil {
+			metadata.Labels = *patch.Labels
+		}
+		for key, value := range patch.Attributes {
+			if value == nil {
+				delete(metadata.Attributes, key)
+				continue
+			}
+			if metadata.Attributes == nil {
+				metadata.Attributes = map[string]interface{}{}
+			}
+			metadata.Attributes[key] = value
+		}
+	})
+}
+
+// updateWalletMetadata applies fn to the metadata of an open wallet and stores the result
+func (d *DB) updateWalletMetadata(walletID string, fn func(metadata *models.WalletMetadata)) (*models.WalletMetadata, error) {
+	if models.IsSystemAccount(walletID) {
+		return nil, ErrSystemAccount
+	}
+
+	var metadata *models.WalletMetadata
+
+	err := d.update(func(txn *badger.Txn) error {
+		if err := checkWalletOpen(txn, walletID); err != nil {
+			return err
+		}
+
+		var err error
+		metadata, _, err = loadWalletMetadata(txn, walletID)
+		if err != nil {
+			return err
+		}
+
+		fn(metadata)
+
+		metadata.Labels, err = normalizeLabels(metadata.Labels)
+		if err != nil {
+			return err
+		}
+		if len(metadata.Attributes) == 0 {
+			metadata.Attributes = nil
+		}
+
+		now := time.Now()
+		if metadata.CreatedAt == nil {
+			metadata.CreatedAt = &now
+		}
+		metadata.UpdatedAt = &now
+
+		return saveWalletMetadata(txn, metadata)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// touchWallet records a transaction posted to a wallet at the given time,
+// creating the wallet's record on its first transaction
+func touchWallet(txn *badger.Txn, walletID string, at time.Time) error {
+	metadata, _, err := loadWalletMetadata(txn, walletID)
+	if err != nil {
+		return err
+	}
+
+	if metadata.CreatedAt == nil {
+		metadata.CreatedAt = &at
+	}
+	if metadata.LastActivityAt == nil || at.After(*metadata.LastActivityAt) {
+		metadata.LastActivityAt = &at
+	}
+
+	return saveWalletMetadata(txn, metadata)
+}
+
+// createWallet records that a wallet was created at the given time unless it already exists
+func createWallet(txn *badger.Txn, walletID string, at time.Time) error {
+	metadata, exists, err := loadWalletMetadata(txn, walletID)
+	if err != nil || exists {
+		return err
+	}
+
+	metadata.CreatedAt = &at
+	return saveWalletMetadata(txn, metadata)
+}
+
+// normalizeLabels drops repeated labels, keeping the first occurrence of each, and refuses empty ones
+func normalizeLabels(labels []string) ([]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(labels))
+	normalized := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label == "" {
+			return nil, ErrInvalidLabel
+		}
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+
+	return normalized, nil
+}
+
+// loadWalletMetadata reads the metadata of a wallet inside a transaction. A wallet
+// without metadata is returned with only its ID set and exists set to false.
+func loadWalletMetadata(txn *badger.Txn, walletID string) (metadata *models.WalletMetadata, exists bool, err error) {
+	metadata = &models.WalletMetadata{WalletID: walletID}
+
+	item, err := txn.Get(metadata.Key())
+	if err == badger.ErrKeyNotFound {
+		return metadata, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := item.Value(metadata.FromJSON); err != nil {
+		return nil, false, err
+	}
+
+	return metadata, true, nil
+}
+
+// saveWalletMetadata writes the metadata of a wallet inside a transaction
+func saveWalletMetadata(txn *badger.Txn, metadata *models.WalletMetadata) error {
+	data, err := metadata.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return txn.Set(metadata.Key(), data)
+}
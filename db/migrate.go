@@ -0,0 +1,946 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// schemaVersionKey stores the version of the on-disk data layout
+var schemaVersionKey = []byte("meta:schema_version")
+
+// decimalsKey stores the number of decimal places the database's amounts were written with
+var decimalsKey = []byte("meta:decimals")
+
+// ErrDecimalsMismatch is returned when a database is opened with a different number of
+// decimal places than its amounts were written with
+var ErrDecimalsMismatch = errors.New("decimal places do not match the database")
+
+// migration upgrades the data layout of a database by one version
+type migration struct {
+	version     int
+	description string
+	apply       func(d *DB) error
+}
+
+// migrations lists every data layout change in the order it must be applied
+var migrations = []migration{
+	{1, "store amounts as fixed-point decimals", migrateFixedPointAmounts},
+	{2, "index wallet transactions by time and amount", migrateTransactionIndex},
+	{3, "record running balances on transactions", migrateRunningBalances},
+	{4, "write periodic balance checkpoints", migrateBalanceCheckpoints},
+	{5, "store balances per currency", migrateCurrencies},
+	{6, "balance existing wallets against an opening balance faucet", migrateOpeningBalances},
+	{7, "version wallets", migrateWalletVersions},
+	{8, "record when wallets were created and last active", migrateWalletMetadata},
+	{9, "record the time of each wallet's latest transaction", migrateLastTransactionTimes},
+	{10, "record wallets that have a balance or a status but no transactions", migrateWalletRecords},
+	{11, "keep every pause of a grant", migrateGrantPauses},
+}
+
+// Keys used before wallets held several currencies. Migrations up to version 4
+// run against this layout, which migrateCurrencies then moves to the current one.
+
+func legacyWalletKey(walletID string) []byte {
+	return []byte("wallet:" + walletID)
+}
+
+func legacyTimeIndexPrefix(walletID string) []byte {
+	return []byte("wallet:" + walletID + ":tx_time:")
+}
+
+func legacyTimeIndexKey(tx *models.Transaction) []byte {
+	return append(legacyTimeIndexPrefix(tx.WalletID), models.SortableTime(tx.Timestamp)+":"+tx.ID...)
+}
+
+func legacyAmountIndexKey(tx *models.Transaction) []byte {
+	return []byte("wallet:" + tx.WalletID + ":tx_amount:" + models.SortableAmount(tx.Amount) + ":" + models.SortableTime(tx.Timestamp) + ":" + tx.ID)
+}
+
+func legacyCheckpointPrefix(walletID string) []byte {
+	return []byte("wallet:" + walletID + ":checkpoint:")
+}
+
+func legacyCheckpointKey(c *models.BalanceCheckpoint) []byte {
+	return append(legacyCheckpointPrefix(c.WalletID), models.SortableTime(c.Timestamp)+":"+c.TransactionID...)
+}
+
+// migrate applies all migrations newer than the stored schema version
+func (d *DB) migrate() error {
+	current, err := d.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	// A new database starts out in the current layout
+	if current == 0 {
+		empty, err := d.isEmpty()
+		if err != nil {
+			return err
+		}
+		if empty {
+			return d.setSchemaVersion(migrations[len(migrations)-1].version)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := m.apply(d); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+
+		if err := d.setSchemaVersion(m.version); err != nil {
+			return err
+		}
+
+		log.Printf("Environment %s migrated to schema version %d: %s", d.environment, m.version, m.description)
+	}
+
+	return nil
+}
+
+// checkDecimals records the configured number of decimal places on the first open and
+// refuses to open the database with any other number afterwards. Amounts are stored as
+// decimal strings but indexed by their minor units, so both only agree at one scale.
+func (d *DB) checkDecimals() error {
+	return d.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(decimalsKey)
+		if err == badger.ErrKeyNotFound {
+			return txn.Set(decimalsKey, []byte(strconv.Itoa(models.Decimals())))
+		}
+		if err != nil {
+			return err
+		}
+
+		var stored int
+		err = item.Value(func(val []byte) error {
+			stored, err = strconv.Atoi(string(val))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if stored != models.Decimals() {
+			return fmt.Errorf("%w: environment %s stores amounts with %d decimal places, but %d are configured", ErrDecimalsMismatch, d.environment, stored, models.Decimals())
+		}
+		return nil
+	})
+}
+
+// isEmpty reports whether the database holds nothing but meta keys
+func (d *DB) isEmpty() (bool, error) {
+	empty := true
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if !bytes.HasPrefix(it.Item().Key(), []byte("meta:")) {
+				empty = false
+				break
+			}
+		}
+		return nil
+	})
+
+	return empty, err
+}
+
+// schemaVersion returns the stored schema version, or 0 for a database that predates versioning
+func (d *DB) schemaVersion() (int, error) {
+	version := 0
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(schemaVersionKey)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			version, err = strconv.Atoi(string(val))
+			return err
+		})
+	})
+
+	return version, err
+}
+
+// setSchemaVersion records the schema version the database has been migrated to
+func (d *DB) setSchemaVersion(version int) error {
+	return d.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(schemaVersionKey, []byte(strconv.Itoa(version)))
+	})
+}
+
+// forEachRecord passes every record under prefix to fn together with a write batch
+// that is flushed once the whole prefix has been visited
+func (d *DB) forEachRecord(prefix []byte, fn func(batch *badger.WriteBatch, key, val []byte) error) error {
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			item := it.Item()
+			key := item.KeyCopy(nil)
+
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(batch, key, val); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return batch.Flush()
+}
+
+// rewriteValues passes every record under prefix to fn and stores the value it returns.
+// Records for which fn returns nil are left untouched.
+func (d *DB) rewriteValues(prefix []byte, fn func(key, val []byte) ([]byte, error)) error {
+	return d.forEachRecord(prefix, func(batch *badger.WriteBatch, key, val []byte) error {
+		updated, err := fn(key, val)
+		if err != nil || updated == nil {
+			return err
+		}
+		return batch.Set(key, updated)
+	})
+}
+
+// migrateFixedPointAmounts converts float64 balances and amounts into decimal strings
+func migrateFixedPointAmounts(d *DB) error {
+	convert := func(key, val []byte) ([]byte, error) {
+		if len(val) == 0 {
+			return nil, nil
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(val))
+		decoder.UseNumber()
+
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+
+		changed := false
+		for _, field := range []string{"balance", "amount"} {
+			number, ok := record[field].(json.Number)
+			if !ok {
+				continue
+			}
+
+			f, err := number.Float64()
+			if err != nil {
+				return nil, err
+			}
+
+			amount, err := models.AmountFromFloat(f)
+			if err != nil {
+				return nil, err
+			}
+
+			record[field] = amount.String()
+			changed = true
+		}
+
+		if !changed {
+			return nil, nil
+		}
+		return json.Marshal(record)
+	}
+
+	if err := d.rewriteValues([]byte("wallet:"), convert); err != nil {
+		return err
+	}
+	return d.rewriteValues([]byte("transaction:"), convert)
+}
+
+// migrateTransactionIndex replaces the unordered copies of transactions kept
+// under each wallet with time and amount index entries pointing at the transaction
+func migrateTransactionIndex(d *DB) error {
+	err := d.forEachRecord([]byte("transaction:"), func(batch *badger.WriteBatch, key, val []byte) error {
+		var tx models.Transaction
+		if err := tx.FromJSON(val); err != nil {
+			return err
+		}
+
+		if err := batch.Set(legacyTimeIndexKey(&tx), nil); err != nil {
+			return err
+		}
+		return batch.Set(legacyAmountIndexKey(&tx), nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.forEachRecord([]byte("wallet:"), func(batch *badger.WriteBatch, key, val []byte) error {
+		if !bytes.Contains(key, []byte(":transaction:")) {
+			return nil
+		}
+
+		var tx models.Transaction
+		if err := tx.FromJSON(val); err != nil || !bytes.Equal(key, tx.LegacyWalletKey()) {
+			return nil // not a legacy copy
+		}
+
+		// Keep the transaction even if only its copy under the wallet survived
+		if err := batch.Set(tx.Key(), val); err != nil {
+			return err
+		}
+		if err := batch.Set(legacyTimeIndexKey(&tx), nil); err != nil {
+			return err
+		}
+		if err := batch.Set(legacyAmountIndexKey(&tx), nil); err != nil {
+			return err
+		}
+		return batch.Delete(key)
+	})
+}
+
+// loadLegacyWallets returns every wallet record stored in the single-currency layout
+func (d *DB) loadLegacyWallets() ([]*models.Wallet, error) {
+	var wallets []*models.Wallet
+
+	err := d.forEachRecord([]byte("wallet:"), func(batch *badger.WriteBatch, key, val []byte) error {
+		// Index entries under the wallet prefix carry no value
+		if len(val) == 0 {
+			return nil
+		}
+
+		wallet := &models.Wallet{}
+		if err := wallet.FromJSON(val); err != nil {
+			return err
+		}
+
+		if bytes.Equal(key, legacyWalletKey(wallet.WalletID)) {
+			wallets = append(wallets, wallet)
+		}
+		return nil
+	})
+
+	return wallets, err
+}
+
+// forEachIndexedTransaction passes the transactions of a time index to fn in time order
+func (d *DB) forEachIndexedTransaction(prefix []byte, fn func(tx *models.Transaction) error) error {
+	return d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			tx, err := loadTransaction(txn, models.TransactionIDFromIndexKey(it.Item().Key()))
+			if err != nil {
+				return err
+			}
+
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateRunningBalances backfills balance_before and balance_after by replaying
+// each wallet's history. The replay is anchored on the current balance, so the
+// last transaction always ends at the balance the wallet holds today.
+func migrateRunningBalances(d *DB) error {
+	wallets, err := d.loadLegacyWallets()
+	if err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		var total models.Amount
+		err := d.forEachIndexedTransaction(legacyTimeIndexPrefix(wallet.WalletID), func(tx *models.Transaction) error {
+			total += tx.Amount
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		batch := d.db.NewWriteBatch()
+		running := wallet.Balance - total
+
+		err = d.forEachIndexedTransaction(legacyTimeIndexPrefix(wallet.WalletID), func(tx *models.Transaction) error {
+			tx.BalanceBefore = running
+			running += tx.Amount
+			tx.BalanceAfter = running
+
+			data, err := tx.ToJSON()
+			if err != nil {
+				return err
+			}
+			return batch.Set(tx.Key(), data)
+		})
+		if err != nil {
+			batch.Cancel()
+			return err
+		}
+
+		if err := batch.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateBalanceCheckpoints counts each wallet's transactions and writes the
+// balance checkpoints that new transactions would have produced
+func migrateBalanceCheckpoints(d *DB) error {
+	wallets, err := d.loadLegacyWallets()
+	if err != nil {
+		return err
+	}
+
+	interval := int64(d.config.BalanceCheckpointInterval)
+
+	for _, wallet := range wallets {
+		batch := d.db.NewWriteBatch()
+
+		wallet.TransactionCount = 0
+		err := d.forEachIndexedTransaction(legacyTimeIndexPrefix(wallet.WalletID), func(tx *models.Transaction) error {
+			wallet.TransactionCount++
+			if wallet.TransactionCount%interval != 0 {
+				return nil
+			}
+			checkpoint := newCheckpoint(tx, wallet.TransactionCount)
+			data, err := checkpoint.ToJSON()
+			if err != nil {
+				return err
+			}
+			return batch.Set(legacyCheckpointKey(checkpoint), data)
+		})
+		if err != nil {
+			batch.Cancel()
+			return err
+		}
+
+		data, err := wallet.ToJSON()
+		if err != nil {
+			batch.Cancel()
+			return err
+		}
+
+		if err := batch.Set(legacyWalletKey(wallet.WalletID), data); err != nil {
+			batch.Cancel()
+			return err
+		}
+
+		if err := batch.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateCurrencies assigns every balance, transaction and checkpoint stored in the
+// single-currency layout to the default currency and moves them to currency-scoped keys
+func migrateCurrencies(d *DB) error {
+	currency := d.config.DefaultCurrency
+
+	wallets, err := d.loadLegacyWallets()
+	if err != nil {
+		return err
+	}
+
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, wallet := range wallets {
+		if err := batch.Delete(legacyWalletKey(wallet.WalletID)); err != nil {
+			return err
+		}
+
+		wallet.Currency = currency
+		data, err := wallet.ToJSON()
+		if err != nil {
+			return err
+		}
+
+		if err := batch.Set(wallet.Key(), data); err != nil {
+			return err
+		}
+	}
+
+	if err := batch.Flush(); err != nil {
+		return err
+	}
+
+	err = d.forEachRecord([]byte("transaction:"), func(batch *badger.WriteBatch, key, val []byte) error {
+		var tx models.Transaction
+		if err := tx.FromJSON(val); err != nil {
+			return err
+		}
+		if tx.Currency != "" {
+			return nil
+		}
+
+		for _, legacy := range [][]byte{legacyTimeIndexKey(&tx), legacyAmountIndexKey(&tx)} {
+			if err := batch.Delete(legacy); err != nil {
+				return err
+			}
+		}
+
+		tx.Currency = currency
+		data, err := tx.ToJSON()
+		if err != nil {
+			return err
+		}
+
+		if err := batch.Set(key, data); err != nil {
+			return err
+		}
+		if err := batch.Set(tx.TimeIndexKey(), nil); err != nil {
+			return err
+		}
+		return batch.Set(tx.AmountIndexKey(), nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		err := d.forEachRecord(legacyCheckpointPrefix(wallet.WalletID), func(batch *badger.WriteBatch, key, val []byte) error {
+			checkpoint := &models.BalanceCheckpoint{}
+			if err := checkpoint.FromJSON(val); err != nil {
+				return err
+			}
+			if checkpoint.Currency != "" {
+				return nil
+			}
+
+			if err := batch.Delete(key); err != nil {
+				return err
+			}
+
+			checkpoint.Currency = currency
+			return saveCheckpoint(batch.Set, checkpoint)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openingBalanceAccount is the faucet that balances currency held before double-entry bookkeeping
+var openingBalanceAccount = models.FaucetAccount("opening_balance")
+
+const openingBalanceDescription = "Opening balance from before double-entry bookkeeping"
+
+// openingBalanceMarkerKey records that the opening entry of a wallet in a currency has
+// been written, so that rerunning an interrupted migration does not write it twice
+func openingBalanceMarkerKey(walletID, currency string) []byte {
+	return []byte("meta:opening_balance:" + currency + ":" + walletID)
+}
+
+// migrateOpeningBalances balances the currency wallets held before every write had
+// a counterparty. Wallets whose history does not explain their balance get an
+// opening entry before their first transaction, and the opening balance faucet
+// gets one entry per currency for everything the wallets hold, so that both the
+// balances and the entries of the ledger sum to zero. Each entry is written in one
+// transaction with a marker, so the migration can be rerun after an interruption.
+func migrateOpeningBalances(d *DB) error {
+	var wallets []*models.Wallet
+	err := d.db.View(func(txn *badger.Txn) error {
+		return forEachWallet(txn, func(wallet *models.Wallet) error {
+			// The faucet itself exists only if an earlier run was interrupted
+			if !models.IsSystemAccount(wallet.WalletID) {
+				wallets = append(wallets, wallet)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	totals := make(map[string]models.Amount)
+
+	for _, wallet := range wallets {
+		totals[wallet.Currency] += wallet.Balance
+
+		var explained models.Amount
+		var first *models.Transaction
+		err := d.forEachIndexedTransaction(models.TimeIndexPrefix(wallet.WalletID, wallet.Currency), func(tx *models.Transaction) error {
+			if first == nil {
+				first = tx
+			}
+			explained += tx.Amount
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		gap := wallet.Balance - explained
+		if gap == 0 {
+			continue
+		}
+
+		opening := &models.Transaction{
+			ID:                   d.ids.New(),
+			WalletID:             wallet.WalletID,
+			Currency:             wallet.Currency,
+			Amount:               gap,
+			BalanceAfter:         gap,
+			Description:          openingBalanceDescription,
+			CounterpartyWalletID: openingBalanceAccount,
+			Timestamp:            now,
+		}
+		if first != nil {
+			opening.Timestamp = first.Timestamp.Add(-time.Nanosecond)
+		}
+
+		wallet.TransactionCount++
+		if err := d.writeOpeningEntry(opening, wallet); err != nil {
+			return err
+		}
+	}
+
+	for currency, total := range totals {
+		if total == 0 {
+			continue
+		}
+
+		tx := &models.Transaction{
+			ID:           d.ids.New(),
+			WalletID:     openingBalanceAccount,
+			Currency:     currency,
+			Amount:       -total,
+			BalanceAfter: -total,
+			Description:  openingBalanceDescription,
+			Timestamp:    now,
+		}
+
+		faucet := &models.Wallet{WalletID: openingBalanceAccount, Currency: currency, Balance: -total, TransactionCount: 1}
+		if err := d.writeOpeningEntry(tx, faucet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeOpeningEntry stores an opening entry and the wallet it was posted to together
+// with their marker, unless the marker shows that an earlier run already did
+func (d *DB) writeOpeningEntry(tx *models.Transaction, wallet *models.Wallet) error {
+	marker := openingBalanceMarkerKey(wallet.WalletID, wallet.Currency)
+
+	return d.db.Update(func(txn *badger.Txn) error {
+		_, err := txn.Get(marker)
+		if err == nil {
+			return nil
+		}
+		if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		if err := saveTransaction(txn.Set, tx); err != nil {
+			return err
+		}
+		if err := saveWallet(txn, wallet); err != nil {
+			return err
+		}
+		return txn.Set(marker, nil)
+	})
+}
+
+// migrateWalletVersions starts the version of every wallet at its transaction count,
+// since every transaction so far changed its balance once
+func migrateWalletVersions(d *DB) error {
+	var wallets []*models.Wallet
+	err := d.db.View(func(txn *badger.Txn) error {
+		return forEachWallet(txn, func(wallet *models.Wallet) error {
+			wallets = append(wallets, wallet)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, wallet := range wallets {
+		wallet.Version = wallet.TransactionCount
+
+		data, err := wallet.ToJSON()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(wallet.Key(), data); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+// migrateWalletMetadata creates the metadata of existing wallets from their
+// history: the first transaction in any currency is when the wallet was created
+// and the last one that did not expire a lot is its last activity
+func migrateWalletMetadata(d *DB) error {
+	var wallets []*models.Wallet
+	err := d.db.View(func(txn *badger.Txn) error {
+		return forEachWallet(txn, func(wallet *models.Wallet) error {
+			if !models.IsSystemAccount(wallet.WalletID) {
+				wallets = append(wallets, wallet)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]*models.WalletMetadata{}
+	for _, wallet := range wallets {
+		m, ok := metadata[wallet.WalletID]
+		if !ok {
+			m = &models.WalletMetadata{WalletID: wallet.WalletID}
+			metadata[wallet.WalletID] = m
+		}
+
+		err := d.forEachIndexedTransaction(models.TimeIndexPrefix(wallet.WalletID, wallet.Currency), func(tx *models.Transaction) error {
+			at := tx.Timestamp
+			if m.CreatedAt == nil || at.Before(*m.CreatedAt) {
+				m.CreatedAt = &at
+			}
+			expiry := tx.Amount < 0 && tx.LotID != ""
+			if !expiry && (m.LastActivityAt == nil || at.After(*m.LastActivityAt)) {
+				m.LastActivityAt = &at
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, m := range metadata {
+		if m.CreatedAt == nil {
+			continue
+		}
+
+		data, err := m.ToJSON()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(m.Key(), data); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+// migrateLastTransactionTimes records on every wallet the time of its latest
+// transaction in each currency, which later transactions are dated after
+func migrateLastTransactionTimes(d *DB) error {
+	var wallets []*models.Wallet
+	err := d.db.View(func(txn *badger.Txn) error {
+		return forEachWallet(txn, func(wallet *models.Wallet) error {
+			wallets = append(wallets, wallet)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, wallet := range wallets {
+		err := d.db.View(func(txn *badger.Txn) error {
+			prefix := models.TimeIndexPrefix(wallet.WalletID, wallet.Currency)
+
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			opts.Reverse = true
+			opts.Prefix = prefix
+
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			it.Seek(append(append([]byte{}, prefix...), 0xff))
+			if !it.Valid() {
+				return nil
+			}
+
+			tx, err := loadTransaction(txn, models.TransactionIDFromIndexKey(it.Item().Key()))
+			if err != nil {
+				return err
+			}
+			wallet.LastTransactionAt = &tx.Timestamp
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if wallet.LastTransactionAt == nil {
+			continue
+		}
+
+		data, err := wallet.ToJSON()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(wallet.Key(), data); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+// migrateWalletRecords creates the metadata that wallets without any transaction
+// lack, e.g. those that only had limits or a status set, so that every wallet is
+// listed. A wallet whose status was set was created with its first status change.
+func migrateWalletRecords(d *DB) error {
+	missing := map[string]*models.WalletMetadata{}
+	err := d.db.View(func(txn *badger.Txn) error {
+		add := func(walletID string) error {
+			if models.IsSystemAccount(walletID) || missing[walletID] != nil {
+				return nil
+			}
+
+			metadata, exists, err := loadWalletMetadata(txn, walletID)
+			if err != nil || exists {
+				return err
+			}
+			missing[walletID] = metadata
+			return nil
+		}
+
+		err := forEachWallet(txn, func(wallet *models.Wallet) error {
+			return add(wallet.WalletID)
+		})
+		if err != nil {
+			return err
+		}
+
+		prefix := models.WalletStatusKey("")
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			status := &models.WalletStatus{}
+			if err := it.Item().Value(status.FromJSON); err != nil {
+				return err
+			}
+			if err := add(status.WalletID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := d.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for walletID, metadata := range missing {
+		changes, err := d.GetWalletStatusHistory(walletID)
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			metadata.CreatedAt = &changes[0].ChangedAt
+		}
+
+		data, err := metadata.ToJSON()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(metadata.Key(), data); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+// migrateGrantPauses moves the last pause of each grant, which was all a grant
+// recorded before, into its list of pauses
+func migrateGrantPauses(d *DB) error {
+	return d.forEachRecord(models.GrantKey(""), func(batch *badger.WriteBatch, key, val []byte) error {
+		var legacy struct {
+			PausedAt  *time.Time `json:"paused_at"`
+			ResumedAt *time.Time `json:"resumed_at"`
+		}
+		if err := json.Unmarshal(val, &legacy); err != nil {
+			return err
+		}
+		if legacy.PausedAt == nil {
+			return nil
+		}
+
+		grant := &models.Grant{}
+		if err := grant.FromJSON(val); err != nil {
+			return err
+		}
+		grant.Pauses = []models.GrantPause{{PausedAt: *legacy.PausedAt, ResumedAt: legacy.ResumedAt}}
+
+		data, err := grant.ToJSON()
+		if err != nil {
+			return err
+		}
+		return batch.Set(key, data)
+	})
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestMigrateFixedPointAmounts(t *testing.T) {
+	dir := t.TempDir()
+
+	// Write records in the legacy float64 layout, bypassing migrations
+	options := badger.DefaultOptions(dir)
+	options.Logger = nil
+	raw, err := badger.Open(options)
+	require.NoError(t, err)
+
+	legacy := map[string]string{
+		"wallet:w1":                            `{"wallet_id":"w1","balance":0.30000000000000004}`,
+		"transaction:20230101120000":           `{"id":"20230101120000","wallet_id":"w1","amount":0.1,"description":"a","timestamp":"2023-01-01T12:00:00Z"}`,
+		"wallet:w1:transaction:20230101120000": `{"id":"20230101120000","wallet_id":"w1","amount":0.1,"description":"a","timestamp":"2023-01-01T12:00:00Z"}`,
+	}
+	err = raw.Update(func(txn *badger.Txn) error {
+		for k, v := range legacy {
+			if err := txn.Set([]byte(k), []byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	// Checkpoint every transaction so that migrated checkpoints are exercised too
+	config := DefaultConfig()
+	config.BalanceCheckpointInterval = 1
+
+	d, err := NewDB(dir, "test", config)
+	require.NoError(t, err)
+	defer d.Close()
+
+	wallet, err := d.GetWallet("w1", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("0.30"), wallet.Balance)
+	assert.Equal(t, config.DefaultCurrency, wallet.Currency)
+	assert.Equal(t, int64(2), wallet.TransactionCount)
+	assert.Equal(t, int64(2), wallet.Version)
+
+	page, err := d.GetTransactionsByWallet("w1", "", TransactionQuery{Limit: 10, SortBy: SortByTimestamp, SortOrder: SortDescending})
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 2)
+	assert.Equal(t, models.MustParseAmount("0.10"), page.Transactions[0].Amount)
+
+	// Running balances are replayed backwards from the current balance
+	assert.Equal(t, models.MustParseAmount("0.20"), page.Transactions[0].BalanceBefore)
+	assert.Equal(t, models.MustParseAmount("0.30"), page.Transactions[0].BalanceAfter)
+	assert.Equal(t, config.DefaultCurrency, page.Transactions[0].Currency)
+
+	// The part of the balance the history does not explain is an opening entry before it
+	opening := page.Transactions[1]
+	assert.Equal(t, models.MustParseAmount("0.20"), opening.Amount)
+	assert.Equal(t, models.Amount(0), opening.BalanceBefore)
+	assert.Equal(t, openingBalanceAccount, opening.CounterpartyWalletID)
+
+	// Balances and checkpoints moved to the default currency
+	balances, err := d.GetWalletBalances("w1")
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	assert.Equal(t, config.DefaultCurrency, balances[0].Currency)
+
+	historical, err := d.GetWalletBalanceAt("w1", "", time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("0.30"), historical.Balance)
+
+	// Nothing is left under the single-currency keys
+	err = d.db.View(func(txn *badger.Txn) error {
+		for _, key := range [][]byte{legacyWalletKey("w1"), legacyTimeIndexKey(page.Transactions[0]), legacyAmountIndexKey(page.Transactions[0])} {
+			_, err := txn.Get(key)
+			assert.Equal(t, badger.ErrKeyNotFound, err, "key %q", key)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Later transactions are dated after the latest one
+	require.NotNil(t, wallet.LastTransactionAt)
+	assert.True(t, wallet.LastTransactionAt.Equal(page.Transactions[0].Timestamp))
+
+	// The wallet was created with its opening entry and last active with its last transaction
+	metadata, err := d.GetWalletMetadata("w1")
+	require.NoError(t, err)
+	require.NotNil(t, metadata.CreatedAt)
+	require.NotNil(t, metadata.LastActivityAt)
+	assert.True(t, metadata.CreatedAt.Equal(opening.Timestamp))
+	assert.True(t, metadata.LastActivityAt.Equal(page.Transactions[0].Timestamp))
+
+	// The opening balance faucet balances what the wallets already held
+	faucet, err := d.GetWallet(openingBalanceAccount, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("-0.30"), faucet.Balance)
+
+	trial, err := d.TrialBalance()
+	require.NoError(t, err)
+	require.Len(t, trial, 1)
+	assert.True(t, trial[0].Balanced)
+
+	version, err := d.schemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].version, version)
+}
+
+func TestDecimalsMustMatchDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	d, err := NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	_, err = d.AddCurrency("w1", "", models.MustParseAmount("1.25"), "Reward", nil)
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+
+	// Reopening with another scale would misread stored amounts and their index keys
+	require.NoError(t, models.SetDecimals(3))
+	defer models.SetDecimals(2)
+
+	_, err = NewDB(dir, "test", DefaultConfig())
+	assert.ErrorIs(t, err, ErrDecimalsMismatch)
+
+	// The scale the amounts were written with still opens the database
+	require.NoError(t, models.SetDecimals(2))
+	d, err = NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	wallet, err := d.GetWallet("w1", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("1.25"), wallet.Balance)
+}
+
+func TestMigrateOpeningBalancesRerun(t *testing.T) {
+	dir := t.TempDir()
+
+	setRaw := func(records map[string]string) {
+		options := badger.DefaultOptions(dir)
+		options.Logger = nil
+		raw, err := badger.Open(options)
+		require.NoError(t, err)
+
+		err = raw.Update(func(txn *badger.Txn) error {
+			for k, v := range records {
+				if err := txn.Set([]byte(k), []byte(v)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, raw.Close())
+	}
+
+	setRaw(map[string]string{"wallet:w1": `{"wallet_id":"w1","balance":0.5}`})
+
+	d, err := NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+
+	// Rerun the migration as if the process had stopped before recording its version
+	setRaw(map[string]string{string(schemaVersionKey): "5"})
+
+	d, err = NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	page, err := d.GetTransactionsByWallet("w1", "", TransactionQuery{Limit: 10, SortBy: SortByTimestamp, SortOrder: SortDescending})
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, openingBalanceAccount, page.Transactions[0].CounterpartyWalletID)
+
+	faucet, err := d.GetWallet(openingBalanceAccount, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("-0.50"), faucet.Balance)
+	assert.Equal(t, int64(1), faucet.TransactionCount)
+
+	trial, err := d.TrialBalance()
+	require.NoError(t, err)
+	require.Len(t, trial, 1)
+	assert.True(t, trial[0].Balanced)
+}
+
+func TestMigrateEmptyDatabase(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	// A new database starts at the latest version without running any migration
+	version, err := d.schemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].version, version)
+
+	trial, err := d.TrialBalance()
+	require.NoError(t, err)
+	assert.Empty(t, trial)
+}
+
+func TestMigrateWalletRecords(t *testing.T) {
+	dir := t.TempDir()
+
+	// A wallet with only a balance record and one with only a status, written before every wallet had metadata
+	options := badger.DefaultOptions(dir)
+	options.Logger = nil
+	raw, err := badger.Open(options)
+	require.NoError(t, err)
+
+	err = raw.Update(func(txn *badger.Txn) error {
+		for k, v := range map[string]string{
+			string(schemaVersionKey):                          "9",
+			"wallet:w1:balance:DEFAULT":                       `{"wallet_id":"w1","currency":"DEFAULT","balance":"0.00"}`,
+			"wallet_status:w2":                                `{"wallet_id":"w2","status":"frozen"}`,
+			"wallet_status_change:w2:01672574400000000000:c1": `{"id":"c1","wallet_id":"w2","from":"active","to":"frozen","changed_at":"2023-01-01T12:00:00Z"}`,
+		} {
+			if err := txn.Set([]byte(k), []byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	d, err := NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	page, err := d.ListWallets(WalletQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Wallets, 2)
+	assert.Equal(t, "w1", page.Wallets[0].Metadata.WalletID)
+	assert.Nil(t, page.Wallets[0].Metadata.CreatedAt)
+	assert.Equal(t, "w2", page.Wallets[1].Metadata.WalletID)
+	assert.Equal(t, models.WalletStatusFrozen, page.Wallets[1].Status.Status)
+	require.NotNil(t, page.Wallets[1].Metadata.CreatedAt)
+	assert.True(t, page.Wallets[1].Metadata.CreatedAt.Equal(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)))
+	for _, record := range page.Wallets {
+		assert.True(t, record.Exists)
+	}
+}
+
+func TestMigrateGrantPauses(t *testing.T) {
+	dir := t.TempDir()
+
+	options := badger.DefaultOptions(dir)
+	options.Logger = nil
+	raw, err := badger.Open(options)
+	require.NoError(t, err)
+
+	err = raw.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(schemaVersionKey, []byte("10")); err != nil {
+			return err
+		}
+		return txn.Set(models.GrantKey("g1"), []byte(`{"id":"g1","name":"Hourly bonus","currency":"DEFAULT","amount":"10.00","interval_seconds":3600,"catch_up":"all","status":"paused","start_at":"2023-01-01T00:00:00Z","paused_at":"2023-01-01T12:00:00Z"}`))
+	})
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	d, err := NewDB(dir, "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	// The last pause of a grant becomes the only one in its list
+	grant, err := d.GetGrant("g1")
+	require.NoError(t, err)
+	require.Len(t, grant.Pauses, 1)
+	assert.True(t, grant.Pauses[0].PausedAt.Equal(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)))
+	assert.Nil(t, grant.Pauses[0].ResumedAt)
+	assert.True(t, grant.Paused(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// ErrConflict is returned when a write still conflicts with concurrent writes after all retries
+var ErrConflict = errors.New("write conflict: too many concurrent updates")
+
+// Stats are counters describing how often writes conflicted and were retried
+type Stats struct {
+	Conflicts uint64 `json:"conflicts"`
+	Retries   uint64 `json:"retries"`
+	Exhausted uint64 `json:"exhausted"`
+}
+
+// writeStats holds the live counters behind Stats
+type writeStats struct {
+	conflicts atomic.Uint64
+	retries   atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+// Stats returns the write conflict counters for this environment
+func (d *DB) Stats() Stats {
+	return Stats{
+		Conflicts: d.stats.conflicts.Load(),
+		Retries:   d.stats.retries.Load(),
+		Exhausted: d.stats.exhausted.Load(),
+	}
+}
+
+// update runs fn in a read-write transaction, retrying it with jittered
+// exponential backoff when the commit conflicts with a concurrent write.
+// fn may run several times, so it must derive all of its writes from what it reads:
+// the current time and new IDs are taken inside fn, never captured from outside it,
+// so that a retry does not commit values from before the writes it lost against.
+func (d *DB) update(fn func(txn *badger.Txn) error) error {
+	delay := d.config.RetryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := d.db.Update(fn)
+		if err != badger.ErrConflict {
+			return err
+		}
+
+		d.stats.conflicts.Add(1)
+		if attempt >= d.config.RetryAttempts {
+			d.stats.exhausted.Add(1)
+			return ErrConflict
+		}
+
+		d.stats.retries.Add(1)
+		time.Sleep(delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)))
+
+		delay *= 2
+		if delay > d.config.RetryMaxDelay {
+			delay = d.config.RetryMaxDelay
+		}
+	}
+}
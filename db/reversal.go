@@ -0,0 +1,191 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrNotReversible is returned for transactions that cannot be reversed: reversals
+	// themselves and entries written without a counterparty entry
+	ErrNotReversible = errors.New("transaction cannot be reversed")
+
+	// ErrAlreadyReversed is returned when a transaction has already been reversed in full
+	ErrAlreadyReversed = errors.New("transaction has already been reversed")
+
+	// ErrReversalTooLarge is returned when a partial reversal exceeds what is left to reverse
+	ErrReversalTooLarge = errors.New("amount exceeds the part of the transaction left to reverse")
+)
+
+// operationReverse is the operation recorded for reversals by idempotent writes
+const operationReverse = "reverse"
+
+// Reverse posts compensating entries for a transaction and its counterparty entry,
+// moving amount back the other way. A zero amount reverses everything not reversed
+// yet. Both original entries are marked reversed or partially reversed.
+//
+// Reversals restore an earlier state, so they are posted even if the wallet
+// they debit has spent the currency since and goes negative, and currency
+// limits other than precision do not apply.
+func (d *DB) Reverse(transactionID string, amount models.Amount, description string, additionalData map[string]interface{}, opts ...WriteOption) (*Result, error) {
+	if amount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+
+	options := newWriteOptions(opts)
+
+	var hash string
+	if options.idempotencyKey != "" {
+		var err error
+		if hash, err = requestHash(operationReverse, transactionID, "", "", amount, description, additionalData, writeOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *Result
+	err := d.update(func(txn *badger.Txn) error {
+		// Return the original outcome if this request was already applied
+		if options.idempotencyKey != "" {
+			replay, err := replayIdempotent(txn, options.idempotencyKey, hash)
+			if err != nil {
+				return err
+			}
+			if replay != nil {
+				result = replay
+				return nil
+			}
+		}
+
+		original, err := loadTransaction(txn, transactionID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf != "" || original.CounterpartyTransactionID == "" {
+			return ErrNotReversible
+		}
+
+		counterparty, err := loadTransaction(txn, original.CounterpartyTransactionID)
+		if err != nil {
+			return err
+		}
+
+		remaining := original.Reversible()
+		if remaining == 0 {
+			return ErrAlreadyReversed
+		}
+
+		reversed := amount
+		if reversed == 0 {
+			reversed = remaining
+		}
+		if reversed > remaining {
+			return ErrReversalTooLarge
+		}
+
+		definition, err := d.loadCurrency(txn, original.Currency)
+		if err != nil {
+			return err
+		}
+
+		if !definition.HasPrecision(reversed) {
+			return ErrCurrencyPrecision
+		}
+
+		now := time.Now()
+		reversalID := d.ids.New()
+		entryID := d.ids.New()
+
+		text := description
+		if text == "" {
+			text = "Reversal of " + original.ID
+		}
+
+		// Each compensating entry moves the amount back against its original
+		reversal := &models.Transaction{
+			ID:                        reversalID,
+			WalletID:                  original.WalletID,
+			Currency:                  original.Currency,
+			Amount:                    reversed,
+			Description:               text,
+			AdditionalData:            additionalData,
+			CounterpartyWalletID:      counterparty.WalletID,
+			CounterpartyTransactionID: entryID,
+			ReversalOf:                original.ID,
+			Timestamp:                 now,
+		}
+		if original.Amount > 0 {
+			reversal.Amount = -reversed
+		}
+
+		entry := &models.Transaction{
+			ID:                        entryID,
+			WalletID:                  counterparty.WalletID,
+			Currency:                  counterparty.Currency,
+			Amount:                    -reversal.Amount,
+			Description:               text,
+			AdditionalData:            additionalData,
+			CounterpartyWalletID:      original.WalletID,
+			CounterpartyTransactionID: reversalID,
+			ReversalOf:                counterparty.ID,
+			Timestamp:                 now,
+		}
+
+		// Reversals are corrections, so they are allowed on frozen wallets but not on closed ones
+		for _, walletID := range []string{original.WalletID, counterparty.WalletID} {
+			if err := checkWalletOpen(txn, walletID); err != nil {
+				return err
+			}
+		}
+
+		wallet, err := loadAccount(txn, original.WalletID, original.Currency, counterparty.WalletID)
+		if err != nil {
+			return err
+		}
+
+		other, err := loadAccount(txn, counterparty.WalletID, counterparty.Currency, original.WalletID)
+		if err != nil {
+			return err
+		}
+
+		if err := d.postEntries(txn, wallet, reversal, other, entry); err != nil {
+			return err
+		}
+
+		// Mark both originals so that history shows what was reversed
+		for _, tx := range []*models.Transaction{original, counterparty} {
+			markReversed(tx, reversed)
+			if err := saveTransaction(txn.Set, tx); err != nil {
+				return err
+			}
+		}
+
+		// Reversing the entry of a system account returns its shard for the counterparty;
+		// summing every shard here would make the reversal conflict with all other writes
+		result = &Result{Transaction: reversal, Wallet: wallet}
+
+		if options.idempotencyKey != "" {
+			return d.saveIdempotent(txn, options.idempotencyKey, hash, result)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// markReversed records that amount of a transaction has been reversed
+func markReversed(tx *models.Transaction, amount models.Amount) {
+	tx.ReversedAmount += amount
+	tx.Status = models.TransactionStatusPartiallyReversed
+	if tx.Reversible() == 0 {
+		tx.Status = models.TransactionStatusReversed
+	}
+}
@@ -0,0 +1,384 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrInvalidSchedule is returned when an operation is scheduled for a time that is not in the future
+	ErrInvalidSchedule = errors.New("scheduled time must be in the future")
+
+	// ErrNotPending is returned when a scheduled operation that already ran or was cancelled is cancelled
+	ErrNotPending = errors.New("scheduled operation is no longer pending")
+)
+
+// ScheduleOperation stores an add or removal to be applied at executeAt. The faucet
+// or sink it is posted against is chosen with WithSystemAccount; other write options
+// are ignored. An empty currency selects the default currency.
+func (d *DB) ScheduleOperation(operation, walletID, currency string, amount models.Amount, description string, additionalData map[string]interface{}, executeAt time.Time, opts ...WriteOption) (*models.ScheduledOperation, error) {
+	if operation != operationAdd && operation != operationRemove {
+		return nil, errors.New("only adds and removals can be scheduled")
+	}
+
+	options := newWriteOptions(opts)
+
+	currency, account, err := d.writeTarget(operation, walletID, currency, amount, options.systemAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	if !executeAt.After(time.Now()) {
+		return nil, ErrInvalidSchedule
+	}
+
+	scheduled := &models.ScheduledOperation{
+		Type:           operation,
+		WalletID:       walletID,
+		Currency:       currency,
+		Amount:         amount,
+		Account:        account,
+		Description:    description,
+		AdditionalData: additionalData,
+		Status:         models.ScheduledStatusPending,
+		ExecuteAt:      executeAt,
+	}
+
+	err = d.update(func(txn *badger.Txn) error {
+		// Unknown currencies are refused now rather than when the operation is due
+		if _, err := d.loadCurrency(txn, currency); err != nil {
+			return err
+		}
+		if err := checkWalletOpen(txn, walletID); err != nil {
+			return err
+		}
+
+		scheduled.ID = d.ids.New()
+		scheduled.CreatedAt = time.Now()
+		return saveScheduled(txn, scheduled)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// GetScheduledOperation retrieves a scheduled operation by ID
+func (d *DB) GetScheduledOperation(id string) (*models.ScheduledOperation, error) {
+	var scheduled *models.ScheduledOperation
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		scheduled, err = loadScheduled(txn, id)
+		return err
+	})
+
+	return scheduled, err
+}
+
+// ListScheduledOperations retrieves scheduled operations in the order they were
+// created. A non-empty walletID or status only lists the operations that match it.
+func (d *DB) ListScheduledOperations(walletID, status string) ([]*models.ScheduledOperation, error) {
+	operations := []*models.ScheduledOperation{}
+	prefix := models.ScheduledKey("")
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			scheduled := &models.ScheduledOperation{}
+			if err := it.Item().Value(scheduled.FromJSON); err != nil {
+				return err
+			}
+
+			if walletID != "" && scheduled.WalletID != walletID {
+				continue
+			}
+			if status != "" && scheduled.Status != status {
+				continue
+			}
+
+			operations = append(operations, scheduled)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// CancelScheduledOperation cancels a pending scheduled operation so that it never runs
+func (d *DB) CancelScheduledOperation(id string) (*models.ScheduledOperation, error) {
+	var scheduled *models.ScheduledOperation
+
+	err := d.update(func(txn *badger.Txn) error {
+		var err error
+		scheduled, err = loadScheduled(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if scheduled.Status != models.ScheduledStatusPending {
+			return ErrNotPending
+		}
+
+		return closeScheduled(txn, scheduled, models.ScheduledStatusCancelled)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// RunScheduledOperations applies the pending operations that are due by now, in
+// execution order, and returns how many ran. Each operation is applied in the same
+// transaction that marks it executed, so it runs exactly once even if the service
+// stops part way. An operation that breaks a rule, e.g. for lack of funds, is marked
+// failed with its error; one that conflicts with concurrent writes stays pending and
+// is retried on the next run. One that fails for any other reason, e.g. a storage
+// error, records the attempt and is retried until it has failed ScheduleMaxAttempts
+// times, when it is marked failed as well.
+func (d *DB) RunScheduledOperations(now time.Time) (int, error) {
+	prefix := models.ScheduledDuePrefix()
+	end := expiryBoundary(prefix, now)
+
+	var ids []string
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			if bytes.Compare(key, end) >= 0 {
+				break
+			}
+			ids = append(ids, models.ScheduledIDFromDueKey(key))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	ran := 0
+	var lastErr error
+	for _, id := range ids {
+		var pending bool
+		err := d.update(func(txn *badger.Txn) error {
+			scheduled, err := loadScheduled(txn, id)
+			if err != nil {
+				return err
+			}
+
+			// The operation may have been cancelled since it was listed
+			pending = scheduled.Status == models.ScheduledStatusPending
+			if !pending {
+				return nil
+			}
+
+			executedAt := time.Now()
+			result, err := d.postWrite(txn, scheduled.Type, scheduled.WalletID, scheduled.Account, scheduled.Currency, scheduled.Amount, scheduled.Description, scheduled.AdditionalData, writeOptions{}, executedAt)
+			if err != nil {
+				return err
+			}
+
+			scheduled.ExecutedAt = &executedAt
+			scheduled.TransactionID = result.Transaction.ID
+			return closeScheduled(txn, scheduled, models.ScheduledStatusExecuted)
+		})
+
+		if ruleErrors[err] {
+			// The write was rolled back, so record why it failed instead
+			if err := d.failScheduled(id, err); err != nil {
+				return ran, err
+			}
+			continue
+		}
+		if err != nil && err != ErrConflict {
+			lastErr = err
+			if err := d.retryScheduled(id, err); err != nil {
+				lastErr = err
+			}
+			continue
+		}
+		if err == nil && pending {
+			ran++
+		}
+	}
+
+	return ran, lastErr
+}
+
+// ruleErrors are the errors of scheduled operations and grant occurrences that break
+// a rule of their currency, the status or limits of their wallet or are invalid, so
+// that they would fail the same way on every later run
+var ruleErrors = map[error]bool{
+	ErrInsufficientFunds:         true,
+	ErrUnknownCurrency:           true,
+	ErrCurrencyPrecision:         true,
+	ErrAmountBelowMinimum:        true,
+	ErrAmountAboveMaximum:        true,
+	ErrBalanceLimitExceeded:      true,
+	ErrWalletTransactionLimit:    true,
+	ErrWalletBalanceLimit:        true,
+	ErrWalletDebitsFrozen:        true,
+	ErrWalletFrozen:              true,
+	ErrWalletClosed:              true,
+	ErrSystemAccount:             true,
+	ErrAmountOverflow:            true,
+	models.ErrInvalidCurrency:    true,
+	models.ErrInvalidAccountName: true,
+}
+
+// RunScheduledOperations applies the due scheduled operations of every open environment
+func (m *DBManager) RunScheduledOperations(now time.Time) error {
+	var lastErr error
+	for environment, db := range m.openDatabases() {
+		ran, err := db.RunScheduledOperations(now)
+		if ran > 0 {
+			log.Printf("Ran %d scheduled operations in environment %s", ran, environment)
+		}
+		if err != nil {
+			log.Printf("Failed to run scheduled operations in environment %s: %v", environment, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// RunScheduler runs the due scheduled operations and recurring grants of every open
+// environment every ScheduleInterval until ctx is done
+func (m *DBManager) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(m.config.ScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.RunScheduledOperations(now)
+			m.RunGrants(now)
+		}
+	}
+}
+
+// failScheduled marks a pending scheduled operation failed with the error its write returned
+func (d *DB) failScheduled(id string, cause error) error {
+	return d.update(func(txn *badger.Txn) error {
+		now := time.Now()
+
+		scheduled, err := loadScheduled(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if scheduled.Status != models.ScheduledStatusPending {
+			return nil
+		}
+
+		scheduled.Attempts++
+		scheduled.LastError = cause.Error()
+		scheduled.ExecutedAt = &now
+		scheduled.Error = cause.Error()
+		return closeScheduled(txn, scheduled, models.ScheduledStatusFailed)
+	})
+}
+
+// retryScheduled records a failed attempt of a pending scheduled operation that broke
+// no rule, keeping it pending until it has failed ScheduleMaxAttempts times and then
+// marking it failed with the error of the last attempt
+func (d *DB) retryScheduled(id string, cause error) error {
+	return d.update(func(txn *badger.Txn) error {
+		now := time.Now()
+
+		scheduled, err := loadScheduled(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if scheduled.Status != models.ScheduledStatusPending {
+			return nil
+		}
+
+		scheduled.Attempts++
+		scheduled.LastError = cause.Error()
+		if scheduled.Attempts < d.config.ScheduleMaxAttempts {
+			return saveScheduled(txn, scheduled)
+		}
+
+		scheduled.ExecutedAt = &now
+		scheduled.Error = cause.Error()
+		return closeScheduled(txn, scheduled, models.ScheduledStatusFailed)
+	})
+}
+
+// loadScheduled reads a scheduled operation by ID inside a transaction
+func loadScheduled(txn *badger.Txn, id string) (*models.ScheduledOperation, error) {
+	item, err := txn.Get(models.ScheduledKey(id))
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := &models.ScheduledOperation{}
+	if err := item.Value(scheduled.FromJSON); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// closeScheduled sets the final status of a scheduled operation, removes it from the due index and saves it
+func closeScheduled(txn *badger.Txn, scheduled *models.ScheduledOperation, status string) error {
+	scheduled.Status = status
+
+	if err := txn.Delete(scheduled.DueKey()); err != nil {
+		return err
+	}
+	return saveScheduled(txn, scheduled)
+}
+
+// saveScheduled writes a scheduled operation, and indexes it by execution time while it is pending
+func saveScheduled(txn *badger.Txn, scheduled *models.ScheduledOperation) error {
+	data, err := scheduled.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Set(scheduled.Key(), data); err != nil {
+		return err
+	}
+
+	if scheduled.Status != models.ScheduledStatusPending {
+		return nil
+	}
+	return txn.Set(scheduled.DueKey(), nil)
+}
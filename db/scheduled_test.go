@@ -0,0 +1,105 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestRunScheduledOperationsKeepsOperationsPendingOnStorageErrors(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	_, err = d.AddCurrency("w1", "", models.MustParseAmount("10"), "Reward", nil)
+	require.NoError(t, err)
+
+	scheduled, err := d.ScheduleOperation(operationAdd, "w1", "", models.MustParseAmount("5"), "Daily bonus", nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// A balance record that cannot be read fails the write without breaking any rule
+	wallet, err := d.GetWallet("w1", "")
+	require.NoError(t, err)
+	stored, err := wallet.ToJSON()
+	require.NoError(t, err)
+
+	setBalanceRecord := func(val []byte) {
+		err := d.db.Update(func(txn *badger.Txn) error {
+			return txn.Set(wallet.Key(), val)
+		})
+		require.NoError(t, err)
+	}
+
+	setBalanceRecord([]byte("{"))
+	ran, err := d.RunScheduledOperations(time.Now().Add(2 * time.Hour))
+	assert.Error(t, err)
+	assert.Equal(t, 0, ran)
+
+	operation, err := d.GetScheduledOperation(scheduled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusPending, operation.Status)
+	assert.Empty(t, operation.Error)
+	assert.Equal(t, 1, operation.Attempts)
+	assert.NotEmpty(t, operation.LastError)
+
+	// Once the record can be read again the operation runs on the next tick
+	setBalanceRecord(stored)
+	ran, err = d.RunScheduledOperations(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+
+	operation, err = d.GetScheduledOperation(scheduled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusExecuted, operation.Status)
+
+	balance, err := d.GetWalletBalance("w1", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("15"), balance)
+}
+
+func TestRunScheduledOperationsFailsAfterMaxAttempts(t *testing.T) {
+	config := DefaultConfig()
+	config.ScheduleMaxAttempts = 3
+	d, err := NewDB(t.TempDir(), "test", config)
+	require.NoError(t, err)
+	defer d.Close()
+
+	scheduled, err := d.ScheduleOperation(operationAdd, "w1", "", models.MustParseAmount("5"), "Daily bonus", nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// A balance record that never becomes readable fails every attempt
+	wallet := &models.Wallet{WalletID: "w1", Currency: config.DefaultCurrency}
+	err = d.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(wallet.Key(), []byte("{"))
+	})
+	require.NoError(t, err)
+
+	for attempt := 1; attempt <= config.ScheduleMaxAttempts; attempt++ {
+		ran, err := d.RunScheduledOperations(time.Now().Add(2 * time.Hour))
+		assert.Error(t, err)
+		assert.Equal(t, 0, ran)
+
+		operation, err := d.GetScheduledOperation(scheduled.ID)
+		require.NoError(t, err)
+		assert.Equal(t, attempt, operation.Attempts)
+		assert.NotEmpty(t, operation.LastError)
+
+		if attempt < config.ScheduleMaxAttempts {
+			assert.Equal(t, models.ScheduledStatusPending, operation.Status)
+			continue
+		}
+
+		// The last attempt fails the operation and takes it off the schedule
+		assert.Equal(t, models.ScheduledStatusFailed, operation.Status)
+		assert.Equal(t, operation.LastError, operation.Error)
+		assert.NotNil(t, operation.ExecutedAt)
+	}
+
+	ran, err := d.RunScheduledOperations(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, ran)
+}
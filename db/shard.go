@@ -0,0 +1,105 @@
+package db
+
+import (
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// loadAccount reads the balance that one side of a pair of entries is posted to
+// inside a transaction. Player wallets are read from their balance record; system
+// accounts from their shard for the wallet on the other side, so that writes on
+// different wallets never read and rewrite the same system balance.
+func loadAccount(txn *badger.Txn, walletID, currency, counterpartyID string) (*models.Wallet, error) {
+	if !models.IsSystemAccount(walletID) {
+		wallet, _, err := loadWallet(txn, walletID, currency)
+		return wallet, err
+	}
+
+	shard := &models.Wallet{WalletID: walletID, Currency: currency}
+
+	item, err := txn.Get(shard.ShardKey(counterpartyID))
+	if err == badger.ErrKeyNotFound {
+		return shard, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := item.Value(shard.FromJSON); err != nil {
+		return nil, err
+	}
+	return shard, nil
+}
+
+// loadBalance reads the whole balance of a wallet in one currency inside a read-only
+// transaction, adding up the shards of a system account. Writes must not use it:
+// reading every shard would make them conflict with every write on the account.
+func loadBalance(txn *badger.Txn, walletID, currency string) (*models.Wallet, error) {
+	wallet, _, err := loadWallet(txn, walletID, currency)
+	if err != nil || !models.IsSystemAccount(walletID) {
+		return wallet, err
+	}
+
+	if err := addShards(txn, wallet); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// saveShard writes the shard of a system account that holds its entries against counterpartyID
+func saveShard(txn *badger.Txn, shard *models.Wallet, counterpartyID string) error {
+	data, err := shard.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return txn.Set(shard.ShardKey(counterpartyID), data)
+}
+
+// addShards adds the shards of a system account in the wallet's currency to its balance record
+func addShards(txn *badger.Txn, wallet *models.Wallet) error {
+	prefix := append(models.ShardPrefix(wallet.WalletID), wallet.Currency+":"...)
+
+	return forEachShard(txn, prefix, func(shard *models.Wallet) error {
+		return addShard(wallet, shard)
+	})
+}
+
+// addShard adds one shard of a system account to its balance
+func addShard(wallet, shard *models.Wallet) error {
+	balance, ok := wallet.Balance.Add(shard.Balance)
+	if !ok {
+		return ErrAmountOverflow
+	}
+
+	wallet.Balance = balance
+	wallet.TransactionCount += shard.TransactionCount
+	wallet.Version += shard.Version
+	if shard.LastTransactionAt != nil && (wallet.LastTransactionAt == nil || shard.LastTransactionAt.After(*wallet.LastTransactionAt)) {
+		wallet.LastTransactionAt = shard.LastTransactionAt
+	}
+	return nil
+}
+
+// forEachShard passes every balance shard under prefix to fn
+func forEachShard(txn *badger.Txn, prefix []byte, fn func(shard *models.Wallet) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		shard := &models.Wallet{}
+		if err := it.Item().Value(shard.FromJSON); err != nil {
+			return err
+		}
+
+		if err := fn(shard); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"virtigia-microcurrency/models"
+)
+
+func TestSystemAccountWritesDoNotConflict(t *testing.T) {
+	d, err := NewDB(t.TempDir(), "test", DefaultConfig())
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Writers on different wallets share the default faucet and sink but never a key
+	const writers = 64
+	errs := make(chan error, 2*writers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(walletID string) {
+			defer wg.Done()
+			_, err := d.AddCurrency(walletID, "", models.MustParseAmount("10"), "Reward", nil)
+			errs <- err
+			_, err = d.RemoveCurrency(walletID, "", models.MustParseAmount("4"), "Purchase", nil)
+			errs <- err
+		}(fmt.Sprintf("player:%d", i))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, Stats{}, d.Stats())
+
+	// The shards add up to the balances of the system accounts
+	faucet, err := d.GetWallet(models.FaucetAccount(models.DefaultSystemAccount), "")
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("-640"), faucet.Balance)
+	assert.Equal(t, int64(writers), faucet.TransactionCount)
+
+	balances, err := d.GetWalletBalances(models.SinkAccount(models.DefaultSystemAccount))
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	assert.Equal(t, models.MustParseAmount("256"), balances[0].Balance)
+
+	sink, err := d.GetWalletBalanceAt(models.SinkAccount(models.DefaultSystemAccount), "", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.MustParseAmount("256"), sink.Balance)
+
+	trial, err := d.TrialBalance()
+	require.NoError(t, err)
+	require.Len(t, trial, 1)
+	assert.Equal(t, models.MustParseAmount("384"), trial[0].Wallets)
+	assert.Equal(t, models.MustParseAmount("-640"), trial[0].Faucets)
+	assert.Equal(t, models.MustParseAmount("256"), trial[0].Sinks)
+	assert.True(t, trial[0].Balanced)
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var (
+	// ErrWalletDebitsFrozen is returned when a wallet that is frozen for debits would be debited
+	ErrWalletDebitsFrozen = errors.New("wallet is frozen for debits")
+
+	// ErrWalletFrozen is returned when a frozen wallet would be debited or credited
+	ErrWalletFrozen = errors.New("wallet is frozen")
+
+	// ErrWalletClosed is returned when a closed wallet would be written to or have its status changed
+	ErrWalletClosed = errors.New("wallet is closed")
+
+	// ErrInvalidWalletStatus is returned when a wallet is set to an unknown status
+	ErrInvalidWalletStatus = errors.New("unknown wallet status")
+
+	// ErrWalletNotEmpty is returned when a wallet that still holds a balance is closed
+	ErrWalletNotEmpty = errors.New("wallet cannot be closed while it holds a balance")
+)
+
+// GetWalletStatus retrieves the status of a wallet; wallets without one are active
+func (d *DB) GetWalletStatus(walletID string) (*models.WalletStatus, error) {
+	var status *models.WalletStatus
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		status, err = loadWalletStatus(txn, walletID)
+		return err
+	})
+
+	return status, err
+}
+
+// SetWalletStatus changes the status of a wallet in every currency and records the
+// change with its reason and actor in the wallet's audit trail. Closing a wallet
+// requires all of its balances to be zero, and a closed wallet stays closed.
+func (d *DB) SetWalletStatus(walletID, status, reason, actor string) (*models.WalletStatus, error) {
+	if models.IsSystemAccount(walletID) {
+		return nil, ErrSystemAccount
+	}
+
+	if !models.ValidWalletStatus(status) {
+		return nil, ErrInvalidWalletStatus
+	}
+
+	var current *models.WalletStatus
+
+	err := d.update(func(txn *badger.Txn) error {
+		var err error
+		current, err = loadWalletStatus(txn, walletID)
+		if err != nil {
+			return err
+		}
+
+		if current.Status == models.WalletStatusClosed {
+			return ErrWalletClosed
+		}
+
+		if status == models.WalletStatusClosed {
+			balances, err := walletBalances(txn, walletID)
+			if err != nil {
+				return err
+			}
+			for _, wallet := range balances {
+				if wallet.Balance != 0 {
+					return ErrWalletNotEmpty
+				}
+			}
+		}
+
+		now := time.Now()
+		if err := createWallet(txn, walletID, now); err != nil {
+			return err
+		}
+
+		change := &models.WalletStatusChange{
+			ID:        d.ids.New(),
+			WalletID:  walletID,
+			From:      current.Status,
+			To:        status,
+			Reason:    reason,
+			Actor:     actor,
+			ChangedAt: now,
+		}
+
+		current.Status = status
+		current.Reason = reason
+		current.Actor = actor
+		current.UpdatedAt = &now
+
+		data, err := change.ToJSON()
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(change.Key(), data); err != nil {
+			return err
+		}
+
+		data, err = current.ToJSON()
+		if err != nil {
+			return err
+		}
+		return txn.Set(current.Key(), data)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return current, nil
+}
+
+// GetWalletStatusHistory retrieves the audit trail of a wallet's status changes, oldest first
+func (d *DB) GetWalletStatusHistory(walletID string) ([]*models.WalletStatusChange, error) {
+	changes := []*models.WalletStatusChange{}
+	prefix := models.WalletStatusChangePrefix(walletID)
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			change := &models.WalletStatusChange{}
+			if err := it.Item().Value(change.FromJSON); err != nil {
+				return err
+			}
+
+			// Wallet IDs that extend this one share the prefix
+			if change.WalletID != walletID {
+				continue
+			}
+
+			changes = append(changes, change)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// checkWalletStatus refuses a debit or credit of a wallet that its status does not
+// allow. System accounts have no status.
+func checkWalletStatus(txn *badger.Txn, walletID string, debit bool) error {
+	if models.IsSystemAccount(walletID) {
+		return nil
+	}
+
+	status, err := loadWalletStatus(txn, walletID)
+	if err != nil {
+		return err
+	}
+
+	switch status.Status {
+	case models.WalletStatusClosed:
+		return ErrWalletClosed
+	case models.WalletStatusFrozen:
+		return ErrWalletFrozen
+	case models.WalletStatusDebitFrozen:
+		if debit {
+			return ErrWalletDebitsFrozen
+		}
+	}
+	return nil
+}
+
+// checkWalletOpen refuses writes to a closed wallet
+func checkWalletOpen(txn *badger.Txn, walletID string) error {
+	status, err := loadWalletStatus(txn, walletID)
+	if err != nil {
+		return err
+	}
+
+	if status.Status == models.WalletStatusClosed {
+		return ErrWalletClosed
+	}
+	return nil
+}
+
+// loadWalletStatus reads the status of a wallet inside a transaction, defaulting to active
+func loadWalletStatus(txn *badger.Txn, walletID string) (*models.WalletStatus, error) {
+	status := &models.WalletStatus{WalletID: walletID, Status: models.WalletStatusActive}
+
+	item, err := txn.Get(status.Key())
+	if err == badger.ErrKeyNotFound {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := item.Value(status.FromJSON); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
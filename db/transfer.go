@@ -0,0 +1,129 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// TransferResult is the outcome of a transfer between two wallets
+type TransferResult struct {
+	TransferID string
+	Currency   string
+	Debit      *models.Transaction
+	Credit     *models.Transaction
+	FromWallet *models.Wallet
+	ToWallet   *models.Wallet
+}
+
+// Transfer moves currency from one wallet to another. The debit, the credit
+// and both transaction records are committed in a single database transaction.
+// An empty currency selects the default currency.
+func (d *DB) Transfer(fromWalletID, toWalletID, currency string, amount models.Amount, description string, additionalData map[string]interface{}) (*TransferResult, error) {
+	currency, err := d.transferTarget(fromWalletID, toWalletID, currency, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *TransferResult
+	err = d.update(func(txn *badger.Txn) error {
+		var err error
+		result, err = d.postTransfer(txn, fromWalletID, toWalletID, currency, amount, description, additionalData, time.Now())
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// transferTarget validates a transfer and resolves its currency. System accounts
+// only move currency through adds and removals, so they cannot take either side.
+func (d *DB) transferTarget(fromWalletID, toWalletID, currency string, amount models.Amount) (string, error) {
+	if amount <= 0 {
+		return "", errors.New("amount must be positive")
+	}
+
+	if models.IsSystemAccount(fromWalletID) || models.IsSystemAccount(toWalletID) {
+		return "", ErrSystemAccount
+	}
+
+	if fromWalletID == toWalletID {
+		return "", ErrSameWallet
+	}
+
+	return d.currency(currency)
+}
+
+// postTransfer checks a transfer against the rules of its currency and the statuses,
+// balances and limits of both wallets, then posts the debit and the credit inside a transaction
+func (d *DB) postTransfer(txn *badger.Txn, fromWalletID, toWalletID, currency string, amount models.Amount, description string, additionalData map[string]interface{}, now time.Time) (*TransferResult, error) {
+	definition, err := d.loadCurrency(txn, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if !definition.Transferable {
+		return nil, ErrCurrencyNotTransferable
+	}
+
+	if err := checkAmount(definition, amount); err != nil {
+		return nil, err
+	}
+
+	if err := checkWalletStatus(txn, fromWalletID, true); err != nil {
+		return nil, err
+	}
+	if err := checkWalletStatus(txn, toWalletID, false); err != nil {
+		return nil, err
+	}
+
+	from, exists, err := loadWallet(txn, fromWalletID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := heldAmount(txn, fromWalletID, currency, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkWalletAmount(from, amount); err != nil {
+		return nil, err
+	}
+
+	if err := checkDebit(definition, from, exists, held, amount); err != nil {
+		return nil, err
+	}
+
+	to, _, err := loadWallet(txn, toWalletID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkWalletAmount(to, amount); err != nil {
+		return nil, err
+	}
+
+	if err := checkCredit(definition, to, amount); err != nil {
+		return nil, err
+	}
+
+	result := &TransferResult{TransferID: d.ids.New(), Currency: currency}
+	result.Debit, result.Credit = d.newEntries(fromWalletID, toWalletID, currency, -amount, description, additionalData, now)
+	result.Debit.TransferID = result.TransferID
+	result.Credit.TransferID = result.TransferID
+
+	if err := d.postEntries(txn, from, result.Debit, to, result.Credit); err != nil {
+		return nil, err
+	}
+
+	result.FromWallet = from
+	result.ToWallet = to
+	return result, nil
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"time"
+
+	"virtigia-microcurrency/models"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// WalletRecord is everything stored about a wallet across its currencies
+type WalletRecord struct {
+	Metadata *models.WalletMetadata
+	Status   *models.WalletStatus
+	Balances []*models.Wallet
+
+	// Exists is false for wallet IDs that were never written to or described
+	Exists bool
+}
+
+// WalletQuery selects a page of the wallets of an environment in wallet ID order
+type WalletQuery struct {
+	Limit int
+
+	// Cursor continues after the last wallet of a page returned earlier
+	Cursor string
+
+	// Prefix restricts the page to wallet IDs that start with it
+	Prefix string
+
+	Filter WalletFilter
+}
+
+// WalletFilter restricts a wallet listing. Zero values match every wallet.
+type WalletFilter struct {
+	// Label is a label the wallet must carry
+	Label string
+
+	// MinBalance and MaxBalance bound the balance in Currency, both inclusive.
+	// A wallet that never held the currency has a zero balance in it.
+	Currency   string
+	MinBalance *models.Amount
+	MaxBalance *models.Amount
+
+	// ActiveSince and ActiveBefore bound the last activity of the wallet; wallets
+	// without any transaction match neither
+	ActiveSince  time.Time
+	ActiveBefore time.Time
+}
+
+// matches reports whether a wallet record passes the filter
+func (f WalletFilter) matches(record *WalletRecord) bool {
+	if f.Label != "" && !hasLabel(record.Metadata.Labels, f.Label) {
+		return false
+	}
+
+	if f.MinBalance != nil || f.MaxBalance != nil {
+		var balance models.Amount
+		for _, wallet := range record.Balances {
+			if wallet.Currency == f.Currency {
+				balance = wallet.Balance
+			}
+		}
+		if f.MinBalance != nil && balance < *f.MinBalance || f.MaxBalance != nil && balance > *f.MaxBalance {
+			return false
+		}
+	}
+
+	if !f.ActiveSince.IsZero() || !f.ActiveBefore.IsZero() {
+		active := record.Metadata.LastActivityAt
+		if active == nil || active.Before(f.ActiveSince) || !f.ActiveBefore.IsZero() && !active.Before(f.ActiveBefore) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WalletPage is a page of the wallets of an environment
+type WalletPage struct {
+	Wallets    []*WalletRecord
+	NextCursor string
+}
+
+// GetWalletRecord retrieves the metadata, status and balances of a wallet. Unlike
+// GetWallet it reports whether the wallet exists rather than only returning zeros.
+func (d *DB) GetWalletRecord(walletID string) (*WalletRecord, error) {
+	var record *WalletRecord
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		var err error
+		record, err = loadWalletRecord(txn, walletID)
+		return err
+	})
+
+	return record, err
+}
+
+// ListWallets retrieves a page of the wallets of an environment in wallet ID order.
+// Every wallet has one metadata record, so the listing walks those records from
+// the cursor and loads the status and balances of each to apply the filter.
+// System accounts are not listed.
+func (d *DB) ListWallets(query WalletQuery) (*WalletPage, error) {
+	if query.Filter.MinBalance != nil || query.Filter.MaxBalance != nil {
+		currency, err := d.currency(query.Filter.Currency)
+		if err != nil {
+			return nil, err
+		}
+		query.Filter.Currency = currency
+	}
+
+	prefix := models.WalletMetadataKey(query.Prefix)
+	start := prefix
+	var after []byte
+	if query.Cursor != "" {
+		walletID, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err != nil || len(walletID) == 0 {
+			return nil, ErrInvalidCursor
+		}
+		after = models.WalletMetadataKey(string(walletID))
+		if bytes.Compare(after, start) > 0 {
+			start = after
+		}
+	}
+
+	page := &WalletPage{Wallets: []*WalletRecord{}}
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(start); it.Valid(); it.Next() {
+			if bytes.Equal(it.Item().Key(), after) {
+				continue
+			}
+
+			metadata := &models.WalletMetadata{}
+			if err := it.Item().Value(metadata.FromJSON); err != nil {
+				return err
+			}
+
+			record, err := loadWalletRecord(txn, metadata.WalletID)
+			if err != nil {
+				return err
+			}
+			if !query.Filter.matches(record) {
+				continue
+			}
+
+			// A further match means there is another page
+			if len(page.Wallets) == query.Limit {
+				last := page.Wallets[len(page.Wallets)-1].Metadata.WalletID
+				page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+				return nil
+			}
+
+			page.Wallets = append(page.Wallets, record)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// loadWalletRecord reads the metadata, status and balances of a wallet inside a transaction
+func loadWalletRecord(txn *badger.Txn, walletID string) (*WalletRecord, error) {
+	metadata, exists, err := loadWalletMetadata(txn, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := loadWalletStatus(txn, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := walletBalances(txn, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletRecord{
+		Metadata: metadata,
+		Status:   status,
+		Balances: balances,
+		Exists:   exists || len(balances) > 0,
+	}, nil
+}
+
+// hasLabel reports whether labels contains label
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
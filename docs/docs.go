@@ -24,9 +24,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/wallets/{wallet_id}/add": {
+        "/batch": {
             "post": {
-                "description": "Add currency to a wallet and record the transaction",
+                "description": "Apply adds, removes and transfers in order in a single database transaction: either all of them\nare committed or none is. Each operation sees the balances left by the ones before it.\nIf an operation fails, the response names its index and nothing is applied.",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,9 +34,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wallet"
+                    "batch"
                 ],
-                "summary": "Add currency to a wallet",
+                "summary": "Apply a batch of operations",
                 "parameters": [
                     {
                         "type": "string",
@@ -47,18 +47,17 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "wallet_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
                     },
                     {
-                        "description": "Add currency request",
+                        "description": "Batch request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.AddCurrencyRequest"
+                            "$ref": "#/definitions/api.BatchRequest"
                         }
                     }
                 ],
@@ -66,13 +65,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.TransactionResponse"
+                            "$ref": "#/definitions/api.BatchResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
+                            "$ref": "#/definitions/api.BatchErrorResponse"
                         }
                     },
                     "401": {
@@ -81,6 +80,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.BatchErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -90,19 +107,66 @@ const docTemplate = `{
                 }
             }
         },
-        "/wallets/{wallet_id}/balance": {
+        "/currencies": {
             "get": {
-                "description": "Get the balance of a wallet",
-                "consumes": [
+                "description": "List every currency definition of the environment, including the default currency",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "currencies"
+                ],
+                "summary": "List currencies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Currency"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/currencies/{currency}": {
+            "get": {
+                "description": "Get the definition of a currency",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wallet"
+                    "currencies"
                 ],
-                "summary": "Get wallet balance",
+                "summary": "Get currency",
                 "parameters": [
                     {
                         "type": "string",
@@ -113,8 +177,14 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "wallet_id",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
                         "in": "path",
                         "required": true
                     }
@@ -123,7 +193,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.WalletBalanceResponse"
+                            "$ref": "#/definitions/models.Currency"
                         }
                     },
                     "400": {
@@ -138,6 +208,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -145,11 +221,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/wallets/{wallet_id}/remove": {
-            "post": {
-                "description": "Remove currency from a wallet and record the transaction",
+            },
+            "put": {
+                "description": "Create or replace the definition of a currency. The rules apply to later transactions;\nbalances already held are not changed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -157,9 +231,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wallet"
+                    "currencies"
                 ],
-                "summary": "Remove currency from a wallet",
+                "summary": "Define currency",
                 "parameters": [
                     {
                         "type": "string",
@@ -170,18 +244,24 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "wallet_id",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Remove currency request",
+                        "description": "Currency definition",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.RemoveCurrencyRequest"
+                            "$ref": "#/definitions/api.CurrencyRequest"
                         }
                     }
                 ],
@@ -189,7 +269,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.TransactionResponse"
+                            "$ref": "#/definitions/models.Currency"
                         }
                     },
                     "400": {
@@ -204,6 +284,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -213,9 +299,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/wallets/{wallet_id}/transactions": {
+        "/grants": {
             "get": {
-                "description": "Get the transaction history for a wallet with pagination",
+                "description": "List every recurring grant in the order they were created",
                 "consumes": [
                     "application/json"
                 ],
@@ -223,9 +309,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "grants"
                 ],
-                "summary": "Get transaction history",
+                "summary": "List grants",
                 "parameters": [
                     {
                         "type": "string",
@@ -236,31 +322,73 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "wallet_id",
-                        "in": "path",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.GrantsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a recurring grant, such as a daily allowance, that pays a fixed amount from a faucet to every\nenrolled wallet on a schedule. The schedule is either interval_seconds, counted from each\nenrollment's start, or a five-field cron expression evaluated in UTC. catch_up decides which\noccurrences missed while the service was down are granted: all of them, only the latest, or none\nthat is overdue by more than GRANT_GRACE_PERIOD (skip).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Create a grant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Limit",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
                     },
                     {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Offset",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Create grant request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateGrantRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.TransactionHistoryResponse"
+                            "$ref": "#/definitions/models.Grant"
                         }
                     },
                     "400": {
@@ -275,6 +403,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -283,102 +417,4950 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.AddCurrencyRequest": {
-            "type": "object",
-            "required": [
-                "amount",
-                "description"
-            ],
-            "properties": {
-                "additional_data": {
-                    "type": "object",
-                    "additionalProperties": true
-                },
-                "amount": {
-                    "type": "number"
-                },
-                "description": {
-                    "type": "string"
-                }
-            }
-        },
-        "api.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
         },
-        "api.Pagination": {
-            "type": "object",
-            "properties": {
-                "count": {
-                    "type": "integer"
-                },
-                "limit": {
-                    "type": "integer"
-                },
-                "offset": {
-                    "type": "integer"
+        "/grants/{grant_id}": {
+            "get": {
+                "description": "Get a recurring grant by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Get grant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Grant"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "api.RemoveCurrencyRequest": {
-            "type": "object",
-            "required": [
-                "amount",
-                "description"
-            ],
-            "properties": {
-                "additional_data": {
-                    "type": "object",
-                    "additionalProperties": true
-                },
-                "amount": {
-                    "type": "number"
-                },
-                "description": {
+        "/grants/{grant_id}/enrollments": {
+            "get": {
+                "description": "List the enrollments of every wallet in a grant, ordered by wallet ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "List enrollments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.GrantEnrollmentsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Enroll a wallet in a grant. Its first occurrence is due at start_at, or at the first cron match from\nthen, and its occurrences end before end_at, e.g. 30 days after the start of a subscription. A wallet\nwhose earlier enrollment ended or was cancelled is enrolled anew.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Enroll a wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Enroll wallet request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.EnrollWalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/enrollments/{wallet_id}": {
+            "get": {
+                "description": "Get the enrollment of a wallet in a grant, with its next occurrence and how many occurrences were\ngranted, failed or skipped",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Get enrollment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantEnrollment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/enrollments/{wallet_id}/cancel": {
+            "post": {
+                "description": "End the enrollment of a wallet in a grant before its end_at",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Cancel an enrollment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/enrollments/{wallet_id}/pause": {
+            "post": {
+                "description": "Stop crediting one wallet enrolled in a grant. Occurrences that fall due while the enrollment is\npaused are not granted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Pause an enrollment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/enrollments/{wallet_id}/resume": {
+            "post": {
+                "description": "Resume crediting a wallet whose enrollment is paused from its next occurrence",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Resume an enrollment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/pause": {
+            "post": {
+                "description": "Stop crediting every wallet enrolled in an active grant. Occurrences that fall due while the grant is\npaused are skipped, whatever its catch-up policy.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Pause a grant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Grant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/resume": {
+            "post": {
+                "description": "Resume crediting the wallets enrolled in a paused grant from their next occurrence",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "Resume a grant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Grant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/grants/{grant_id}/runs": {
+            "get": {
+                "description": "List the occurrences a grant ran, in order of their due time, with the transaction each granted\noccurrence recorded or the error a failed one was refused with. The transactions carry the grant_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "grants"
+                ],
+                "summary": "List grant runs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grant ID",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only runs for this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.GrantRunsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/holds/{hold_id}": {
+            "get": {
+                "description": "Get a hold by its ID. Holds past their expiry are reported as expired.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Get hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "hold_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/holds/{hold_id}/capture": {
+            "post": {
+                "description": "Pay all or part of an active hold from its wallet into its sink and close the hold.\nWhatever is not captured is released.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Capture a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "hold_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Capture hold request",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.CaptureHoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.CaptureHoldResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/holds/{hold_id}/release": {
+            "post": {
+                "description": "Close an active hold without moving any currency, making the held amount available again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Release a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "hold_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/trial-balance": {
+            "get": {
+                "description": "Sum the balances of all wallets, faucets and sinks and the amounts of all transactions per currency.\nEvery sum is zero in a consistent ledger. The whole ledger is read, so use it for audits only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ledger"
+                ],
+                "summary": "Get trial balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TrialBalanceResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled": {
+            "get": {
+                "description": "List scheduled operations in the order they were created, optionally only those of one wallet\nor in one status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "List scheduled operations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only operations on this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "executed",
+                            "failed",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Only operations in this status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ScheduledOperationsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Store an add or removal to be applied at execute_at. A background job applies due operations\nexactly once and records the transaction on the operation; an operation that breaks a rule,\ne.g. for lack of funds, is marked failed with the error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "Schedule an operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Schedule operation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ScheduleOperationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledOperation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled/{scheduled_id}": {
+            "get": {
+                "description": "Get a scheduled operation by its ID, including the transaction it recorded once executed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "Get scheduled operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scheduled operation ID",
+                        "name": "scheduled_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledOperation"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled/{scheduled_id}/cancel": {
+            "post": {
+                "description": "Cancel a pending scheduled operation so that it never runs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled"
+                ],
+                "summary": "Cancel a scheduled operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scheduled operation ID",
+                        "name": "scheduled_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledOperation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "description": "Get write conflict and retry counters for the current environment since startup",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get database statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.StatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{transaction_id}": {
+            "get": {
+                "description": "Get a transaction by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{transaction_id}/reverse": {
+            "post": {
+                "description": "Post compensating entries that move the amount of a transaction and its counterparty entry back.\nOmit amount to reverse everything not reversed yet. The originals are marked reversed or partially_reversed.\nReversals are posted even if the debited wallet goes negative.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Reverse a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the original result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reverse transaction request",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.ReverseTransactionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers": {
+            "post": {
+                "description": "Debit one wallet and credit another in a single atomic operation.\nTransfers without a currency use the default currency. System accounts cannot take part in transfers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Transfer currency between wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Transfer request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets": {
+            "get": {
+                "description": "List the wallets of an environment in wallet ID order with their full records. System accounts are\nnot listed. Filters narrow the listing and must all match: prefix on the wallet ID, a label, a range\non the balance in currency (the default currency if omitted; wallets that never held it have zero)\nand a range on the last activity. Pass next_cursor from a previous response as cursor for the next page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "List wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label the wallet carries",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the balance range",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum balance, inclusive",
+                        "name": "min_balance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum balance, inclusive",
+                        "name": "max_balance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last activity at or after this time (RFC3339)",
+                        "name": "active_since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last activity before this time (RFC3339)",
+                        "name": "active_before",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Limit, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}": {
+            "get": {
+                "description": "Get a wallet's owner, labels and attributes, when it was created and last active, its status and\nits balance, version and limits in every currency it has held. exists is false for wallet IDs that\nwere never written to or described, which are returned with an active status and no balances.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Get wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the owner, labels and attributes of a wallet. Fields left out are cleared. A wallet that\nhas never been written to is created. Repeated labels are dropped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Set wallet metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Wallet metadata",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletMetadataRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change the owner, labels or attributes of a wallet. Omitted fields are left unchanged; labels are\nreplaced as a whole, attributes are merged into the existing ones and an attribute set to null is\nremoved. A wallet that has never been written to is created.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Update wallet metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Wallet metadata changes",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletMetadataPatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/add": {
+            "post": {
+                "description": "Issue currency from a faucet system account into a wallet and record both entries.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Add currency to a wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the original result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ETag the write is conditional on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add currency request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.AddCurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet after the write"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/balance": {
+            "get": {
+                "description": "Get the balance of a wallet in one currency. Routes without a currency code use the default currency.\nThe available balance is the balance less active holds. Wallets with a credit limit also report how much credit is left.\nPass at to get the balance at that instant together with the last transaction applied at or before it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Get wallet balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Return the balance as of this time (RFC3339)",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletBalanceResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/balances": {
+            "get": {
+                "description": "Get the balance of a wallet in every currency it has held",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Get wallet balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletBalancesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/add": {
+            "post": {
+                "description": "Issue currency from a faucet system account into a wallet and record both entries.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Add currency to a wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the original result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ETag the write is conditional on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add currency request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.AddCurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet after the write"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/balance": {
+            "get": {
+                "description": "Get the balance of a wallet in one currency. Routes without a currency code use the default currency.\nThe available balance is the balance less active holds. Wallets with a credit limit also report how much credit is left.\nPass at to get the balance at that instant together with the last transaction applied at or before it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Get wallet balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Return the balance as of this time (RFC3339)",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletBalanceResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/holds": {
+            "get": {
+                "description": "Get the active holds of a wallet in one currency, ordered by expiry.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "List holds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.HoldsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Reserve part of a wallet's available balance until the hold is captured, released or expires.\nHeld currency stays in the balance but cannot be removed, transferred or held again.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Create a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Create hold request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateHoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/limits": {
+            "put": {
+                "description": "Replace the limits of a wallet in one currency. A credit limit lets the available balance go\nthat far below zero; a maximum balance and maximum transaction cap the wallet in addition to the\nlimits of the currency. The limits apply to later transactions; a balance already beyond them is\nnot changed. Routes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Set wallet limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Wallet limits",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletLimitsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/lots": {
+            "get": {
+                "description": "Get the balance of a wallet in one currency broken down into its active expiring lots, in expiry\norder, and the part of the balance that does not expire. Debits spend the lots that expire first.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "List lots",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.LotsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/remove": {
+            "post": {
+                "description": "Pay currency from a wallet into a sink system account and record both entries.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Remove currency from a wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the original result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ETag the write is conditional on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Remove currency request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.RemoveCurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet after the write"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/currencies/{currency}/transactions": {
+            "get": {
+                "description": "Get the transaction history of a wallet in one currency with pagination.\nRoutes without a currency code use the default currency.\nPass next_cursor or prev_cursor from a previous response as cursor to move between pages;\na cursor keeps the sort order of the page it came from and ignores offset.\nFilter on additional data with additional_data.\u003ckey\u003e=\u003cvalue\u003e, e.g. additional_data.item_id=item789;\nseveral such parameters must all match.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency code",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Limit, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "\"timestamp\"",
+                        "description": "Sort by",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "\"DESC\"",
+                        "description": "Sort order",
+                        "name": "sort_order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions at or after this time (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions at or before this time (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "credit",
+                            "debit"
+                        ],
+                        "type": "string",
+                        "description": "Only credits or only debits",
+                        "name": "direction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum signed amount, inclusive",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum signed amount, inclusive",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Description contains this text, ignoring case",
+                        "name": "description",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionHistoryResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/holds": {
+            "get": {
+                "description": "Get the active holds of a wallet in one currency, ordered by expiry.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "List holds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.HoldsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Reserve part of a wallet's available balance until the hold is captured, released or expires.\nHeld currency stays in the balance but cannot be removed, transferred or held again.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Create a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Create hold request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateHoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/limits": {
+            "put": {
+                "description": "Replace the limits of a wallet in one currency. A credit limit lets the available balance go\nthat far below zero; a maximum balance and maximum transaction cap the wallet in addition to the\nlimits of the currency. The limits apply to later transactions; a balance already beyond them is\nnot changed. Routes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Set wallet limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Wallet limits",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletLimitsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/lots": {
+            "get": {
+                "description": "Get the balance of a wallet in one currency broken down into its active expiring lots, in expiry\norder, and the part of the balance that does not expire. Debits spend the lots that expire first.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "List lots",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.LotsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/remove": {
+            "post": {
+                "description": "Pay currency from a wallet into a sink system account and record both entries.\nRoutes without a currency code use the default currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Remove currency from a wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the original result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ETag the write is conditional on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Remove currency request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.RemoveCurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet after the write"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/status": {
+            "get": {
+                "description": "Get the status of a wallet across all of its currencies, with the reason and actor of the change that\nset it. Wallets whose status was never changed are active.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Get wallet status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Change the status of a wallet in every currency and record the change in its audit trail.\ndebit_frozen refuses removals, outgoing transfers, holds and captures but still allows credits;\nfrozen refuses every debit and credit; closed refuses every write for good and requires all balances\nof the wallet to be zero. Reversals are corrections and are allowed unless the wallet is closed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Set wallet status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Wallet status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/status/history": {
+            "get": {
+                "description": "Get every change of a wallet's status, oldest first, with the status it changed from and to, the\nreason and the actor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Get wallet status history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WalletStatusHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wallets/{wallet_id}/transactions": {
+            "get": {
+                "description": "Get the transaction history of a wallet in one currency with pagination.\nRoutes without a currency code use the default currency.\nPass next_cursor or prev_cursor from a previous response as cursor to move between pages;\na cursor keeps the sort order of the page it came from and ignores offset.\nFilter on additional data with additional_data.\u003ckey\u003e=\u003cvalue\u003e, e.g. additional_data.item_id=item789;\nseveral such parameters must all match.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment (default: production)",
+                        "name": "X-ENV",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Limit, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "\"timestamp\"",
+                        "description": "Sort by",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "\"DESC\"",
+                        "description": "Sort order",
+                        "name": "sort_order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions at or after this time (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions at or before this time (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "credit",
+                            "debit"
+                        ],
+                        "type": "string",
+                        "description": "Only credits or only debits",
+                        "name": "direction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum signed amount, inclusive",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum signed amount, inclusive",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Description contains this text, ignoring case",
+                        "name": "description",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TransactionHistoryResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the wallet"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.AddCurrencyRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description"
+            ],
+            "properties": {
+                "account": {
+                    "description": "Account names the faucet the currency is issued from (default: \"default\")",
+                    "type": "string",
+                    "example": "quest_rewards"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "clamp": {
+                    "description": "Clamp credits as much of the amount as the currency's and wallet's limits allow instead of failing",
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expected_version": {
+                    "description": "ExpectedVersion makes the write fail unless the wallet is still at this version",
+                    "type": "integer",
+                    "example": 7
+                },
+                "expires_at": {
+                    "description": "ExpiresAt makes the credited currency expire at this time; it is tracked as a lot of the wallet",
+                    "type": "string",
+                    "example": "2023-01-08T12:00:00Z"
+                }
+            }
+        },
+        "api.BatchErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "wallet_balance_limit"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.BatchOperationRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description",
+                "type"
+            ],
+            "properties": {
+                "account": {
+                    "type": "string",
+                    "example": "quest_rewards"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "clamp": {
+                    "type": "boolean"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "description": "FromWalletID and ToWalletID apply to transfer",
+                    "type": "string"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "add",
+                        "remove",
+                        "transfer"
+                    ],
+                    "example": "add"
+                },
+                "wallet_id": {
+                    "description": "WalletID and Account apply to add and remove; Clamp and ExpiresAt apply to add",
+                    "type": "string",
+                    "example": "wallet123"
+                }
+            }
+        },
+        "api.BatchOperationResponse": {
+            "type": "object",
+            "properties": {
+                "transaction": {
+                    "$ref": "#/definitions/models.Transaction"
+                },
+                "transfer": {
+                    "$ref": "#/definitions/api.TransferResponse"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "add"
+                },
+                "wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                }
+            }
+        },
+        "api.BatchRequest": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "operations": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/api.BatchOperationRequest"
+                    }
+                }
+            }
+        },
+        "api.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.BatchOperationResponse"
+                    }
+                }
+            }
+        },
+        "api.CaptureHoldRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount captures part of the hold and releases the rest; omit it to capture the whole hold",
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "30.00"
+                }
+            }
+        },
+        "api.CaptureHoldResponse": {
+            "type": "object",
+            "properties": {
+                "hold": {
+                    "$ref": "#/definitions/models.Hold"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/models.Transaction"
+                },
+                "wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                }
+            }
+        },
+        "api.CreateGrantRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description",
+                "name"
+            ],
+            "properties": {
+                "account": {
+                    "description": "Account names the faucet the grant is paid from (default: \"default\")",
+                    "type": "string",
+                    "example": "vip"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "catch_up": {
+                    "description": "CatchUp decides which occurrences missed during downtime are granted (default: \"all\")",
+                    "type": "string",
+                    "enum": [
+                        "all",
+                        "latest",
+                        "skip"
+                    ],
+                    "example": "all"
+                },
+                "cron": {
+                    "type": "string",
+                    "example": "0 12 * * *"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GEMS"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "interval_seconds": {
+                    "description": "IntervalSeconds or Cron sets the schedule; exactly one of them is required. Intervals are at most ten years.",
+                    "type": "integer",
+                    "maximum": 315360000,
+                    "minimum": 0,
+                    "example": 86400
+                },
+                "name": {
+                    "type": "string",
+                    "example": "VIP daily gems"
+                },
+                "start_at": {
+                    "description": "StartAt and EndAt bound the occurrences of every enrollment (default: from now, without end)",
+                    "type": "string"
+                }
+            }
+        },
+        "api.CreateHoldRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description"
+            ],
+            "properties": {
+                "account": {
+                    "description": "Account names the sink the hold is captured into (default: \"default\")",
+                    "type": "string",
+                    "example": "shop"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "40.00"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "TTLSeconds is how long the hold lasts before it expires (default: HOLD_TTL)",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 300
+                }
+            }
+        },
+        "api.CurrencyBalance": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                }
+            }
+        },
+        "api.CurrencyRequest": {
+            "type": "object",
+            "properties": {
+                "allow_negative": {
+                    "type": "boolean"
+                },
+                "decimals": {
+                    "description": "Decimals defaults to CURRENCY_DECIMALS and may not exceed it",
+                    "type": "integer",
+                    "example": 2
+                },
+                "max_balance": {
+                    "type": "string",
+                    "example": "1000000.00"
+                },
+                "max_transaction": {
+                    "type": "string",
+                    "example": "10000.00"
+                },
+                "min_transaction": {
+                    "type": "string",
+                    "example": "1.00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Gold"
+                },
+                "transferable": {
+                    "description": "Transferable defaults to true",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "api.EnrollWalletRequest": {
+            "type": "object",
+            "required": [
+                "wallet_id"
+            ],
+            "properties": {
+                "end_at": {
+                    "type": "string",
+                    "example": "2026-02-01T00:00:00Z"
+                },
+                "start_at": {
+                    "description": "StartAt and EndAt bound the occurrences granted to the wallet (default: from now until the grant ends)",
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string",
+                    "example": "wallet123"
+                }
+            }
+        },
+        "api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code identifies errors that clients are expected to handle, such as a broken limit",
+                    "type": "string",
+                    "example": "wallet_balance_limit"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.GrantEnrollmentsResponse": {
+            "type": "object",
+            "properties": {
+                "enrollments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.GrantEnrollment"
+                    }
+                }
+            }
+        },
+        "api.GrantRunsResponse": {
+            "type": "object",
+            "properties": {
+                "runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.GrantRun"
+                    }
+                }
+            }
+        },
+        "api.GrantsResponse": {
+            "type": "object",
+            "properties": {
+                "grants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Grant"
+                    }
+                }
+            }
+        },
+        "api.HoldsResponse": {
+            "type": "object",
+            "properties": {
+                "holds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Hold"
+                    }
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.LotsResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string",
+                    "example": "150.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "EVENT_TOKEN"
+                },
+                "lots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Lot"
+                    }
+                },
+                "non_expiring": {
+                    "description": "NonExpiring is the part of the balance that is not in a lot",
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.Pagination": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "prev_cursor": {
+                    "type": "string"
+                },
+                "sort_by": {
+                    "type": "string"
+                },
+                "sort_order": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.RemoveCurrencyRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description"
+            ],
+            "properties": {
+                "account": {
+                    "description": "Account names the sink the currency is paid into (default: \"default\")",
+                    "type": "string",
+                    "example": "shop"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expected_version": {
+                    "description": "ExpectedVersion makes the write fail unless the wallet is still at this version",
+                    "type": "integer",
+                    "example": 7
+                }
+            }
+        },
+        "api.ReverseTransactionRequest": {
+            "type": "object",
+            "properties": {
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "description": "Amount reverses part of the transaction; omit it to reverse everything not reversed yet",
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "20.00"
+                },
+                "description": {
+                    "description": "Description defaults to \"Reversal of \u003ctransaction_id\u003e\"",
+                    "type": "string",
+                    "example": "Refund of mistaken grant"
+                }
+            }
+        },
+        "api.ScheduleOperationRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description",
+                "execute_at",
+                "type",
+                "wallet_id"
+            ],
+            "properties": {
+                "account": {
+                    "description": "Account names the faucet or sink the operation is posted against (default: \"default\")",
+                    "type": "string",
+                    "example": "holiday_event"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "500.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GEMS"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "execute_at": {
+                    "description": "ExecuteAt is when the operation is applied; it must be in the future",
+                    "type": "string",
+                    "example": "2026-12-24T18:00:00Z"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "add",
+                        "remove"
+                    ],
+                    "example": "add"
+                },
+                "wallet_id": {
+                    "type": "string",
+                    "example": "wallet123"
+                }
+            }
+        },
+        "api.ScheduledOperationsResponse": {
+            "type": "object",
+            "properties": {
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ScheduledOperation"
+                    }
+                }
+            }
+        },
+        "api.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "environment": {
+                    "type": "string"
+                },
+                "writes": {
+                    "$ref": "#/definitions/db.Stats"
+                }
+            }
+        },
+        "api.TransactionHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "pagination": {
+                    "$ref": "#/definitions/api.Pagination"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Transaction"
+                    }
+                },
+                "wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                }
+            }
+        },
+        "api.TransactionResponse": {
+            "type": "object",
+            "properties": {
+                "transaction": {
+                    "$ref": "#/definitions/models.Transaction"
+                },
+                "wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                }
+            }
+        },
+        "api.TransferRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description",
+                "from_wallet_id",
+                "to_wallet_id"
+            ],
+            "properties": {
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.TransferResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "from_transaction": {
+                    "$ref": "#/definitions/models.Transaction"
+                },
+                "from_wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                },
+                "to_transaction": {
+                    "$ref": "#/definitions/models.Transaction"
+                },
+                "to_wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                },
+                "transfer_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.TrialBalanceResponse": {
+            "type": "object",
+            "properties": {
+                "balanced": {
+                    "type": "boolean"
+                },
+                "currencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.TrialBalance"
+                    }
+                },
+                "environment": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.WalletBalanceResponse": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "description": "At and LastTransaction are only set for historical balance queries",
+                    "type": "string"
+                },
+                "available": {
+                    "description": "Available and Version are not set for historical balance queries. Available is the balance less active holds.",
+                    "type": "string",
+                    "example": "60.00"
+                },
+                "available_credit": {
+                    "type": "string",
+                    "example": "500.00"
+                },
+                "balance": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "credit_limit": {
+                    "description": "CreditLimit and AvailableCredit are only set for wallets with a credit limit.\nAvailableCredit is the part of the credit limit the wallet has not used.",
+                    "type": "string",
+                    "example": "500.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "last_transaction": {
+                    "$ref": "#/definitions/models.Transaction"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.WalletBalancesResponse": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.CurrencyBalance"
+                    }
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.WalletLimitsRequest": {
+            "type": "object",
+            "properties": {
+                "credit_limit": {
+                    "description": "CreditLimit lets the available balance go this far below zero; omit it to remove the credit limit",
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "500.00"
+                },
+                "max_balance": {
+                    "description": "MaxBalance and MaxTransaction cap the balance and the size of a single transaction; omit them to remove the caps",
+                    "type": "string",
+                    "example": "100000.00"
+                },
+                "max_transaction": {
+                    "type": "string",
+                    "example": "5000.00"
+                }
+            }
+        },
+        "api.WalletMetadataPatchRequest": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "beta"
+                    ]
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "player-42"
+                }
+            }
+        },
+        "api.WalletMetadataRequest": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "beta"
+                    ]
+                },
+                "owner_id": {
+                    "type": "string",
+                    "example": "player-42"
+                }
+            }
+        },
+        "api.WalletResponse": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Wallet"
+                    }
+                },
+                "created_at": {
+                    "description": "CreatedAt is when the wallet was first written to or described, and\nLastActivityAt when a transaction was last posted to it",
+                    "type": "string"
+                },
+                "exists": {
+                    "description": "Exists is false for wallet IDs that were never written to or described",
+                    "type": "boolean"
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "beta"
+                    ]
+                },
+                "last_activity_at": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "description": "OwnerID identifies the player or account that owns the wallet",
+                    "type": "string",
+                    "example": "player-42"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.WalletStatusHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WalletStatusChange"
+                    }
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.WalletStatusRequest": {
+            "type": "object",
+            "required": [
+                "actor",
+                "reason",
+                "status"
+            ],
+            "properties": {
+                "actor": {
+                    "description": "Actor names who made the change, for the audit trail",
+                    "type": "string",
+                    "example": "support:alice"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Suspected fraud, ticket 4711"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "debit_frozen",
+                        "frozen",
+                        "closed"
+                    ],
+                    "example": "debit_frozen"
+                }
+            }
+        },
+        "api.WalletsResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
                     "type": "string"
+                },
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.WalletResponse"
+                    }
                 }
             }
         },
-        "api.TransactionHistoryResponse": {
+        "db.Stats": {
             "type": "object",
             "properties": {
-                "pagination": {
-                    "$ref": "#/definitions/api.Pagination"
+                "conflicts": {
+                    "type": "integer"
                 },
-                "transactions": {
+                "exhausted": {
+                    "type": "integer"
+                },
+                "retries": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.TrialBalance": {
+            "type": "object",
+            "properties": {
+                "balanced": {
+                    "type": "boolean"
+                },
+                "balances": {
+                    "description": "Balances is the sum of every account balance and Entries the sum of every transaction amount",
+                    "type": "string",
+                    "example": "0.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "entries": {
+                    "type": "string",
+                    "example": "0.00"
+                },
+                "faucets": {
+                    "type": "string",
+                    "example": "-300.00"
+                },
+                "sinks": {
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "wallets": {
+                    "description": "Wallets, Faucets and Sinks are the summed balances of each kind of account",
+                    "type": "string",
+                    "example": "250.00"
+                }
+            }
+        },
+        "models.Currency": {
+            "type": "object",
+            "properties": {
+                "allow_negative": {
+                    "type": "boolean"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "decimals": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "max_balance": {
+                    "description": "MaxBalance caps the balance a wallet may hold, if set",
+                    "type": "string",
+                    "example": "1000000.00"
+                },
+                "max_transaction": {
+                    "type": "string",
+                    "example": "10000.00"
+                },
+                "min_transaction": {
+                    "description": "MinTransaction and MaxTransaction bound the size of a single transaction, if set",
+                    "type": "string",
+                    "example": "1.00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Gold"
+                },
+                "transferable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.Grant": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string",
+                    "example": "faucet:vip"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "catch_up": {
+                    "type": "string",
+                    "example": "all"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "cron": {
+                    "type": "string",
+                    "example": "0 12 * * *"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GEMS"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "interval_seconds": {
+                    "description": "IntervalSeconds or Cron sets the schedule; exactly one of them is set",
+                    "type": "integer",
+                    "example": 86400
+                },
+                "name": {
+                    "type": "string",
+                    "example": "VIP daily gems"
+                },
+                "pauses": {
+                    "description": "Pauses records every period the grant was paused, oldest first; occurrences in one are skipped",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Transaction"
+                        "$ref": "#/definitions/models.GrantPause"
                     }
                 },
-                "wallet": {
-                    "$ref": "#/definitions/models.Wallet"
+                "start_at": {
+                    "description": "StartAt and EndAt bound the occurrences of every enrollment",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
                 }
             }
         },
-        "api.TransactionResponse": {
+        "models.GrantEnrollment": {
             "type": "object",
             "properties": {
-                "transaction": {
-                    "$ref": "#/definitions/models.Transaction"
+                "end_at": {
+                    "type": "string"
                 },
-                "wallet": {
-                    "$ref": "#/definitions/models.Wallet"
+                "enrolled_at": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "grant_id": {
+                    "type": "string"
+                },
+                "granted": {
+                    "description": "Granted, Failed and Skipped count the occurrences by outcome",
+                    "type": "integer"
+                },
+                "last_run_at": {
+                    "type": "string"
+                },
+                "next_run_at": {
+                    "description": "NextRunAt is the next occurrence that is due; it is cleared once the enrollment ends",
+                    "type": "string"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "start_at": {
+                    "description": "StartAt and EndAt bound the occurrences granted to this wallet, within those of the grant",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
+                "wallet_id": {
+                    "type": "string"
                 }
             }
         },
-        "api.WalletBalanceResponse": {
+        "models.GrantPause": {
             "type": "object",
             "properties": {
-                "balance": {
-                    "type": "number"
+                "paused_at": {
+                    "type": "string"
+                },
+                "resumed_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GrantRun": {
+            "type": "object",
+            "properties": {
+                "due_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "executed_at": {
+                    "type": "string"
+                },
+                "grant_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "granted"
+                },
+                "transaction_id": {
+                    "description": "TransactionID is the credit a granted occurrence recorded, and Error why a failed one was not granted",
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Hold": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string",
+                    "example": "sink:shop"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "40.00"
+                },
+                "captured_amount": {
+                    "description": "CapturedAmount and TransactionID are set once the hold is captured",
+                    "type": "string",
+                    "example": "30.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Lot": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount is the part of the credit that expires and Remaining is what has not been spent yet",
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "EVENT_TOKEN"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "expiry_transaction_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "remaining": {
+                    "type": "string",
+                    "example": "40.00"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                },
+                "transaction_id": {
+                    "description": "TransactionID is the credit that created the lot and ExpiryTransactionID the one that expired it",
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ScheduledOperation": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string",
+                    "example": "faucet:holiday_event"
+                },
+                "additional_data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "500.00"
+                },
+                "attempts": {
+                    "description": "Attempts counts the runs that failed to apply the operation, and LastError is\nthe error of the latest one; a pending operation with attempts is retried",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GEMS"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "execute_at": {
+                    "type": "string"
+                },
+                "executed_at": {
+                    "description": "ExecutedAt is set once the operation was attempted, together with the\ntransaction it recorded or the error it failed with",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "add"
                 },
                 "wallet_id": {
                     "type": "string"
@@ -393,17 +5375,68 @@ const docTemplate = `{
                     "additionalProperties": true
                 },
                 "amount": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "-50.00"
+                },
+                "balance_after": {
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "balance_before": {
+                    "type": "string",
+                    "example": "150.00"
+                },
+                "counterparty_transaction_id": {
+                    "type": "string"
+                },
+                "counterparty_wallet_id": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
                 },
                 "description": {
                     "type": "string"
                 },
+                "grant_id": {
+                    "description": "GrantID is set on the credits a recurring grant paid",
+                    "type": "string"
+                },
+                "hold_id": {
+                    "description": "HoldID is set on transactions that captured a hold",
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "lot_id": {
+                    "description": "LotID is set on credits that created an expiring lot and on the transactions that expired one",
+                    "type": "string"
+                },
+                "requested_amount": {
+                    "description": "RequestedAmount is set on credits that were clamped to the limits of their\ncurrency or wallet and records the amount originally requested",
+                    "type": "string",
+                    "example": "250.00"
+                },
+                "reversal_of": {
+                    "type": "string"
+                },
+                "reversed_amount": {
+                    "type": "string",
+                    "example": "20.00"
+                },
+                "status": {
+                    "description": "Status and ReversedAmount track reversals of this transaction; ReversalOf\nis set on the compensating transaction and names the one it reverses",
+                    "type": "string",
+                    "example": "partially_reversed"
+                },
                 "timestamp": {
                     "type": "string"
                 },
+                "transfer_id": {
+                    "type": "string"
+                },
                 "wallet_id": {
                     "type": "string"
                 }
@@ -413,7 +5446,91 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "balance": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "100.00"
+                },
+                "credit_limit": {
+                    "description": "CreditLimit is how far below zero the available balance may go in a currency\nthat does not allow negative balances",
+                    "type": "string",
+                    "example": "500.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "last_transaction_at": {
+                    "description": "LastTransactionAt is the time of the wallet's latest transaction in the currency.\nLater transactions are never dated before it.",
+                    "type": "string"
+                },
+                "max_balance": {
+                    "description": "MaxBalance and MaxTransaction cap the balance of the wallet and the size of a\nsingle transaction on it, if set; the currency's own limits apply as well",
+                    "type": "string",
+                    "example": "100000.00"
+                },
+                "max_transaction": {
+                    "type": "string",
+                    "example": "5000.00"
+                },
+                "transaction_count": {
+                    "type": "integer"
+                },
+                "version": {
+                    "description": "Version increases by one with every change to the balance, so that clients can\nmake writes conditional on the state they read",
+                    "type": "integer",
+                    "example": 7
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletStatus": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "support:alice"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Suspected fraud, ticket 4711"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "debit_frozen"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletStatusChange": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "support:alice"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "active"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Suspected fraud, ticket 4711"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "debit_frozen"
                 },
                 "wallet_id": {
                     "type": "string"
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"virtigia-microcurrency/api"
 	"virtigia-microcurrency/db"
 	_ "virtigia-microcurrency/docs"
+	"virtigia-microcurrency/models"
 )
 
 // @title Virtigia Microcurrency API
@@ -49,10 +52,43 @@ func main() {
 		dataDir = filepath.Join(".", "data")
 	}
 
+	// Configure the number of decimal places used for amounts
+	if decimalsStr := os.Getenv("CURRENCY_DECIMALS"); decimalsStr != "" {
+		decimals, err := strconv.Atoi(decimalsStr)
+		if err != nil {
+			log.Fatalf("Invalid CURRENCY_DECIMALS: %v", err)
+		}
+		if err := models.SetDecimals(decimals); err != nil {
+			log.Fatalf("Invalid CURRENCY_DECIMALS: %v", err)
+		}
+	}
+
+	// Load database settings
+	dbConfig, err := db.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid database configuration: %v", err)
+	}
+
 	// Initialize database manager
-	dbManager := db.NewDBManager(dataDir)
+	dbManager := db.NewDBManagerWithConfig(dataDir, dbConfig)
 	defer dbManager.Close()
 
+	// Open existing environments so their data is migrated before serving requests
+	if err := dbManager.OpenExisting(); err != nil {
+		log.Fatalf("Failed to open databases: %v", err)
+	}
+
+	// Expire lots and run scheduled operations in the background until shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	for _, job := range []func(context.Context){dbManager.RunLotExpiry, dbManager.RunScheduler} {
+		jobs.Add(1)
+		go func(job func(context.Context)) {
+			defer jobs.Done()
+			job(jobsCtx)
+		}(job)
+	}
+
 	// Set up router
 	router := api.SetupRouter(dbManager)
 
@@ -91,5 +127,9 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Let running background jobs finish before the databases are closed
+	stopJobs()
+	jobs.Wait()
+
 	log.Println("Server exited properly")
 }
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Prefixes of system account IDs. Faucets issue currency into player wallets and
+// run negative balances; sinks receive the currency players spend.
+const (
+	FaucetPrefix = "faucet:"
+	SinkPrefix   = "sink:"
+)
+
+// DefaultSystemAccount names the faucet and sink used when a write names none
+const DefaultSystemAccount = "default"
+
+// ExpiredSystemAccount names the sink that expired lots are paid into
+const ExpiredSystemAccount = "expired"
+
+// MaxSystemAccountNameLength is the longest system account name accepted
+const MaxSystemAccountNameLength = 64
+
+// ErrInvalidAccountName is returned when a system account name is malformed
+var ErrInvalidAccountName = errors.New("invalid system account name")
+
+// ParseSystemAccountName normalizes a system account name such as "Quest_Rewards"
+// to "quest_rewards". Names contain only letters, digits and underscores.
+func ParseSystemAccountName(s string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" || len(name) > MaxSystemAccountNameLength {
+		return "", ErrInvalidAccountName
+	}
+
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return "", ErrInvalidAccountName
+		}
+	}
+
+	return name, nil
+}
+
+// FaucetAccount returns the wallet ID of the faucet with the given name
+func FaucetAccount(name string) string {
+	return FaucetPrefix + name
+}
+
+// SinkAccount returns the wallet ID of the sink with the given name
+func SinkAccount(name string) string {
+	return SinkPrefix + name
+}
+
+// IsSystemAccount reports whether a wallet ID names a faucet or a sink
+func IsSystemAccount(walletID string) bool {
+	return strings.HasPrefix(walletID, FaucetPrefix) || strings.HasPrefix(walletID, SinkPrefix)
+}
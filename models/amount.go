@@ -0,0 +1,180 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxDecimals is the largest number of decimal places an amount may carry
+const MaxDecimals = 8
+
+var (
+	// ErrInvalidAmount is returned when a value cannot be parsed as a decimal amount
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// ErrAmountPrecision is returned when a value has more decimal places than configured
+	ErrAmountPrecision = errors.New("amount has too many decimal places")
+
+	// ErrAmountOverflow is returned when a value does not fit into an Amount
+	ErrAmountOverflow = errors.New("amount out of range")
+)
+
+// decimals is the number of decimal places shared by every amount in the service
+var decimals = 2
+
+// Amount is a monetary value stored as an integer number of minor units.
+// It is encoded in JSON as an exact decimal string, e.g. "12.50".
+type Amount int64
+
+// SetDecimals configures the number of decimal places used for all amounts.
+// It must be called before any amount is parsed or any database is opened.
+func SetDecimals(n int) error {
+	if n < 0 || n > MaxDecimals {
+		return fmt.Errorf("decimals must be between 0 and %d", MaxDecimals)
+	}
+	decimals = n
+	return nil
+}
+
+// Decimals returns the number of decimal places used for all amounts
+func Decimals() int {
+	return decimals
+}
+
+// scale returns the number of minor units in one major unit
+func scale() int64 {
+	s := int64(1)
+	for i := 0; i < decimals; i++ {
+		s *= 10
+	}
+	return s
+}
+
+// ParseAmount parses an exact decimal string such as "12.5" or "-0.01"
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" || hasDot && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidAmount
+	}
+
+	// Trailing zeros never lose precision, so drop them before checking the scale
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > decimals {
+		return 0, ErrAmountPrecision
+	}
+	fracPart += strings.Repeat("0", decimals-len(fracPart))
+
+	var units int64
+	for _, r := range intPart + fracPart {
+		if units > (math.MaxInt64-int64(r-'0'))/10 {
+			return 0, ErrAmountOverflow
+		}
+		units = units*10 + int64(r-'0')
+	}
+
+	if negative {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+// MustParseAmount is like ParseAmount but panics if the value cannot be parsed
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// AmountFromFloat converts a legacy floating point value, rounding to the nearest minor unit
+func AmountFromFloat(f float64) (Amount, error) {
+	units := math.Round(f * float64(scale()))
+	if math.IsNaN(units) || units >= math.MaxInt64 || units <= math.MinInt64 {
+		return 0, ErrAmountOverflow
+	}
+	return Amount(units), nil
+}
+
+// Add returns a+b and reports false instead if the sum does not fit into an Amount
+func (a Amount) Add(b Amount) (Amount, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// String formats the amount as an exact decimal string
+func (a Amount) String() string {
+	units := int64(a)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+
+	digits := fmt.Sprintf("%d", units)
+	digits = strings.TrimPrefix(digits, "-")
+	if decimals == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	split := len(digits) - decimals
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// MarshalJSON encodes the amount as a decimal string
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes the amount from a decimal string or a JSON number.
+// Numbers are parsed from their literal text, so no floating point rounding occurs.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var s string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else {
+		s = string(data)
+	}
+
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
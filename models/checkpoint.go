@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BalanceCheckpoint records a wallet's balance after one of its transactions,
+// so that historical balances can be computed without replaying the whole history
+type BalanceCheckpoint struct {
+	WalletID         string    `json:"wallet_id"`
+	Currency         string    `json:"currency"`
+	TransactionID    string    `json:"transaction_id"`
+	Timestamp        time.Time `json:"timestamp"`
+	Balance          Amount    `json:"balance"`
+	TransactionCount int64     `json:"transaction_count"`
+}
+
+// CheckpointPrefix returns the key prefix of a wallet's checkpoints in one currency in time order
+func CheckpointPrefix(walletID, currency string) []byte {
+	return []byte("wallet:" + walletID + ":checkpoint:" + currency + ":")
+}
+
+// Key returns the database key for this checkpoint
+func (c *BalanceCheckpoint) Key() []byte {
+	return append(CheckpointPrefix(c.WalletID, c.Currency), SortableTime(c.Timestamp)+":"+c.TransactionID...)
+}
+
+// ToJSON converts the checkpoint to JSON
+func (c *BalanceCheckpoint) ToJSON() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// FromJSON populates the checkpoint from JSON
+func (c *BalanceCheckpoint) FromJSON(data []byte) error {
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxCurrencyCodeLength is the longest currency code accepted
+const MaxCurrencyCodeLength = 16
+
+// ErrInvalidCurrency is returned when a currency code is malformed
+var ErrInvalidCurrency = errors.New("invalid currency code")
+
+// ParseCurrency normalizes a currency code such as "gold" to "GOLD". Codes start
+// with a letter and contain only letters, digits and underscores.
+func ParseCurrency(s string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	if code == "" || len(code) > MaxCurrencyCodeLength || code[0] < 'A' || code[0] > 'Z' {
+		return "", ErrInvalidCurrency
+	}
+
+	for _, r := range code {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return "", ErrInvalidCurrency
+		}
+	}
+
+	return code, nil
+}
+
+// Currency defines a currency and the rules its transactions must follow
+type Currency struct {
+	Code     string `json:"code" example:"GOLD"`
+	Name     string `json:"name" example:"Gold"`
+	Decimals int    `json:"decimals" example:"2"`
+
+	// MinTransaction and MaxTransaction bound the size of a single transaction, if set
+	MinTransaction *Amount `json:"min_transaction,omitempty" swaggertype:"string" example:"1.00"`
+	MaxTransaction *Amount `json:"max_transaction,omitempty" swaggertype:"string" example:"10000.00"`
+
+	// MaxBalance caps the balance a wallet may hold, if set
+	MaxBalance *Amount `json:"max_balance,omitempty" swaggertype:"string" example:"1000000.00"`
+
+	Transferable  bool `json:"transferable"`
+	AllowNegative bool `json:"allow_negative"`
+}
+
+// CurrencyKey returns the database key for a currency definition
+func CurrencyKey(code string) []byte {
+	return []byte("currency:" + code)
+}
+
+// Key returns the database key for this currency
+func (c *Currency) Key() []byte {
+	return CurrencyKey(c.Code)
+}
+
+// Validate checks that the definition is consistent with itself and with the configured decimals
+func (c *Currency) Validate() error {
+	if c.Decimals < 0 || c.Decimals > decimals {
+		return fmt.Errorf("decimals must be between 0 and %d", decimals)
+	}
+
+	for name, limit := range map[string]*Amount{"min_transaction": c.MinTransaction, "max_transaction": c.MaxTransaction, "max_balance": c.MaxBalance} {
+		if limit == nil {
+			continue
+		}
+		if *limit <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+		if !c.HasPrecision(*limit) {
+			return fmt.Errorf("%s has more than %d decimal places", name, c.Decimals)
+		}
+	}
+
+	if c.MinTransaction != nil && c.MaxTransaction != nil && *c.MinTransaction > *c.MaxTransaction {
+		return errors.New("min_transaction must not exceed max_transaction")
+	}
+
+	return nil
+}
+
+// HasPrecision reports whether an amount has no more decimal places than the currency allows
+func (c *Currency) HasPrecision(a Amount) bool {
+	step := int64(1)
+	for i := c.Decimals; i < decimals; i++ {
+		step *= 10
+	}
+	return int64(a)%step == 0
+}
+
+// ToJSON converts the currency to JSON
+func (c *Currency) ToJSON() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// FromJSON populates the currency from JSON
+func (c *Currency) FromJSON(data []byte) error {
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxGrantIntervalSeconds is the longest interval between the occurrences of a grant, ten years
+const MaxGrantIntervalSeconds = 10 * 365 * 24 * 60 * 60
+
+// ErrScheduleOutOfRange is returned when the next occurrence of a grant cannot be computed
+var ErrScheduleOutOfRange = errors.New("grant schedule is out of range")
+
+// Statuses of a grant. Paused grants credit no enrolled wallet.
+const (
+	GrantStatusActive = "active"
+	GrantStatusPaused = "paused"
+)
+
+// Catch-up policies decide what happens to occurrences of a grant that were due
+// while the service was down
+const (
+	// CatchUpAll grants every missed occurrence
+	CatchUpAll = "all"
+
+	// CatchUpLatest grants only the most recent missed occurrence
+	CatchUpLatest = "latest"
+
+	// CatchUpSkip grants no occurrence that is overdue by more than the grace period
+	CatchUpSkip = "skip"
+)
+
+// Statuses of a grant enrollment. Only active enrollments are credited.
+const (
+	EnrollmentStatusActive    = "active"
+	EnrollmentStatusPaused    = "paused"
+	EnrollmentStatusEnded     = "ended"
+	EnrollmentStatusCancelled = "cancelled"
+)
+
+// Statuses of a grant run
+const (
+	GrantRunStatusGranted = "granted"
+	GrantRunStatusFailed  = "failed"
+)
+
+// Grant is a recurring credit, such as a daily allowance, paid from a faucet to
+// every enrolled wallet on a schedule. The schedule is either a fixed interval
+// counted from each enrollment's start or a cron expression evaluated in UTC.
+type Grant struct {
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name" example:"VIP daily gems"`
+	Currency       string                 `json:"currency" example:"GEMS"`
+	Amount         Amount                 `json:"amount" swaggertype:"string" example:"100.00"`
+	Account        string                 `json:"account" example:"faucet:vip"`
+	Description    string                 `json:"description"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// IntervalSeconds or Cron sets the schedule; exactly one of them is set
+	IntervalSeconds int64  `json:"interval_seconds,omitempty" example:"86400"`
+	Cron            string `json:"cron,omitempty" example:"0 12 * * *"`
+
+	CatchUp string `json:"catch_up" example:"all"`
+	Status  string `json:"status" example:"active"`
+
+	// StartAt and EndAt bound the occurrences of every enrollment
+	StartAt time.Time  `json:"start_at"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+
+	// Pauses records every period the grant was paused, oldest first; occurrences in one are skipped
+	Pauses []GrantPause `json:"pauses,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GrantPause is a period in which a grant was paused. ResumedAt is unset while it still is.
+type GrantPause struct {
+	PausedAt  time.Time  `json:"paused_at"`
+	ResumedAt *time.Time `json:"resumed_at,omitempty"`
+}
+
+// GrantEnrollment is the enrollment of one wallet in a grant
+type GrantEnrollment struct {
+	GrantID  string `json:"grant_id"`
+	WalletID string `json:"wallet_id"`
+	Status   string `json:"status" example:"active"`
+
+	// StartAt and EndAt bound the occurrences granted to this wallet, within those of the grant
+	StartAt time.Time  `json:"start_at"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+
+	// NextRunAt is the next occurrence that is due; it is cleared once the enrollment ends
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+
+	// Granted, Failed and Skipped count the occurrences by outcome
+	Granted int `json:"granted"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+
+	EnrolledAt time.Time `json:"enrolled_at"`
+}
+
+// GrantRun records the outcome of one occurrence of a grant for one wallet
+type GrantRun struct {
+	GrantID  string    `json:"grant_id"`
+	WalletID string    `json:"wallet_id"`
+	DueAt    time.Time `json:"due_at"`
+	Status   string    `json:"status" example:"granted"`
+
+	// TransactionID is the credit a granted occurrence recorded, and Error why a failed one was not granted
+	TransactionID string `json:"transaction_id,omitempty"`
+	Error         string `json:"error,omitempty"`
+
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// GrantKey returns the database key of a grant
+func GrantKey(id string) []byte {
+	return []byte("grant:" + id)
+}
+
+// GrantEnrollmentKey returns the database key of a wallet's enrollment in a grant
+func GrantEnrollmentKey(grantID, walletID string) []byte {
+	return append(GrantEnrollmentPrefix(grantID), walletID...)
+}
+
+// GrantEnrollmentPrefix returns the key prefix of the enrollments of a grant
+func GrantEnrollmentPrefix(grantID string) []byte {
+	return []byte("grant_enrollment:" + grantID + ":")
+}
+
+// GrantDuePrefix returns the key prefix of the active enrollments of every grant in order of their next occurrence
+func GrantDuePrefix() []byte {
+	return []byte("grant_due:")
+}
+
+// GrantRunPrefix returns the key prefix of the runs of a grant in order of their occurrence
+func GrantRunPrefix(grantID string) []byte {
+	return []byte("grant_run:" + grantID + ":")
+}
+
+// EnrollmentFromDueKey returns the grant and wallet IDs of a due index key
+func EnrollmentFromDueKey(key []byte) (grantID, walletID string) {
+	// The key is the prefix, a sortable time and a colon followed by the grant ID, a colon and the wallet ID
+	rest := string(key[len(GrantDuePrefix())+len(SortableTime(time.Time{}))+1:])
+	grantID, walletID, _ = strings.Cut(rest, ":")
+	return grantID, walletID
+}
+
+// Schedule returns the parsed cron expression of the grant, or nil for an interval schedule
+func (g *Grant) Schedule() (*Cron, error) {
+	if g.Cron == "" {
+		return nil, nil
+	}
+	return ParseCron(g.Cron)
+}
+
+// Next returns the first occurrence after t of an enrollment whose occurrences
+// start at start, or the zero time if there is none
+func (g *Grant) Next(start, t time.Time) (time.Time, error) {
+	cron, err := g.Schedule()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cron != nil {
+		if t.Before(start) {
+			t = start.Add(-time.Nanosecond)
+		}
+		return cron.Next(t), nil
+	}
+
+	if t.Before(start) {
+		return start, nil
+	}
+	if g.IntervalSeconds <= 0 || g.IntervalSeconds > MaxGrantIntervalSeconds {
+		return time.Time{}, ErrScheduleOutOfRange
+	}
+
+	// Step from the last occurrence at or before t, which is never further from start than t,
+	// so that no duration overflows
+	interval := time.Duration(g.IntervalSeconds) * time.Second
+	next := start.Add(t.Sub(start) / interval * interval).Add(interval)
+	if !next.After(t) {
+		return time.Time{}, ErrScheduleOutOfRange
+	}
+	return next, nil
+}
+
+// Paused reports whether an occurrence fell due while the grant was paused
+func (g *Grant) Paused(at time.Time) bool {
+	for _, pause := range g.Pauses {
+		if !at.Before(pause.PausedAt) && (pause.ResumedAt == nil || at.Before(*pause.ResumedAt)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the database key for this grant
+func (g *Grant) Key() []byte {
+	return GrantKey(g.ID)
+}
+
+// ToJSON converts the grant to JSON
+func (g *Grant) ToJSON() ([]byte, error) {
+	return json.Marshal(g)
+}
+
+// FromJSON populates the grant from JSON
+func (g *Grant) FromJSON(data []byte) error {
+	return json.Unmarshal(data, g)
+}
+
+// End returns the end of the occurrences of an enrollment in a grant, or nil if they never end
+func (e *GrantEnrollment) End(grant *Grant) *time.Time {
+	if grant.EndAt != nil && (e.EndAt == nil || grant.EndAt.Before(*e.EndAt)) {
+		return grant.EndAt
+	}
+	return e.EndAt
+}
+
+// Key returns the database key for this enrollment
+func (e *GrantEnrollment) Key() []byte {
+	return GrantEnrollmentKey(e.GrantID, e.WalletID)
+}
+
+// DueKey returns the key that orders this enrollment by its next occurrence
+func (e *GrantEnrollment) DueKey() []byte {
+	return append(GrantDuePrefix(), SortableTime(*e.NextRunAt)+":"+e.GrantID+":"+e.WalletID...)
+}
+
+// ToJSON converts the enrollment to JSON
+func (e *GrantEnrollment) ToJSON() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// FromJSON populates the enrollment from JSON
+func (e *GrantEnrollment) FromJSON(data []byte) error {
+	return json.Unmarshal(data, e)
+}
+
+// Key returns the database key for this run
+func (r *GrantRun) Key() []byte {
+	return append(GrantRunPrefix(r.GrantID), SortableTime(r.DueAt)+":"+r.WalletID...)
+}
+
+// ToJSON converts the run to JSON
+func (r *GrantRun) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// FromJSON populates the run from JSON
+func (r *GrantRun) FromJSON(data []byte) error {
+	return json.Unmarshal(data, r)
+}
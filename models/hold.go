@@ -0,0 +1,75 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a hold. Only active holds that have not expired reserve currency.
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+// Hold reserves part of a wallet's balance until it is captured into a sink,
+// released or expires. Held currency stays in the balance but is not available.
+type Hold struct {
+	ID             string                 `json:"id"`
+	WalletID       string                 `json:"wallet_id"`
+	Currency       string                 `json:"currency" example:"GOLD"`
+	Amount         Amount                 `json:"amount" swaggertype:"string" example:"40.00"`
+	Account        string                 `json:"account" example:"sink:shop"`
+	Status         string                 `json:"status" example:"active"`
+	Description    string                 `json:"description"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+
+	// CapturedAmount and TransactionID are set once the hold is captured
+	CapturedAmount Amount `json:"captured_amount,omitempty" swaggertype:"string" example:"30.00"`
+	TransactionID  string `json:"transaction_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HoldKey returns the database key of a hold
+func HoldKey(id string) []byte {
+	return []byte("hold:" + id)
+}
+
+// HoldIndexPrefix returns the key prefix of a wallet's active holds in one currency in expiry order
+func HoldIndexPrefix(walletID, currency string) []byte {
+	return []byte("wallet:" + walletID + ":hold:" + currency + ":")
+}
+
+// HoldIDFromIndexKey extracts the hold ID from a wallet hold index key
+func HoldIDFromIndexKey(key []byte) string {
+	return string(key[bytes.LastIndexByte(key, ':')+1:])
+}
+
+// Key returns the database key for this hold
+func (h *Hold) Key() []byte {
+	return HoldKey(h.ID)
+}
+
+// IndexKey returns the key that orders this hold by expiry within its wallet and currency
+func (h *Hold) IndexKey() []byte {
+	return append(HoldIndexPrefix(h.WalletID, h.Currency), SortableTime(h.ExpiresAt)+":"+h.ID...)
+}
+
+// Active reports whether the hold still reserves currency at the given time
+func (h *Hold) Active(now time.Time) bool {
+	return h.Status == HoldStatusActive && now.Before(h.ExpiresAt)
+}
+
+// ToJSON converts the hold to JSON
+func (h *Hold) ToJSON() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+// FromJSON populates the hold from JSON
+func (h *Hold) FromJSON(data []byte) error {
+	return json.Unmarshal(data, h)
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyRecord remembers the outcome of a write made with an idempotency key
+type IdempotencyRecord struct {
+	Key           string    `json:"key"`
+	RequestHash   string    `json:"request_hash"`
+	TransactionID string    `json:"transaction_id"`
+	Wallet        *Wallet   `json:"wallet"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// IdempotencyKey returns the database key for an idempotency key
+func IdempotencyKey(key string) []byte {
+	return []byte("idempotency:" + key)
+}
+
+// ToJSON converts the record to JSON
+func (r *IdempotencyRecord) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// FromJSON populates the record from JSON
+func (r *IdempotencyRecord) FromJSON(data []byte) error {
+	return json.Unmarshal(data, r)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a lot. Only active lots still make up part of their wallet's balance.
+const (
+	LotStatusActive   = "active"
+	LotStatusConsumed = "consumed"
+	LotStatusExpired  = "expired"
+)
+
+// Lot is a credit to a wallet that expires. Debits of the wallet consume its lots
+// in expiry order before the rest of the balance; whatever remains of a lot when it
+// expires is removed from the balance by an expiry transaction.
+type Lot struct {
+	ID       string `json:"id"`
+	WalletID string `json:"wallet_id"`
+	Currency string `json:"currency" example:"EVENT_TOKEN"`
+
+	// Amount is the part of the credit that expires and Remaining is what has not been spent yet
+	Amount    Amount `json:"amount" swaggertype:"string" example:"100.00"`
+	Remaining Amount `json:"remaining" swaggertype:"string" example:"40.00"`
+	Status    string `json:"status" example:"active"`
+
+	// TransactionID is the credit that created the lot and ExpiryTransactionID the one that expired it
+	TransactionID       string `json:"transaction_id"`
+	ExpiryTransactionID string `json:"expiry_transaction_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LotKey returns the database key of a lot
+func LotKey(id string) []byte {
+	return []byte("lot:" + id)
+}
+
+// LotIndexPrefix returns the key prefix of a wallet's active lots in one currency in expiry order
+func LotIndexPrefix(walletID, currency string) []byte {
+	return []byte("wallet:" + walletID + ":lot:" + currency + ":")
+}
+
+// LotExpiryPrefix returns the key prefix of the active lots of every wallet in expiry order
+func LotExpiryPrefix() []byte {
+	return []byte("lot_expiry:")
+}
+
+// LotIDFromIndexKey returns the lot ID at the end of a wallet or expiry index key
+func LotIDFromIndexKey(key []byte) string {
+	return string(key[bytes.LastIndexByte(key, ':')+1:])
+}
+
+// Key returns the database key for this lot
+func (l *Lot) Key() []byte {
+	return LotKey(l.ID)
+}
+
+// IndexKey returns the key that orders this lot by expiry within its wallet and currency
+func (l *Lot) IndexKey() []byte {
+	return append(LotIndexPrefix(l.WalletID, l.Currency), SortableTime(l.ExpiresAt)+":"+l.ID...)
+}
+
+// ExpiryKey returns the key that orders this lot by expiry among the lots of every wallet
+func (l *Lot) ExpiryKey() []byte {
+	return append(LotExpiryPrefix(), SortableTime(l.ExpiresAt)+":"+l.ID...)
+}
+
+// ToJSON converts the lot to JSON
+func (l *Lot) ToJSON() ([]byte, error) {
+	return json.Marshal(l)
+}
+
+// FromJSON populates the lot from JSON
+func (l *Lot) FromJSON(data []byte) error {
+	return json.Unmarshal(data, l)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a scheduled operation. Only pending operations are executed.
+const (
+	ScheduledStatusPending   = "pending"
+	ScheduledStatusExecuted  = "executed"
+	ScheduledStatusFailed    = "failed"
+	ScheduledStatusCancelled = "cancelled"
+)
+
+// ScheduledOperation is an add or removal that is applied once its time has come
+type ScheduledOperation struct {
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type" example:"add"`
+	WalletID       string                 `json:"wallet_id"`
+	Currency       string                 `json:"currency" example:"GEMS"`
+	Amount         Amount                 `json:"amount" swaggertype:"string" example:"500.00"`
+	Account        string                 `json:"account" example:"faucet:holiday_event"`
+	Description    string                 `json:"description"`
+	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
+	Status         string                 `json:"status" example:"pending"`
+	ExecuteAt      time.Time              `json:"execute_at"`
+
+	// ExecutedAt is set once the operation was attempted, together with the
+	// transaction it recorded or the error it failed with
+	ExecutedAt    *time.Time `json:"executed_at,omitempty"`
+	TransactionID string     `json:"transaction_id,omitempty"`
+	Error         string     `json:"error,omitempty"`
+
+	// Attempts counts the runs that failed to apply the operation, and LastError is
+	// the error of the latest one; a pending operation with attempts is retried
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScheduledKey returns the database key of a scheduled operation
+func ScheduledKey(id string) []byte {
+	return []byte("scheduled:" + id)
+}
+
+// ScheduledDuePrefix returns the key prefix of the pending scheduled operations in execution order
+func ScheduledDuePrefix() []byte {
+	return []byte("scheduled_due:")
+}
+
+// ScheduledIDFromDueKey returns the scheduled operation ID at the end of a due index key
+func ScheduledIDFromDueKey(key []byte) string {
+	return string(key[bytes.LastIndexByte(key, ':')+1:])
+}
+
+// Key returns the database key for this scheduled operation
+func (s *ScheduledOperation) Key() []byte {
+	return ScheduledKey(s.ID)
+}
+
+// DueKey returns the key that orders this scheduled operation by execution time
+func (s *ScheduledOperation) DueKey() []byte {
+	return append(ScheduledDuePrefix(), SortableTime(s.ExecuteAt)+":"+s.ID...)
+}
+
+// ToJSON converts the scheduled operation to JSON
+func (s *ScheduledOperation) ToJSON() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// FromJSON populates the scheduled operation from JSON
+func (s *ScheduledOperation) FromJSON(data []byte) error {
+	return json.Unmarshal(data, s)
+}
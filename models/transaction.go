@@ -1,18 +1,64 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
-// Transaction represents a currency transaction in the system
+// Transaction represents a currency transaction in the system. Every transaction
+// is one entry of a balanced pair: CounterpartyTransactionID is the entry on the
+// counterparty wallet that moved the same amount the other way. Entries on system
+// accounts have no running balance and leave BalanceBefore and BalanceAfter at zero.
 type Transaction struct {
-	ID            string                 `json:"id"`
-	WalletID      string                 `json:"wallet_id"`
-	Amount        float64                `json:"amount"`
-	Description   string                 `json:"description"`
-	AdditionalData map[string]interface{} `json:"additional_data,omitempty"`
-	Timestamp     time.Time              `json:"timestamp"`
+	ID                        string                 `json:"id"`
+	WalletID                  string                 `json:"wallet_id"`
+	Currency                  string                 `json:"currency" example:"GOLD"`
+	Amount                    Amount                 `json:"amount" swaggertype:"string" example:"-50.00"`
+	BalanceBefore             Amount                 `json:"balance_before" swaggertype:"string" example:"150.00"`
+	BalanceAfter              Amount                 `json:"balance_after" swaggertype:"string" example:"100.00"`
+	Description               string                 `json:"description"`
+	AdditionalData            map[string]interface{} `json:"additional_data,omitempty"`
+	TransferID                string                 `json:"transfer_id,omitempty"`
+	CounterpartyWalletID      string                 `json:"counterparty_wallet_id,omitempty"`
+	CounterpartyTransactionID string                 `json:"counterparty_transaction_id,omitempty"`
+
+	// Status and ReversedAmount track reversals of this transaction; ReversalOf
+	// is set on the compensating transaction and names the one it reverses
+	Status         string `json:"status,omitempty" example:"partially_reversed"`
+	ReversedAmount Amount `json:"reversed_amount,omitempty" swaggertype:"string" example:"20.00"`
+	ReversalOf     string `json:"reversal_of,omitempty"`
+
+	// HoldID is set on transactions that captured a hold
+	HoldID string `json:"hold_id,omitempty"`
+
+	// RequestedAmount is set on credits that were clamped to the limits of their
+	// currency or wallet and records the amount originally requested
+	RequestedAmount Amount `json:"requested_amount,omitempty" swaggertype:"string" example:"250.00"`
+
+	// LotID is set on credits that created an expiring lot and on the transactions that expired one
+	LotID string `json:"lot_id,omitempty"`
+
+	// GrantID is set on the credits a recurring grant paid
+	GrantID string `json:"grant_id,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Statuses of a transaction that has been reversed. Transactions without a status are posted and unreversed.
+const (
+	TransactionStatusPartiallyReversed = "partially_reversed"
+	TransactionStatusReversed          = "reversed"
+)
+
+// Reversible returns the part of the transaction's amount that has not been reversed yet
+func (t *Transaction) Reversible() Amount {
+	amount := t.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	return amount - t.ReversedAmount
 }
 
 // Key returns the database key for this transaction
@@ -20,11 +66,51 @@ func (t *Transaction) Key() []byte {
 	return []byte("transaction:" + t.ID)
 }
 
-// WalletKey returns the key for indexing by wallet ID
-func (t *Transaction) WalletKey() []byte {
+// LegacyWalletKey returns the key under which earlier versions stored a copy of the transaction
+func (t *Transaction) LegacyWalletKey() []byte {
 	return []byte("wallet:" + t.WalletID + ":transaction:" + t.ID)
 }
 
+// TimeIndexPrefix returns the key prefix of a wallet's transactions in one currency in time order
+func TimeIndexPrefix(walletID, currency string) []byte {
+	return []byte("wallet:" + walletID + ":tx_time:" + currency + ":")
+}
+
+// AmountIndexPrefix returns the key prefix of a wallet's transactions in one currency in amount order
+func AmountIndexPrefix(walletID, currency string) []byte {
+	return []byte("wallet:" + walletID + ":tx_amount:" + currency + ":")
+}
+
+// TimeIndexKey returns the key that orders this transaction by time within its wallet and currency
+func (t *Transaction) TimeIndexKey() []byte {
+	return append(TimeIndexPrefix(t.WalletID, t.Currency), SortableTime(t.Timestamp)+":"+t.ID...)
+}
+
+// AmountIndexKey returns the key that orders this transaction by amount within its wallet and currency
+func (t *Transaction) AmountIndexKey() []byte {
+	return append(AmountIndexPrefix(t.WalletID, t.Currency), SortableAmount(t.Amount)+":"+SortableTime(t.Timestamp)+":"+t.ID...)
+}
+
+// TransactionIDFromIndexKey extracts the transaction ID from a wallet index key
+func TransactionIDFromIndexKey(key []byte) string {
+	return string(key[bytes.LastIndexByte(key, ':')+1:])
+}
+
+// SortableTime formats a time as fixed-width nanoseconds that sort chronologically
+func SortableTime(t time.Time) string {
+	nanos := t.UnixNano()
+	if nanos < 0 {
+		nanos = 0
+	}
+	return fmt.Sprintf("%020d", nanos)
+}
+
+// SortableAmount formats an amount as fixed-width hex that sorts numerically
+func SortableAmount(a Amount) string {
+	// Flipping the sign bit makes the unsigned big-endian form sort like the signed value
+	return fmt.Sprintf("%016x", uint64(a)^(1<<63))
+}
+
 // ToJSON converts the transaction to JSON
 func (t *Transaction) ToJSON() ([]byte, error) {
 	return json.Marshal(t)
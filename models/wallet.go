@@ -2,17 +2,83 @@ package models
 
 import (
 	"encoding/json"
+	"time"
 )
 
-// Wallet represents a currency wallet
+// Wallet represents the balance a wallet holds in one currency
 type Wallet struct {
-	WalletID string  `json:"wallet_id"`
-	Balance  float64 `json:"balance"`
+	WalletID         string `json:"wallet_id"`
+	Currency         string `json:"currency" example:"GOLD"`
+	Balance          Amount `json:"balance" swaggertype:"string" example:"100.00"`
+	TransactionCount int64  `json:"transaction_count"`
+
+	// Version increases by one with every change to the balance, so that clients can
+	// make writes conditional on the state they read
+	Version int64 `json:"version" example:"7"`
+
+	// LastTransactionAt is the time of the wallet's latest transaction in the currency.
+	// Later transactions are never dated before it.
+	LastTransactionAt *time.Time `json:"last_transaction_at,omitempty"`
+
+	WalletLimits
+}
+
+// WalletLimits are the limits set on one wallet in addition to the rules of its currency
+type WalletLimits struct {
+	// CreditLimit is how far below zero the available balance may go in a currency
+	// that does not allow negative balances
+	CreditLimit Amount `json:"credit_limit,omitempty" swaggertype:"string" example:"500.00"`
+
+	// MaxBalance and MaxTransaction cap the balance of the wallet and the size of a
+	// single transaction on it, if set; the currency's own limits apply as well
+	MaxBalance     *Amount `json:"max_balance,omitempty" swaggertype:"string" example:"100000.00"`
+	MaxTransaction *Amount `json:"max_transaction,omitempty" swaggertype:"string" example:"5000.00"`
+}
+
+// AvailableCredit returns how much of the credit limit is left when the wallet
+// has the given available balance
+func (w *Wallet) AvailableCredit(available Amount) Amount {
+	credit := w.CreditLimit
+	if available < 0 {
+		credit += available
+	}
+	if credit < 0 {
+		return 0
+	}
+	return credit
+}
+
+// TransactionTime returns now, or just after the wallet's latest transaction if the
+// clock has not moved past it, so that time order always matches commit order
+func (w *Wallet) TransactionTime(now time.Time) time.Time {
+	if w.LastTransactionAt != nil && !now.After(*w.LastTransactionAt) {
+		return w.LastTransactionAt.Add(time.Nanosecond)
+	}
+	return now
+}
+
+// BalancePrefix returns the key prefix of a wallet's balances in every currency
+func BalancePrefix(walletID string) []byte {
+	return []byte("wallet:" + walletID + ":balance:")
 }
 
 // Key returns the database key for this wallet
 func (w *Wallet) Key() []byte {
-	return []byte("wallet:" + w.WalletID)
+	return append(BalancePrefix(w.WalletID), w.Currency...)
+}
+
+// ShardPrefix returns the key prefix of the balance shards of a system account in
+// every currency. A system account is posted to one shard per wallet it has entries
+// against, so that writes on different wallets never update the same key; its balance
+// is its balance record plus all of its shards.
+func ShardPrefix(walletID string) []byte {
+	return []byte("shard:" + walletID + ":")
+}
+
+// ShardKey returns the key of the shard of this system account that holds its
+// entries against counterpartyID
+func (w *Wallet) ShardKey(counterpartyID string) []byte {
+	return append(ShardPrefix(w.WalletID), w.Currency+":"+counterpartyID...)
 }
 
 // ToJSON converts the wallet to JSON
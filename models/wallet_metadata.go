@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WalletMetadata describes a wallet across all of its currencies. Wallet holds the
+// balance in one currency, so the metadata is stored once per wallet beside it.
+type WalletMetadata struct {
+	WalletID string `json:"wallet_id"`
+
+	// OwnerID identifies the player or account that owns the wallet
+	OwnerID    string                 `json:"owner_id,omitempty" example:"player-42"`
+	Labels     []string               `json:"labels,omitempty" example:"vip,beta"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	// CreatedAt is when the wallet was first written to or described, and
+	// LastActivityAt when a transaction was last posted to it
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// WalletMetadataKey returns the database key of a wallet's metadata
+func WalletMetadataKey(walletID string) []byte {
+	return []byte("wallet_metadata:" + walletID)
+}
+
+// Key returns the database key for this metadata
+func (m *WalletMetadata) Key() []byte {
+	return WalletMetadataKey(m.WalletID)
+}
+
+// ToJSON converts the metadata to JSON
+func (m *WalletMetadata) ToJSON() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// FromJSON populates the metadata from JSON
+func (m *WalletMetadata) FromJSON(data []byte) error {
+	return json.Unmarshal(data, m)
+}
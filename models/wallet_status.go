@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a wallet. A wallet without a recorded status is active.
+const (
+	// WalletStatusActive allows every write
+	WalletStatusActive = "active"
+
+	// WalletStatusDebitFrozen refuses debits such as removals, outgoing transfers and holds but allows credits
+	WalletStatusDebitFrozen = "debit_frozen"
+
+	// WalletStatusFrozen refuses every debit and credit
+	WalletStatusFrozen = "frozen"
+
+	// WalletStatusClosed refuses every write for good
+	WalletStatusClosed = "closed"
+)
+
+// WalletStatus is the status of a wallet across all of its currencies, with the
+// reason and actor of the change that set it
+type WalletStatus struct {
+	WalletID  string     `json:"wallet_id"`
+	Status    string     `json:"status" example:"debit_frozen"`
+	Reason    string     `json:"reason,omitempty" example:"Suspected fraud, ticket 4711"`
+	Actor     string     `json:"actor,omitempty" example:"support:alice"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// WalletStatusChange is one entry of the audit trail of a wallet's status
+type WalletStatusChange struct {
+	ID        string    `json:"id"`
+	WalletID  string    `json:"wallet_id"`
+	From      string    `json:"from" example:"active"`
+	To        string    `json:"to" example:"debit_frozen"`
+	Reason    string    `json:"reason" example:"Suspected fraud, ticket 4711"`
+	Actor     string    `json:"actor" example:"support:alice"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ValidWalletStatus reports whether status is one of the wallet statuses
+func ValidWalletStatus(status string) bool {
+	switch status {
+	case WalletStatusActive, WalletStatusDebitFrozen, WalletStatusFrozen, WalletStatusClosed:
+		return true
+	}
+	return false
+}
+
+// WalletStatusKey returns the database key of a wallet's status
+func WalletStatusKey(walletID string) []byte {
+	return []byte("wallet_status:" + walletID)
+}
+
+// WalletStatusChangePrefix returns the key prefix of a wallet's status changes in time order
+func WalletStatusChangePrefix(walletID string) []byte {
+	return []byte("wallet_status_change:" + walletID + ":")
+}
+
+// Key returns the database key for this status
+func (s *WalletStatus) Key() []byte {
+	return WalletStatusKey(s.WalletID)
+}
+
+// ToJSON converts the status to JSON
+func (s *WalletStatus) ToJSON() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// FromJSON populates the status from JSON
+func (s *WalletStatus) FromJSON(data []byte) error {
+	return json.Unmarshal(data, s)
+}
+
+// Key returns the database key for this status change
+func (c *WalletStatusChange) Key() []byte {
+	return append(WalletStatusChangePrefix(c.WalletID), SortableTime(c.ChangedAt)+":"+c.ID...)
+}
+
+// ToJSON converts the status change to JSON
+func (c *WalletStatusChange) ToJSON() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// FromJSON populates the status change from JSON
+func (c *WalletStatusChange) FromJSON(data []byte) error {
+	return json.Unmarshal(data, c)
+}